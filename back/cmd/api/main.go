@@ -3,10 +3,23 @@ package main
 import (
 	"bookvito/config"
 	"bookvito/internal/delivery/http"
+	"bookvito/internal/domain"
+	"bookvito/internal/repository/blob"
+	"bookvito/internal/repository/mailer"
+	"bookvito/internal/repository/metadata"
 	"bookvito/internal/repository/postgres"
 	"bookvito/internal/usecase"
 	"bookvito/pkg/database"
+	"bookvito/pkg/migrations"
+	"bookvito/pkg/scheduler"
+	"context"
+	"database/sql"
+	"errors"
 	"log"
+	nethttp "net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,51 +38,188 @@ func main() {
 		log.Fatalf("Не удалось подключиться к базе данных: %v", err)
 	}
 
-	// Auto-migrate database schema
-	if err := database.AutoMigrate(db); err != nil {
-		log.Fatalf("Не удалось выполнить миграцию базы данных: %v", err)
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Не удалось получить пул соединений: %v", err)
+	}
+
+	// "main migrate ..." - управление схемой без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(context.Background(), sqlDB, os.Args[2:])
+		sqlDB.Close()
+		if err != nil {
+			log.Fatalf("Миграция не выполнена: %v", err)
+		}
+		return
+	}
+
+	// Применяем новые миграции; advisory lock не дает нескольким экземплярам мигрировать одновременно
+	if cfg.DBAutoMigrate {
+		migrator, err := migrations.New(sqlDB)
+		if err != nil {
+			log.Fatalf("Не удалось загрузить миграции: %v", err)
+		}
+		if err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Не удалось выполнить миграцию базы данных: %v", err)
+		}
 	}
 
 	// Initialize repositories
 	userRepo := postgres.NewUserRepository(db)
+	sessionRepo := postgres.NewSessionRepository(db)
+	tokenRepo := postgres.NewAccountTokenRepository(db)
 	bookRepo := postgres.NewBookRepository(db)
+	workRepo := postgres.NewWorkRepository(db)
 	exchangeRepo := postgres.NewExchangeRepository(db)
 	movementRepo := postgres.NewBookMovementHistoryRepository(db)
 	locationRepo := postgres.NewLocationRepository(db)
+	reviewRepo := postgres.NewReviewRepository(db)
+	waitlistRepo := postgres.NewWaitlistRepository(db)
+	uploadRepo := postgres.NewUploadRepository(db)
+	uow := postgres.NewUnitOfWork(db)
+
+	// Файлы загрузок; S3-совместимое хранилище подключается здесь же вместо локального
+	blobs, err := blob.NewLocalStore(cfg.UploadDir)
+	if err != nil {
+		log.Fatalf("Не удалось подготовить хранилище файлов: %v", err)
+	}
+
+	// Описания изданий по ISBN: локальный набор данных, если он задан
+	var bookMetadata domain.MetadataProvider = metadata.None{}
+	if cfg.ISBNDataset != "" {
+		offline, err := metadata.NewOfflineProvider(cfg.ISBNDataset)
+		if err != nil {
+			log.Fatalf("Не удалось загрузить набор данных ISBN: %v", err)
+		}
+		log.Printf("ISBN dataset loaded: %d editions", offline.Len())
+		bookMetadata = offline
+	}
+
+	// Письма пользователям: SMTP, каталог с .eml-файлами или лог
+	var mail domain.Mailer = mailer.LogMailer{}
+	switch {
+	case cfg.SMTPHost != "":
+		mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	case cfg.MailDir != "":
+		fileMailer, err := mailer.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+		if err != nil {
+			log.Fatalf("Не удалось подготовить каталог для писем: %v", err)
+		}
+		mail = fileMailer
+	}
 
 	// Initialize use cases
-	userUseCase := usecase.NewUserUseCase(userRepo, movementRepo, cfg.JWTSecret)
-	bookUseCase := usecase.NewBookUseCase(bookRepo, movementRepo, exchangeRepo)
-	exchangeUseCase := usecase.NewExchangeUseCase(exchangeRepo, bookRepo, userRepo, movementRepo)
-	locationUseCase := usecase.NewLocationUseCase(locationRepo)
+	userUseCase := usecase.NewUserUseCase(userRepo, sessionRepo, tokenRepo, movementRepo, mail, uow, cfg.JWTSecret, cfg.AppURL)
+	bookUseCase := usecase.NewBookUseCase(bookRepo, workRepo, movementRepo, exchangeRepo, userRepo, uploadRepo, bookMetadata, uow, cfg.LoanPeriod)
+	exchangeUseCase := usecase.NewExchangeUseCase(exchangeRepo, bookRepo, userRepo, movementRepo, uow, cfg.RenewalPeriod, cfg.MaxRenewals)
+	locationUseCase := usecase.NewLocationUseCase(locationRepo, userRepo)
+	reviewUseCase := usecase.NewReviewUseCase(reviewRepo, bookRepo, movementRepo)
+	workUseCase := usecase.NewWorkUseCase(workRepo, bookRepo, reviewRepo)
+	waitlistUseCase := usecase.NewWaitlistUseCase(waitlistRepo, userRepo, uow)
+	uploadUseCase := usecase.NewUploadUseCase(uploadRepo, blobs, cfg.UploadMaxSize)
+
+	// Фоновые задачи: advisory lock в Postgres гарантирует, что каждую задачу выполняет только один экземпляр
+	jobs, err := newScheduler(sqlDB, cfg, exchangeUseCase)
+	if err != nil {
+		log.Fatalf("Не удалось настроить фоновые задачи: %v", err)
+	}
 
 	// Initialize HTTP handlers
 	router := gin.Default()
-	http.NewRouter(router, userUseCase, bookUseCase, exchangeUseCase, locationUseCase, cfg)
+	http.NewRouter(router, userUseCase, bookUseCase, exchangeUseCase, locationUseCase, reviewUseCase, workUseCase, waitlistUseCase, uploadUseCase, jobs, cfg)
+
+	// ctx отменяется по SIGINT/SIGTERM (docker-compose stop) - это сигнал к остановке
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	jobs.Start(ctx)
+
+	server := &nethttp.Server{
+		Addr:         ":" + cfg.ServerPort,
+		Handler:      router,
+		ReadTimeout:  cfg.ServerReadTimeout,
+		WriteTimeout: cfg.ServerWriteTimeout,
+		IdleTimeout:  cfg.ServerIdleTimeout,
+	}
 
-	// Запускаем фоновую задачу для отмены просроченных бронирований
-	go startExpiredExchangesCron(exchangeUseCase)
 	// Start server
 	log.Printf("Server starting on port %s", cfg.ServerPort)
 	for _, ri := range router.Routes() {
 		println(ri.Method, ri.Path)
 	}
 
-	if err := router.Run(":" + cfg.ServerPort); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	var startErr error
+	select {
+	case startErr = <-serverErr:
+		log.Printf("Failed to start server: %v", startErr)
+		stop()
+	case <-ctx.Done():
+		log.Println("Shutdown signal received, stopping server...")
+	}
+
+	shutdown(server, jobs, sqlDB, cfg.ServerShutdownTimeout)
+	if startErr != nil {
+		os.Exit(1)
 	}
 }
 
-func startExpiredExchangesCron(exchangeUC *usecase.ExchangeUseCase) {
-	// Создаем тикер, который срабатывает каждый час
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
+// shutdown останавливает сервис по порядку: сначала перестаем принимать запросы и дожидаемся
+// активных, затем дожидаемся фоновых задач (их контекст уже отменен) и только потом закрываем пул БД
+func shutdown(server *nethttp.Server, jobs *scheduler.Scheduler, sqlDB *sql.DB, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	for range ticker.C {
-		log.Println("Running cron job to cancel expired exchanges...")
-		err := exchangeUC.CancelExpiredExchanges()
-		if err != nil {
-			log.Printf("Error during expired exchanges cron job: %v", err)
-		}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+	if err := jobs.Wait(ctx); err != nil {
+		log.Printf("Background jobs did not stop in time: %v", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("Failed to close database pool: %v", err)
+	}
+	log.Println("Server stopped")
+}
+
+// newScheduler регистрирует фоновые задачи сервиса
+func newScheduler(sqlDB *sql.DB, cfg *config.Config, exchangeUC *usecase.ExchangeUseCase) (*scheduler.Scheduler, error) {
+	jobs := scheduler.New(scheduler.NewPostgresLocker(sqlDB))
+
+	// Отмена просроченных бронирований
+	if err := jobs.Register(scheduler.Job{
+		Name:     "cancel_expired_exchanges",
+		Schedule: cfg.CancelExpiredSchedule,
+		Run: func(ctx context.Context) error {
+			return exchangeUC.CancelExpiredExchanges(ctx)
+		},
+	}); err != nil {
+		return nil, err
 	}
+
+	// Поиск просроченных выдач
+	if err := jobs.Register(scheduler.Job{
+		Name:     "mark_overdue_exchanges",
+		Schedule: cfg.MarkOverdueSchedule,
+		Run: func(ctx context.Context) error {
+			return exchangeUC.MarkOverdueExchanges(ctx)
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
 }
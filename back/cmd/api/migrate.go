@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bookvito/pkg/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  up            применить все новые миграции
+  down          откатить последнюю примененную миграцию
+  status        показать состояние миграций
+  to <version>  привести схему к версии (0 - откатить все)`
+
+// runMigrate выполняет подкоманду "migrate"
+func runMigrate(ctx context.Context, sqlDB *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", migrateUsage)
+	}
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	case "down":
+		if err := migrator.Down(ctx); err != nil {
+			return err
+		}
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("missing version\n%s", migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := migrator.To(ctx, version); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+
+	return printMigrationStatus(ctx, migrator)
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
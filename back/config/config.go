@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +19,36 @@ type Config struct {
 	DBSSLMode  string
 	ServerPort string
 	JWTSecret  string
+
+	DBAutoMigrate bool // Применять миграции при старте (иначе - только через "migrate up")
+
+	// Таймауты HTTP-сервера и время на корректную остановку
+	ServerReadTimeout     time.Duration
+	ServerWriteTimeout    time.Duration
+	ServerIdleTimeout     time.Duration
+	ServerShutdownTimeout time.Duration
+
+	LoanPeriod    time.Duration // Срок, на который книга выдается читателю
+	RenewalPeriod time.Duration // На сколько продлевается выдача за одно продление
+	MaxRenewals   int           // Сколько раз можно продлить одну выдачу
+
+	UploadDir     string // Каталог для загруженных картинок (локальный BlobStore)
+	UploadMaxSize int64  // Максимальный размер загружаемого файла, байт
+
+	ISBNDataset string // Файл JSON Lines с описаниями изданий по ISBN; пустой - поиск по ISBN отключен
+
+	// Письма: SMTP, если задан SMTPHost; иначе сохраняются в MailDir или пишутся в лог
+	AppURL       string // Адрес приложения для ссылок в письмах
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// Расписания фоновых задач (@hourly, @daily, "@every 15m" или "30m")
+	CancelExpiredSchedule string
+	MarkOverdueSchedule   string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -32,8 +65,64 @@ func LoadConfig() (*Config, error) {
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key-here"),
+		UploadDir:  getEnv("UPLOAD_DIR", "./uploads"),
+
+		ISBNDataset: os.Getenv("ISBN_DATASET"),
+
+		AppURL:       getEnv("APP_URL", "http://localhost:8080"),
+		MailFrom:     getEnv("MAIL_FROM", "Bookvito <noreply@bookvito.local>"),
+		MailDir:      os.Getenv("MAIL_DIR"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+
+		CancelExpiredSchedule: getEnv("JOB_CANCEL_EXPIRED_SCHEDULE", "@hourly"),
+		MarkOverdueSchedule:   getEnv("JOB_MARK_OVERDUE_SCHEDULE", "@hourly"),
+	}
+
+	var err error
+	if cfg.ServerReadTimeout, err = getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ServerWriteTimeout, err = getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ServerIdleTimeout, err = getEnvDuration("SERVER_IDLE_TIMEOUT", 60*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ServerShutdownTimeout, err = getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 20*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.DBAutoMigrate, err = getEnvBool("DB_AUTO_MIGRATE", true); err != nil {
+		return nil, err
 	}
 
+	loanPeriod, err := getEnvDuration("LOAN_PERIOD", 14*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LoanPeriod = loanPeriod
+
+	renewalPeriod, err := getEnvDuration("LOAN_RENEWAL_PERIOD", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RenewalPeriod = renewalPeriod
+
+	maxRenewals, err := getEnvInt("LOAN_MAX_RENEWALS", 2)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxRenewals = maxRenewals
+
+	uploadMaxSize, err := getEnvInt("UPLOAD_MAX_SIZE", 10<<20)
+	if err != nil {
+		return nil, err
+	}
+	cfg.UploadMaxSize = int64(uploadMaxSize)
+
 	return cfg, nil
 }
 
@@ -45,3 +134,42 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvDuration parses an environment variable as time.Duration (e.g. "336h") or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// getEnvBool parses an environment variable as bool ("true", "false", "1", "0") or returns a default value
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// getEnvInt parses an environment variable as int or returns a default value
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
@@ -9,6 +9,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	golang.org/x/crypto v0.40.0
+	golang.org/x/image v0.29.0
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/gorm v1.25.10
 )
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bookvito/internal/domain"
+	"bookvito/pkg/scheduler"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// JobStatusProvider отдает состояние фоновых задач (реализуется scheduler.Scheduler)
+type JobStatusProvider interface {
+	Statuses() []scheduler.Status
+}
+
+type AdminHandler struct {
+	jobs       JobStatusProvider
+	userUC     domain.UserUseCase
+	exchangeUC domain.ExchangeUseCase
+}
+
+func NewAdminHandler(jobs JobStatusProvider, userUC domain.UserUseCase, exchangeUC domain.ExchangeUseCase) *AdminHandler {
+	return &AdminHandler{jobs: jobs, userUC: userUC, exchangeUC: exchangeUC}
+}
+
+// ListJobs returns last run, duration and error of every background job
+func (h *AdminHandler) ListJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, h.jobs.Statuses())
+}
+
+// ListUsers returns a page of users, oldest first; q searches email and name, role filters by role
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+	filter := domain.UserFilter{Query: c.Query("q"), Role: domain.UserRole(c.Query("role"))}
+
+	users, err := h.userUC.ListUsers(actorID, filter, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUser returns a user with the current ban
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	actorID, userID, ok := h.actorAndUser(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUC.GetUser(actorID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ListUserExchanges returns a page of the user's exchanges, newest first
+func (h *AdminHandler) ListUserExchanges(c *gin.Context) {
+	actorID, userID, ok := h.actorAndUser(c)
+	if !ok {
+		return
+	}
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	exchanges, err := h.exchangeUC.ListUserExchanges(actorID, userID, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, exchanges)
+}
+
+// GetUserHistory returns a page of book movements initiated by the user
+func (h *AdminHandler) GetUserHistory(c *gin.Context) {
+	actorID, userID, ok := h.actorAndUser(c)
+	if !ok {
+		return
+	}
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.userUC.GetUserHistory(actorID, userID, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+type ChangeRoleRequest struct {
+	Role domain.UserRole `json:"role" binding:"required"`
+}
+
+// ChangeRole assigns a role to the user
+func (h *AdminHandler) ChangeRole(c *gin.Context) {
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actorID, userID, ok := h.actorAndUser(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUC.ChangeRole(actorID, userID, req.Role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+type BanRequest struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"` // Без срока - бессрочная блокировка
+}
+
+// Ban suspends the user until the given time or bans them permanently
+func (h *AdminHandler) Ban(c *gin.Context) {
+	var req BanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actorID, userID, ok := h.actorAndUser(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUC.BanUser(actorID, userID, req.Reason, req.Until)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// Unban lifts the user's ban
+func (h *AdminHandler) Unban(c *gin.Context) {
+	actorID, userID, ok := h.actorAndUser(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userUC.UnbanUser(actorID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// actorAndUser достает того, кто выполняет действие, и пользователя из пути
+func (h *AdminHandler) actorAndUser(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return actorID, userID, true
+}
@@ -1,15 +1,22 @@
 package http
 
 import (
+	"bookvito/internal/domain"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func AuthMiddleware(secret string) gin.HandlerFunc {
+// AccessChecker проверяет, что владелец токена все еще может пользоваться API (реализуется UserUseCase)
+type AccessChecker interface {
+	CheckAccess(userID uuid.UUID) (*domain.User, error)
+}
+
+func AuthMiddleware(secret string, access AccessChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -55,13 +62,70 @@ func AuthMiddleware(secret string) gin.HandlerFunc {
 			return
 		}
 
-		userRole, ok := claims["role"].(string)
-		if !ok {
-			log.Println("AuthMiddleware: role not found or is not a string in token")
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID format"})
+			return
+		}
+		// Токен подписан, но аккаунт могли удалить или заблокировать после его выдачи.
+		// Роль тоже берем из базы: смена роли действует без перевыпуска токена.
+		user, err := access.CheckAccess(userUUID)
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
 		}
 
 		c.Set("userId", userID)
-		c.Set("role", userRole)
+		c.Set("role", string(user.Role))
+		// В токенах, выданных до появления сессий, sid нет
+		if sessionID, ok := claims["sid"].(string); ok {
+			c.Set("sessionId", sessionID)
+		}
+		c.Next()
+	}
+}
+
+// currentUserID достает ID пользователя, который AuthMiddleware положил в контекст.
+// Если ID нет или он некорректен, сразу отвечает 401.
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIdRaw, ok := c.Get("userId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return uuid.Nil, false
+	}
+	userIDStr, ok := userIdRaw.(string)
+	if !ok || userIDStr == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID in token"})
+		return uuid.Nil, false
+	}
+	userUUID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID format"})
+		return uuid.Nil, false
+	}
+	return userUUID, true
+}
+
+// currentSessionID возвращает сессию, которой выдан access-токен, или uuid.Nil
+func currentSessionID(c *gin.Context) uuid.UUID {
+	sessionID, err := uuid.Parse(c.GetString("sessionId"))
+	if err != nil {
+		return uuid.Nil
+	}
+	return sessionID
+}
+
+// RequirePermission пропускает запрос, только если у роли пользователя есть право p.
+// Ставится после AuthMiddleware, которая кладет в контекст текущую роль из базы.
+// Сценарий сверяет право еще раз, поэтому транспорт - не единственная защита.
+func RequirePermission(p domain.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := domain.UserRole(c.GetString("role"))
+		if err := role.Authorize(p); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.Next()
 	}
 }
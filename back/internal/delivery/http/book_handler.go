@@ -2,7 +2,10 @@ package http
 
 import (
 	"bookvito/internal/domain"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	// "strconv"
 	"github.com/gin-gonic/gin"
@@ -18,9 +21,14 @@ func NewBookHandler(bookUC domain.BookUseCase) *BookHandler {
 }
 
 func (h *BookHandler) GetSummaryList(c *gin.Context) {
-	books, err := h.bookUC.GetSummaryBooksList()
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	books, err := h.bookUC.GetSummaryBooksList(page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -28,15 +36,157 @@ func (h *BookHandler) GetSummaryList(c *gin.Context) {
 }
 
 func (h *BookHandler) GetList(c *gin.Context) {
-	books, err := h.bookUC.GetBooksList()
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	books, err := h.bookUC.GetBooksList(page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, books)
 }
 
+type SearchBooksQuery struct {
+	Query  string `form:"q" binding:"required"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+// Search ищет книги по названию, автору и описанию: GET /books/search?q=...&limit=20&offset=0
+func (h *BookHandler) Search(c *gin.Context) {
+	var query SearchBooksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.bookUC.SearchBooks(query.Query, query.Limit, query.Offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ListBooksQuery - параметры каталога. Фильтры можно повторять (?status=available&status=requested),
+// а статус, состояние, пункт и владельца - еще и перечислять через запятую
+type ListBooksQuery struct {
+	Status      []string `form:"status"`
+	Condition   []string `form:"condition"`
+	Author      []string `form:"author"`
+	LocationID  []string `form:"location_id"`
+	OwnerID     []string `form:"owner_id"`
+	CreatedFrom string   `form:"created_from"` // RFC 3339 или YYYY-MM-DD
+	CreatedTo   string   `form:"created_to"`   // RFC 3339 (не включительно) или YYYY-MM-DD (включительно)
+	Sort        string   `form:"sort,default=created_at" binding:"oneof=title created_at rating"`
+	Order       string   `form:"order" binding:"omitempty,oneof=asc desc"`
+	PageParams
+}
+
+// List возвращает каталог с фильтрами, сортировкой и счетчиками для фильтров: GET /books?status=available&sort=rating
+func (h *BookHandler) List(c *gin.Context) {
+	var query ListBooksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := query.filter()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.bookUC.ListBooks(filter, query.request())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (q *ListBooksQuery) filter() (domain.BookFilter, error) {
+	filter := domain.BookFilter{
+		Authors: q.Author,
+		Sort:    domain.BookSort(q.Sort),
+		Desc:    domain.BookSort(q.Sort).DefaultDesc(),
+	}
+	if q.Order != "" {
+		filter.Desc = q.Order == "desc"
+	}
+	for _, s := range splitValues(q.Status) {
+		filter.Statuses = append(filter.Statuses, domain.BookStatus(s))
+	}
+	for _, s := range splitValues(q.Condition) {
+		filter.Conditions = append(filter.Conditions, domain.BookCondition(s))
+	}
+
+	var err error
+	if filter.LocationIDs, err = parseUUIDs(q.LocationID, "location_id"); err != nil {
+		return filter, err
+	}
+	if filter.OwnerIDs, err = parseUUIDs(q.OwnerID, "owner_id"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = parseDateParam(q.CreatedFrom, "created_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseDateParam(q.CreatedTo, "created_to", true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// splitValues разворачивает повторяющиеся и перечисленные через запятую значения параметра
+func splitValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func parseUUIDs(values []string, param string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, v := range splitValues(values) {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", param, v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseDateParam разбирает RFC 3339 или дату YYYY-MM-DD (UTC). Для конца диапазона дата
+// включает весь день: created_to=2024-05-01 превращается в created_at < 2024-05-02T00:00:00Z.
+func parseDateParam(value, param string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: use RFC 3339 or YYYY-MM-DD", param, value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
 func (h *BookHandler) GetByID(c *gin.Context) {
 	idParam := c.Param("id")
 	bookID, err := uuid.Parse(idParam)
@@ -58,6 +208,17 @@ func (h *BookHandler) GetByID(c *gin.Context) {
 	c.JSON(http.StatusOK, book)
 }
 
+// LookupISBN возвращает описание издания по ISBN для предзаполнения формы добавления книги
+func (h *BookHandler) LookupISBN(c *gin.Context) {
+	meta, err := h.bookUC.LookupISBN(c.Param("isbn"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, meta)
+}
+
 func (h *BookHandler) GetBookMovementHistory(c *gin.Context) {
 	idParam := c.Param("id")
 	bookID, err := uuid.Parse(idParam)
@@ -66,9 +227,14 @@ func (h *BookHandler) GetBookMovementHistory(c *gin.Context) {
 		return
 	}
 
-	history, err := h.bookUC.GetBookMovementHistory(bookID)
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.bookUC.GetBookMovementHistory(bookID, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -77,12 +243,16 @@ func (h *BookHandler) GetBookMovementHistory(c *gin.Context) {
 
 //MARK: important part
 
+// CreateBookRequest - название и автор необязательны, если передан ISBN известного издания:
+// недостающие поля заполняются из описания издания
 type CreateBookRequest struct {
-	Title             string               `json:"title" binding:"required"`
-	Author            string               `json:"author" binding:"required"`
+	Title             string               `json:"title"`
+	Author            string               `json:"author"`
+	ISBN              string               `json:"isbn"`
 	Description       string               `json:"description"`
-	Condition         domain.BookCondition `json:"condition" binding:"required,oneof=excellent good bad"`
+	Condition         domain.BookCondition `json:"condition" binding:"omitempty,oneof=excellent good bad"`
 	ImageURL          string               `json:"image_url"`
+	ImageID           *uuid.UUID           `json:"image_id"` // Загрузка из POST /uploads; заменяет image_url
 	CurrentLocationID *uuid.UUID           `json:"current_location_id"`
 }
 
@@ -113,14 +283,16 @@ func (h *BookHandler) Create(c *gin.Context) {
 	err := h.bookUC.CreateBook(&domain.Book{
 		Title:             req.Title,
 		Author:            req.Author,
+		ISBN:              req.ISBN,
 		Description:       req.Description,
 		Condition:         req.Condition,
 		ImageURL:          req.ImageURL,
+		ImageID:           req.ImageID,
 		CurrentLocationID: req.CurrentLocationID,
 		OwnerID:           uuid.MustParse(userIDStr),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -152,7 +324,7 @@ func (h *BookHandler) Request(c *gin.Context) {
 
 	err = h.bookUC.Request(req.BookID, userUUID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -162,11 +334,7 @@ func (h *BookHandler) Request(c *gin.Context) {
 
 type ReturnBookRequest struct {
 	Id                uuid.UUID            `json:"book_id" binding:"required"`
-	Title             string               `json:"title" binding:"required"`
-	Author            string               `json:"author" binding:"required"`
-	Description       string               `json:"description"`
 	Condition         domain.BookCondition `json:"condition" binding:"required,oneof=excellent good bad"`
-	ImageURL          string               `json:"image_url"`
 	CurrentLocationID *uuid.UUID           `json:"current_location_id"`
 }
 
@@ -190,17 +358,13 @@ func (h *BookHandler) Return(c *gin.Context) {
 
 	book := &domain.Book{
 		ID:                req.Id,
-		Title:             req.Title,
-		Author:            req.Author,
-		Description:       req.Description,
 		Condition:         req.Condition,
-		ImageURL:          req.ImageURL,
 		CurrentLocationID: req.CurrentLocationID,
 	}
 
 	err := h.bookUC.Return(book, uuid.MustParse(userIDStr))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "book returned successfully"})
@@ -232,7 +396,7 @@ func (h *BookHandler) Borrow(c *gin.Context) {
 
 	err = h.bookUC.Borrow(req.BookID, userUUID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -267,10 +431,114 @@ func (h *BookHandler) Delete(c *gin.Context) {
 
 	err = h.bookUC.DeleteBook(req.BookID, userUUID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "book delete successfully"})
 
 }
+
+type UpdateBookRequest struct {
+	Title       string               `json:"title" binding:"required"`
+	Author      string               `json:"author" binding:"required"`
+	ISBN        string               `json:"isbn"`
+	Description string               `json:"description"`
+	Condition   domain.BookCondition `json:"condition" binding:"required,oneof=excellent good bad"`
+	ImageURL    string               `json:"image_url"`
+	ImageID     *uuid.UUID           `json:"image_id"` // Загрузка из POST /uploads; заменяет image_url
+}
+
+func (h *BookHandler) Update(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	var req UpdateBookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	book, err := h.bookUC.UpdateBook(&domain.Book{
+		ID:          bookID,
+		Title:       req.Title,
+		Author:      req.Author,
+		ISBN:        req.ISBN,
+		Description: req.Description,
+		Condition:   req.Condition,
+		ImageURL:    req.ImageURL,
+		ImageID:     req.ImageID,
+	}, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+func (h *BookHandler) Archive(c *gin.Context) {
+	h.changeStatus(c, h.bookUC.ArchiveBook, "book archived successfully")
+}
+
+func (h *BookHandler) Unarchive(c *gin.Context) {
+	h.changeStatus(c, h.bookUC.UnarchiveBook, "book unarchived successfully")
+}
+
+func (h *BookHandler) changeStatus(c *gin.Context, change func(bookID, userID uuid.UUID) error, message string) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := change(bookID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+type TransferBookRequest struct {
+	NewOwnerID uuid.UUID `json:"new_owner_id" binding:"required"`
+}
+
+func (h *BookHandler) Transfer(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	var req TransferBookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.bookUC.TransferBook(bookID, userID, req.NewOwnerID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "book ownership transferred successfully"})
+}
@@ -0,0 +1,1443 @@
+package http_test
+
+import (
+	"bookvito/internal/domain"
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestBookLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	ownerToken := s.register("owner@example.com", "Владелец")
+	readerToken := s.register("reader@example.com", "Читатель")
+	reader := s.me(readerToken)
+
+	bookID := s.createBook(ownerToken, "Пикник на обочине")
+	book := s.getBook(bookID)
+	if book.Status != domain.BookAvailable || book.OwnerID != s.me(ownerToken).ID {
+		t.Fatalf("unexpected book after create: %+v", book)
+	}
+
+	// Запрос: книга забронирована читателем
+	s.do(http.MethodPost, "/api/v1/books/request", readerToken, map[string]any{"book_id": bookID}).
+		expect(t, http.StatusOK)
+	if status := s.getBook(bookID).Status; status != domain.BookRequested {
+		t.Fatalf("expected status requested, got %s", status)
+	}
+	s.do(http.MethodPost, "/api/v1/books/request", ownerToken, map[string]any{"book_id": bookID}).
+		expectError(t, http.StatusConflict, domain.ErrBookNotAvailable.Error())
+	s.do(http.MethodPut, "/api/v1/books/return", readerToken, map[string]any{"book_id": bookID, "condition": "good"}).
+		expectError(t, http.StatusConflict, domain.ErrInvalidTransition.Error()+": only borrowed books can be returned")
+
+	var page domain.Page[domain.Exchange]
+	s.do(http.MethodGet, "/api/v1/exchanges/my", readerToken, nil).expect(t, http.StatusOK).decode(t, &page)
+	my := page.Items
+	if len(my) != 1 || my[0].BookID != bookID || my[0].Status != domain.ExchangeRequested || my[0].UserID != reader.ID {
+		t.Fatalf("unexpected reader exchanges: %+v", my)
+	}
+	exchangeID := my[0].ID
+
+	// Забрать книгу может только тот, кто ее забронировал
+	s.do(http.MethodPut, "/api/v1/books/borrow", ownerToken, map[string]any{"book_id": bookID}).
+		expect(t, http.StatusForbidden)
+	s.do(http.MethodPut, "/api/v1/books/borrow", readerToken, map[string]any{"book_id": bookID}).
+		expect(t, http.StatusOK)
+	if status := s.getBook(bookID).Status; status != domain.BookBorrowed {
+		t.Fatalf("expected status borrowed, got %s", status)
+	}
+
+	var exchange domain.Exchange
+	s.do(http.MethodGet, "/api/v1/exchanges/"+exchangeID.String(), readerToken, nil).
+		expect(t, http.StatusOK).decode(t, &exchange)
+	if exchange.Status != domain.ExchangeBorrowed || exchange.DueAt == nil || exchange.BorrowedAt == nil {
+		t.Fatalf("unexpected exchange after borrow: %+v", exchange)
+	}
+	if loan := exchange.DueAt.Sub(*exchange.BorrowedAt); loan != 14*24*time.Hour {
+		t.Fatalf("expected 14 day loan, got %s", loan)
+	}
+
+	// Возврат с обновленным состоянием книги; описание книги меняет только владелец,
+	// поэтому лишние поля в запросе игнорируются
+	returnBody := map[string]any{
+		"book_id": bookID, "title": "Чужое название", "author": "Чужой автор", "condition": "bad",
+	}
+	s.do(http.MethodPut, "/api/v1/books/return", readerToken, returnBody).expect(t, http.StatusOK)
+	returned := s.getBook(bookID)
+	if returned.Status != domain.BookAvailable || returned.Condition != domain.ConditionBad ||
+		returned.Title != book.Title || returned.Author != book.Author {
+		t.Fatalf("unexpected book after return: %+v", returned)
+	}
+	s.do(http.MethodGet, "/api/v1/exchanges/"+exchangeID.String(), readerToken, nil).
+		expect(t, http.StatusOK).decode(t, &exchange)
+	if exchange.Status != domain.ExchangeReturned {
+		t.Fatalf("expected exchange status returned, got %s", exchange.Status)
+	}
+
+	// Доступную книгу нельзя пометить удаленной - только взятую
+	s.do(http.MethodDelete, "/api/v1/books/delete", readerToken, map[string]any{"book_id": bookID}).
+		expect(t, http.StatusConflict)
+
+	s.do(http.MethodPost, "/api/v1/books/request", readerToken, map[string]any{"book_id": bookID}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/borrow", readerToken, map[string]any{"book_id": bookID}).expect(t, http.StatusOK)
+	s.do(http.MethodDelete, "/api/v1/books/delete", readerToken, map[string]any{"book_id": bookID}).
+		expect(t, http.StatusOK)
+	if status := s.getBook(bookID).Status; status != domain.BookDeleted {
+		t.Fatalf("expected status deleted, got %s", status)
+	}
+
+	// История: created, requested, borrowed, returned, requested, borrowed, deleted
+	history, err := s.repos.Movements.GetByBookID(bookID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	want := []string{
+		domain.ActionDeleted, domain.ActionBorrowed, domain.ActionRequested, domain.ActionReturned,
+		domain.ActionBorrowed, domain.ActionRequested, domain.ActionCreated,
+	}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d history records, got %d", len(want), len(history))
+	}
+	for i, m := range history {
+		if m.Action != want[i] {
+			t.Fatalf("history record %d is %q, want %q", i, m.Action, want[i])
+		}
+	}
+}
+
+func TestLoanRenewal(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	reader := s.register("reader@example.com", "Читатель")
+	waiter := s.register("waiter@example.com", "Ждущий")
+	bookID := s.createBook(owner, "Град обреченный")
+
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": bookID}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/borrow", reader, map[string]any{"book_id": bookID}).expect(t, http.StatusOK)
+	var page domain.Page[domain.Exchange]
+	s.do(http.MethodGet, "/api/v1/exchanges/my", reader, nil).expect(t, http.StatusOK).decode(t, &page)
+	if len(page.Items) != 1 || page.Items[0].DueAt == nil {
+		t.Fatalf("unexpected reader exchanges: %+v", page.Items)
+	}
+	loan := page.Items[0]
+	extend := "/api/v1/exchanges/" + loan.ID.String() + "/extend"
+
+	// Продлевать чужую выдачу нельзя; своя сдвигается на RenewalPeriod
+	s.do(http.MethodPut, extend, waiter, nil).expect(t, http.StatusForbidden)
+	var renewed domain.Exchange
+	s.do(http.MethodPut, extend, reader, nil).expect(t, http.StatusOK).decode(t, &renewed)
+	if renewed.Renewals != 1 || renewed.DueAt == nil || !renewed.DueAt.Equal(loan.DueAt.Add(7*24*time.Hour)) {
+		t.Fatalf("unexpected exchange after renewal: %+v", renewed)
+	}
+
+	// Пока книгу кто-то ждет, продлить нельзя
+	s.do(http.MethodPost, "/api/v1/books/"+bookID.String()+"/waitlist", waiter, nil).expect(t, http.StatusCreated)
+	s.do(http.MethodPut, extend, reader, nil).expectError(t, http.StatusConflict, domain.ErrRenewalBlocked.Error())
+	s.do(http.MethodDelete, "/api/v1/books/"+bookID.String()+"/waitlist", waiter, nil).expect(t, http.StatusOK)
+
+	// Лимит продлений - MaxRenewals
+	var again domain.Exchange
+	s.do(http.MethodPut, extend, reader, nil).expect(t, http.StatusOK).decode(t, &again)
+	if again.Renewals != 2 || !again.DueAt.Equal(loan.DueAt.Add(14*24*time.Hour)) {
+		t.Fatalf("unexpected exchange after second renewal: %+v", again)
+	}
+	s.do(http.MethodPut, extend, reader, nil).expectError(t, http.StatusConflict, domain.ErrRenewalLimit.Error())
+
+	// В истории по записи на каждое продление; статус книги не меняется
+	history, err := s.repos.Movements.GetByBookID(bookID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	var renewals int
+	for _, m := range history {
+		if m.Action != domain.ActionRenewed {
+			continue
+		}
+		renewals++
+		if m.ExchangeID == nil || *m.ExchangeID != loan.ID || m.PreviousStatus != domain.BookBorrowed || m.NewStatus != domain.BookBorrowed {
+			t.Fatalf("unexpected renewal record: %+v", m)
+		}
+	}
+	if renewals != 2 || history[0].Action != domain.ActionRenewed ||
+		history[0].Notes != "Loan renewed until "+again.DueAt.Format(time.DateOnly) {
+		t.Fatalf("expected two renewal records, latest first, got %+v", history)
+	}
+}
+
+func TestExchangeJobs(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	reader := s.register("reader@example.com", "Читатель")
+	moder := s.register("moder@example.com", "Модератор")
+	s.setRole("moder@example.com", domain.RoleModer)
+
+	reserved := s.createBook(owner, "Обитаемый остров")
+	late := s.createBook(owner, "Жук в муравейнике")
+	onTime := s.createBook(owner, "Волны гасят ветер")
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": reserved}).expect(t, http.StatusOK)
+	for _, book := range []uuid.UUID{late, onTime} {
+		s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": book}).expect(t, http.StatusOK)
+		s.do(http.MethodPut, "/api/v1/books/borrow", reader, map[string]any{"book_id": book}).expect(t, http.StatusOK)
+	}
+
+	// exchangeOf возвращает единственную бронь книги
+	exchangeOf := func(bookID uuid.UUID) *domain.Exchange {
+		t.Helper()
+		exchanges, err := s.repos.Exchanges.GetByBookID(bookID)
+		if err != nil || len(exchanges) != 1 {
+			t.Fatalf("expected one exchange for %s, got %d (%v)", bookID, len(exchanges), err)
+		}
+		return exchanges[0]
+	}
+	// latestMovement возвращает последнюю запись истории книги
+	latestMovement := func(bookID uuid.UUID) *domain.BookMovementHistory {
+		t.Helper()
+		history, err := s.repos.Movements.GetByBookID(bookID)
+		if err != nil || len(history) == 0 {
+			t.Fatalf("history of %s: %v", bookID, err)
+		}
+		return history[0]
+	}
+
+	// Сдвигаем сроки в прошлое вместо того, чтобы ждать
+	past := time.Now().Add(-time.Hour)
+	expiring := exchangeOf(reserved)
+	expiring.ExpiresAt = &past
+	overdue := exchangeOf(late)
+	overdue.DueAt = &past
+	for _, ex := range []*domain.Exchange{expiring, overdue} {
+		if err := s.repos.Exchanges.Update(ex); err != nil {
+			t.Fatalf("move deadline: %v", err)
+		}
+	}
+
+	// Истекшая бронь отменяется системой, книга снова доступна
+	if err := s.exchanges.CancelExpiredExchanges(context.Background()); err != nil {
+		t.Fatalf("cancel expired: %v", err)
+	}
+	if ex := exchangeOf(reserved); ex.Status != domain.ExchangeCancelled {
+		t.Fatalf("expired reservation must be cancelled, got %s", ex.Status)
+	}
+	if status := s.getBook(reserved).Status; status != domain.BookAvailable {
+		t.Fatalf("book must be available again, got %s", status)
+	}
+	if m := latestMovement(reserved); m.Action != domain.ActionRequestCancelled || m.UserID != nil ||
+		m.Notes != "Book request expired and was automatically cancelled" {
+		t.Fatalf("unexpected history after expiry: %+v", m)
+	}
+
+	// Просроченная выдача помечается, книга остается у читателя; выдача в срок не трогается
+	if err := s.exchanges.MarkOverdueExchanges(context.Background()); err != nil {
+		t.Fatalf("mark overdue: %v", err)
+	}
+	if ex := exchangeOf(late); ex.Status != domain.ExchangeOverdue {
+		t.Fatalf("late loan must be overdue, got %s", ex.Status)
+	}
+	if ex := exchangeOf(onTime); ex.Status != domain.ExchangeBorrowed {
+		t.Fatalf("loan in time must stay borrowed, got %s", ex.Status)
+	}
+	if status := s.getBook(late).Status; status != domain.BookBorrowed {
+		t.Fatalf("overdue book stays borrowed, got %s", status)
+	}
+	m := latestMovement(late)
+	if m.Action != domain.ActionOverdue || m.PreviousStatus != domain.BookBorrowed || m.NewStatus != domain.BookBorrowed {
+		t.Fatalf("unexpected history after overdue: %+v", m)
+	}
+
+	// Повторный запуск ничего не меняет
+	if err := s.exchanges.MarkOverdueExchanges(context.Background()); err != nil {
+		t.Fatalf("mark overdue again: %v", err)
+	}
+	if again := latestMovement(late); again.ID != m.ID {
+		t.Fatalf("second run must not add history, got %+v", again)
+	}
+
+	// Список просроченных видят только модераторы
+	s.do(http.MethodGet, "/api/v1/exchanges/overdue", reader, nil).expect(t, http.StatusForbidden)
+	var list []domain.Exchange
+	s.do(http.MethodGet, "/api/v1/exchanges/overdue", moder, nil).expect(t, http.StatusOK).decode(t, &list)
+	if len(list) != 1 || list[0].ID != overdue.ID || list[0].BookID != late {
+		t.Fatalf("unexpected overdue list: %+v", list)
+	}
+
+	// Просроченную выдачу нельзя продлить, но можно вернуть
+	s.do(http.MethodPut, "/api/v1/exchanges/"+overdue.ID.String()+"/extend", reader, nil).
+		expectError(t, http.StatusConflict, domain.ErrLoanOverdue.Error())
+	s.do(http.MethodPut, "/api/v1/books/return", reader, map[string]any{
+		"book_id": late, "condition": "good",
+	}).expect(t, http.StatusOK)
+	if ex := exchangeOf(late); ex.Status != domain.ExchangeReturned {
+		t.Fatalf("overdue loan must be returned, got %s", ex.Status)
+	}
+	s.do(http.MethodGet, "/api/v1/exchanges/overdue", moder, nil).expect(t, http.StatusOK).decode(t, &list)
+	if len(list) != 0 {
+		t.Fatalf("returned loan must leave the overdue list, got %+v", list)
+	}
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("reader@example.com", "Читатель")
+	if user := s.me(token); user.Email != "reader@example.com" || user.Role != domain.RoleUser {
+		t.Fatalf("unexpected profile: %+v", user)
+	}
+
+	s.do(http.MethodPost, "/api/v1/users/registration", "", map[string]string{
+		"email": "reader@example.com", "password": "secret123", "name": "Другой",
+	}).expectError(t, http.StatusBadRequest, "user with this email already exists")
+	s.do(http.MethodPost, "/api/v1/users/registration", "", map[string]string{
+		"email": "not-an-email", "password": "secret123", "name": "Другой",
+	}).expect(t, http.StatusBadRequest)
+
+	s.login("reader@example.com")
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{
+		"email": "reader@example.com", "password": "wrong-password",
+	}).expectError(t, http.StatusUnauthorized, "invalid email or password")
+
+	// Refresh токен одноразовый: после обновления старый больше не работает
+	var tokens domain.TokenResponse
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{
+		"email": "reader@example.com", "password": "secret123",
+	}).expect(t, http.StatusOK).decode(t, &tokens)
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}).
+		expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}).
+		expectError(t, http.StatusUnauthorized, domain.ErrRefreshTokenReused.Error())
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": "not-a-token"}).
+		expectError(t, http.StatusUnauthorized, domain.ErrInvalidRefreshToken.Error())
+}
+
+func TestSessions(t *testing.T) {
+	s := newTestServer(t)
+	s.register("reader@example.com", "Читатель")
+	login := func(device string) domain.TokenResponse {
+		var tokens domain.TokenResponse
+		s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{
+			"email": "reader@example.com", "password": "secret123", "device": device,
+		}).expect(t, http.StatusOK).decode(t, &tokens)
+		return tokens
+	}
+	refresh := func(token string) *response {
+		return s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": token})
+	}
+	sessions := func(token string) []domain.Session {
+		var list []domain.Session
+		s.do(http.MethodGet, "/api/v1/users/me/sessions", token, nil).expect(t, http.StatusOK).decode(t, &list)
+		return list
+	}
+
+	// Вход на телефоне не выбивает планшет: у каждого устройства своя сессия
+	phone := login("Телефон")
+	tablet := login("Планшет")
+	refresh(tablet.RefreshToken).expect(t, http.StatusOK).decode(t, &tablet)
+	list := sessions(phone.AccessToken)
+	// Третья сессия - со входа при регистрации
+	if len(list) != 3 || list[0].Device != "Планшет" || list[1].Device != "Телефон" {
+		t.Fatalf("unexpected sessions: %+v", list)
+	}
+	for _, session := range list {
+		if session.Current != (session.Device == "Телефон") {
+			t.Fatalf("only the phone session must be current: %+v", list)
+		}
+	}
+
+	// Повтор уже обмененного токена отзывает сессию целиком, включая новый токен
+	rotated := domain.TokenResponse{}
+	refresh(phone.RefreshToken).expect(t, http.StatusOK).decode(t, &rotated)
+	refresh(phone.RefreshToken).expectError(t, http.StatusUnauthorized, domain.ErrRefreshTokenReused.Error())
+	refresh(rotated.RefreshToken).expectError(t, http.StatusUnauthorized, domain.ErrInvalidRefreshToken.Error())
+	refresh(tablet.RefreshToken).expect(t, http.StatusOK).decode(t, &tablet)
+	if list := sessions(tablet.AccessToken); len(list) != 2 {
+		t.Fatalf("expected 2 sessions after reuse, got %+v", list)
+	}
+
+	// Выход завершает только свою сессию
+	s.do(http.MethodPost, "/api/v1/users/logout", "", map[string]string{"refresh_token": tablet.RefreshToken}).expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/users/logout", "", map[string]string{"refresh_token": tablet.RefreshToken}).
+		expectError(t, http.StatusUnauthorized, domain.ErrInvalidRefreshToken.Error())
+	refresh(tablet.RefreshToken).expect(t, http.StatusUnauthorized)
+	laptop := login("Ноутбук")
+	if list := sessions(laptop.AccessToken); len(list) != 2 {
+		t.Fatalf("expected 2 sessions after logout, got %+v", list)
+	}
+
+	// Выход отовсюду
+	s.do(http.MethodPost, "/api/v1/users/logout-all", "", nil).expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/users/logout-all", laptop.AccessToken, nil).expect(t, http.StatusOK)
+	refresh(laptop.RefreshToken).expect(t, http.StatusUnauthorized)
+	if list := sessions(laptop.AccessToken); len(list) != 0 {
+		t.Fatalf("expected no sessions after logout-all, got %+v", list)
+	}
+
+	// Смена пароля тоже завершает остальные сессии
+	other := login("Телефон")
+	var changed domain.TokenResponse
+	s.do(http.MethodPost, "/api/v1/users/me/password", other.AccessToken, map[string]string{"old_password": "secret123", "new_password": "changed123"}).
+		expect(t, http.StatusOK).decode(t, &changed)
+	refresh(other.RefreshToken).expect(t, http.StatusUnauthorized)
+	if list := sessions(changed.AccessToken); len(list) != 1 || !list[0].Current {
+		t.Fatalf("expected only the new session after password change, got %+v", list)
+	}
+}
+
+func TestAuthMiddlewareRejects(t *testing.T) {
+	s := newTestServer(t)
+	s.register("reader@example.com", "Читатель")
+	userID := uuid.New().String()
+
+	sign := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+		return token
+	}
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"userId": userID, "role": "user", "exp": time.Now().Add(time.Hour).Unix()}
+	}
+
+	expired := valid()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	noUserID := valid()
+	delete(noUserID, "userId")
+	badUserID := valid()
+	badUserID["userId"] = "not-a-uuid"
+
+	tests := []struct {
+		name          string
+		authorization string
+		code          int
+		message       string
+	}{
+		{"missing header", "", http.StatusUnauthorized, "Authorization header missing"},
+		{"wrong scheme", "Token abc", http.StatusUnauthorized, "Invalid Authorization header format"},
+		{"extra parts", "Bearer a b", http.StatusUnauthorized, "Invalid Authorization header format"},
+		{"garbage token", "Bearer garbage", http.StatusUnauthorized, "Invalid token"},
+		{"wrong secret", "Bearer " + sign(jwt.SigningMethodHS256, []byte("other-secret"), valid()), http.StatusUnauthorized, "Invalid token"},
+		{"none algorithm", "Bearer " + sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid()), http.StatusUnauthorized, "Invalid token"},
+		{"expired", "Bearer " + sign(jwt.SigningMethodHS256, []byte(testJWTSecret), expired), http.StatusUnauthorized, "Invalid token"},
+		{"no userId", "Bearer " + sign(jwt.SigningMethodHS256, []byte(testJWTSecret), noUserID), http.StatusUnauthorized, "userId not found in token"},
+		{"malformed userId", "Bearer " + sign(jwt.SigningMethodHS256, []byte(testJWTSecret), badUserID), http.StatusUnauthorized, "invalid user ID format"},
+		{"unknown user", "Bearer " + sign(jwt.SigningMethodHS256, []byte(testJWTSecret), valid()), http.StatusUnauthorized, domain.ErrAccountDeleted.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.doRaw(http.MethodGet, "/api/v1/exchanges/my", tt.authorization).expectError(t, tt.code, tt.message)
+		})
+	}
+
+	// Публичные маршруты работают без токена, защищенные книги - нет
+	s.doRaw(http.MethodGet, "/api/v1/books/list", "").expect(t, http.StatusOK)
+	s.doRaw(http.MethodGet, "/health", "").expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/books/create", "", map[string]string{"title": "x", "author": "y", "condition": "good"}).
+		expectError(t, http.StatusUnauthorized, "Authorization header missing")
+}
+
+func TestRoleRestrictedRoutes(t *testing.T) {
+	s := newTestServer(t)
+	userToken := s.register("reader@example.com", "Читатель")
+	s.register("moder@example.com", "Модератор")
+	s.register("admin@example.com", "Администратор")
+	s.setRole("moder@example.com", domain.RoleModer)
+	s.setRole("admin@example.com", domain.RoleAdmin)
+	moderToken := s.login("moder@example.com")
+	adminToken := s.login("admin@example.com")
+
+	s.do(http.MethodGet, "/api/v1/exchanges/overdue", userToken, nil).expect(t, http.StatusForbidden)
+	s.do(http.MethodGet, "/api/v1/exchanges/overdue", moderToken, nil).expect(t, http.StatusOK)
+
+	s.do(http.MethodGet, "/api/v1/admin/jobs", moderToken, nil).expect(t, http.StatusForbidden)
+	var jobs []map[string]any
+	s.do(http.MethodGet, "/api/v1/admin/jobs", adminToken, nil).expect(t, http.StatusOK).decode(t, &jobs)
+
+	// Пункты выдачи меняет только администратор
+	create := map[string]string{"name": "Библиотека", "address": "ул. Ленина, 1"}
+	s.do(http.MethodPost, "/api/v1/locations", "", create).expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/locations", userToken, create).
+		expectError(t, http.StatusForbidden, "action is not allowed for this user: requires location:write")
+	s.do(http.MethodPost, "/api/v1/locations", moderToken, create).expect(t, http.StatusForbidden)
+	s.do(http.MethodPost, "/api/v1/locations", adminToken, create).expect(t, http.StatusCreated)
+	var locations []domain.Location
+	s.do(http.MethodGet, "/api/v1/locations/getAll", "", nil).expect(t, http.StatusOK).decode(t, &locations)
+	if len(locations) != 1 || locations[0].Name != "Библиотека" {
+		t.Fatalf("unexpected locations after create: %+v", locations)
+	}
+	location := locations[0]
+
+	path := "/api/v1/locations/" + location.ID.String()
+	update := map[string]string{"name": "Читальный зал", "address": "ул. Ленина, 1"}
+	s.do(http.MethodPut, path, userToken, update).expect(t, http.StatusForbidden)
+	s.do(http.MethodPut, path, adminToken, update).expect(t, http.StatusOK)
+	s.do(http.MethodGet, path, "", nil).expect(t, http.StatusOK).decode(t, &location)
+	if location.Name != "Читальный зал" {
+		t.Fatalf("location was not updated: %+v", location)
+	}
+	s.do(http.MethodPut, "/api/v1/locations/"+uuid.NewString(), adminToken, update).expect(t, http.StatusNotFound)
+	s.do(http.MethodDelete, path, moderToken, nil).expect(t, http.StatusForbidden)
+	s.do(http.MethodDelete, path, adminToken, nil).expect(t, http.StatusOK)
+	s.do(http.MethodDelete, path, adminToken, nil).expect(t, http.StatusNotFound)
+
+	// Понижение роли действует сразу, даже для уже выданных токенов
+	s.setRole("admin@example.com", domain.RoleUser)
+	s.do(http.MethodPost, "/api/v1/locations", adminToken, create).expect(t, http.StatusForbidden)
+	s.do(http.MethodGet, "/api/v1/admin/users", adminToken, nil).expect(t, http.StatusForbidden)
+	s.setRole("moder@example.com", domain.RoleUser)
+	s.do(http.MethodGet, "/api/v1/exchanges/overdue", moderToken, nil).expect(t, http.StatusForbidden)
+}
+
+func TestAdminUserManagement(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	reader := s.register("reader@example.com", "Читатель")
+	moder := s.register("moder@example.com", "Модератор")
+	s.register("admin@example.com", "Администратор")
+	s.setRole("admin@example.com", domain.RoleAdmin)
+	admin := s.login("admin@example.com")
+	readerID, moderID, adminID := s.me(reader).ID.String(), s.me(moder).ID.String(), s.me(admin).ID.String()
+	users := "/api/v1/admin/users/"
+
+	// Роли назначает только администратор и не себе; новая роль действует без нового токена
+	s.do(http.MethodPut, users+moderID+"/role", moder, map[string]string{"role": "moder"}).expect(t, http.StatusForbidden)
+	s.do(http.MethodPut, users+moderID+"/role", admin, map[string]string{"role": "root"}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidRole.Error())
+	s.do(http.MethodPut, users+adminID+"/role", admin, map[string]string{"role": "user"}).
+		expectError(t, http.StatusForbidden, domain.ErrSelfModeration.Error())
+	var user domain.User
+	s.do(http.MethodPut, users+moderID+"/role", admin, map[string]string{"role": "moder"}).expect(t, http.StatusOK).decode(t, &user)
+	if user.Role != domain.RoleModer {
+		t.Fatalf("expected moder role, got %+v", user)
+	}
+	s.do(http.MethodGet, "/api/v1/exchanges/overdue", moder, nil).expect(t, http.StatusOK)
+
+	// Поиск по почте или имени и отбор по роли
+	var page domain.Page[domain.User]
+	s.do(http.MethodGet, "/api/v1/admin/users?q=READER", moder, nil).expect(t, http.StatusOK).decode(t, &page)
+	if len(page.Items) != 1 || page.Items[0].ID.String() != readerID {
+		t.Fatalf("unexpected search result: %+v", page)
+	}
+	s.do(http.MethodGet, "/api/v1/admin/users?role=moder&with_total=true", admin, nil).expect(t, http.StatusOK).decode(t, &page)
+	if page.Total == nil || *page.Total != 1 || page.Items[0].ID.String() != moderID {
+		t.Fatalf("unexpected role filter result: %+v", page)
+	}
+	s.do(http.MethodGet, "/api/v1/admin/users?role=root", admin, nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/admin/users", reader, nil).expect(t, http.StatusForbidden)
+
+	// Брони и история пользователя
+	book := s.createBook(owner, "Пикник на обочине")
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": book}).expect(t, http.StatusOK)
+	var exchanges domain.Page[domain.Exchange]
+	s.do(http.MethodGet, users+readerID+"/exchanges", moder, nil).expect(t, http.StatusOK).decode(t, &exchanges)
+	if len(exchanges.Items) != 1 || exchanges.Items[0].BookID != book || exchanges.Items[0].Status != domain.ExchangeRequested {
+		t.Fatalf("unexpected exchanges: %+v", exchanges)
+	}
+	var history domain.Page[domain.BookMovementHistory]
+	s.do(http.MethodGet, users+readerID+"/history", moder, nil).expect(t, http.StatusOK).decode(t, &history)
+	if len(history.Items) != 1 || history.Items[0].BookID != book {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	s.do(http.MethodGet, users+readerID+"/exchanges", reader, nil).expect(t, http.StatusForbidden)
+	s.do(http.MethodGet, users+uuid.NewString()+"/exchanges", admin, nil).expect(t, http.StatusNotFound)
+	s.do(http.MethodGet, users+"not-a-uuid", admin, nil).expect(t, http.StatusBadRequest)
+
+	// Блокировка на сутки: причина обязательна, модератор не блокирует администратора и себя
+	var tokens domain.TokenResponse
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "reader@example.com", "password": "secret123"}).
+		expect(t, http.StatusOK).decode(t, &tokens)
+	until := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	s.do(http.MethodPost, users+readerID+"/ban", moder, map[string]any{"until": until}).expect(t, http.StatusBadRequest)
+	s.do(http.MethodPost, users+readerID+"/ban", moder, map[string]any{"reason": "спам", "until": time.Now().Add(-time.Hour)}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidBan.Error())
+	s.do(http.MethodPost, users+adminID+"/ban", moder, map[string]any{"reason": "спам"}).expect(t, http.StatusForbidden)
+	s.do(http.MethodPost, users+moderID+"/ban", moder, map[string]any{"reason": "спам"}).
+		expectError(t, http.StatusForbidden, domain.ErrSelfModeration.Error())
+	s.do(http.MethodPost, users+readerID+"/ban", moder, map[string]any{"reason": "спам", "until": until}).
+		expect(t, http.StatusOK).decode(t, &user)
+	if !user.Banned(time.Now()) || user.BanReason != "спам" || user.BannedBy == nil || user.BannedBy.String() != moderID {
+		t.Fatalf("unexpected ban: %+v", user.UserBan)
+	}
+
+	// Выданные токены перестают работать сразу, войти нельзя до конца блокировки
+	banned := "account is banned until " + until.Format(time.RFC3339) + ": спам"
+	s.do(http.MethodGet, "/api/v1/users/me", reader, nil).expectError(t, http.StatusForbidden, banned)
+	s.do(http.MethodGet, "/api/v1/users/me", tokens.AccessToken, nil).expect(t, http.StatusForbidden)
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}).
+		expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "reader@example.com", "password": "secret123"}).
+		expectError(t, http.StatusForbidden, banned)
+	if status := s.getBook(book).Status; status != domain.BookAvailable {
+		t.Fatalf("reservation of a banned user must be cancelled, book is %s", status)
+	}
+
+	// После снятия блокировки можно войти заново; завершенные сессии не возвращаются
+	var lifted domain.User
+	s.do(http.MethodDelete, users+readerID+"/ban", moder, nil).expect(t, http.StatusOK).decode(t, &lifted)
+	if lifted.BannedAt != nil || lifted.ID.String() != readerID {
+		t.Fatalf("ban must be lifted: %+v", lifted)
+	}
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}).
+		expect(t, http.StatusUnauthorized)
+	s.login("reader@example.com")
+
+	// Модератора блокирует только администратор; без срока блокировка бессрочная
+	s.do(http.MethodPost, users+moderID+"/ban", admin, map[string]any{"reason": "злоупотребление"}).expect(t, http.StatusOK)
+	s.do(http.MethodGet, "/api/v1/admin/users", moder, nil).expectError(t, http.StatusForbidden, "account is banned: злоупотребление")
+	var permanent domain.User
+	s.do(http.MethodGet, users+moderID, admin, nil).expect(t, http.StatusOK).decode(t, &permanent)
+	if permanent.BannedAt == nil || permanent.BannedUntil != nil {
+		t.Fatalf("expected permanent ban: %+v", permanent.UserBan)
+	}
+}
+
+func TestSearchBooks(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("owner@example.com", "Владелец")
+	master := s.createBook(token, "Мастер и Маргарита")
+	s.createBook(token, "Белая гвардия")
+
+	var page domain.BookSearchPage
+	s.do(http.MethodGet, "/api/v1/books/search?q=%D0%BC%D0%B0%D1%81%D1%82%D0%B5%D1%80", "", nil).
+		expect(t, http.StatusOK).decode(t, &page)
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].ID != master {
+		t.Fatalf("unexpected search page: %+v", page)
+	}
+	if page.Items[0].Headline != "<mark>Мастер</mark> и Маргарита" || page.Limit != 20 {
+		t.Fatalf("unexpected headline %q or limit %d", page.Items[0].Headline, page.Limit)
+	}
+
+	s.do(http.MethodGet, "/api/v1/books/search?q=nothing", "", nil).expect(t, http.StatusOK).decode(t, &page)
+	if page.Total != 0 || page.Items == nil {
+		t.Fatalf("expected empty items list, got %+v", page)
+	}
+
+	s.do(http.MethodGet, "/api/v1/books/search", "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books/search?q=%20%20", "", nil).expectError(t, http.StatusBadRequest, domain.ErrEmptySearchQuery.Error())
+	s.do(http.MethodGet, "/api/v1/books/search?q=x&limit=0", "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books/search?q=x&limit=101", "", nil).expect(t, http.StatusBadRequest)
+}
+
+func TestListBooks(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	reader := s.register("reader@example.com", "Читатель")
+	ownerID := s.me(owner).ID
+
+	master := s.createBook(owner, "Мастер и Маргарита")
+	guard := s.createBook(owner, "Белая гвардия")
+	dogs := s.createBook(reader, "Собачье сердце")
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]string{"book_id": master.String()}).expect(t, http.StatusOK)
+
+	var page domain.BookListPage
+	s.do(http.MethodGet, "/api/v1/books?status=available&sort=title", "", nil).expect(t, http.StatusOK).decode(t, &page)
+	if page.Total != nil || page.HasMore || len(page.Items) != 2 || page.Items[0].ID != guard || page.Items[1].ID != dogs {
+		t.Fatalf("unexpected catalog page: %+v", page)
+	}
+	// Фасет статуса считается без фильтра по статусу - видно и забронированную книгу
+	want := []domain.FacetCount{{Value: "available", Count: 2}, {Value: "requested", Count: 1}}
+	if len(page.Facets.Status) != 2 || page.Facets.Status[0] != want[0] || page.Facets.Status[1] != want[1] {
+		t.Fatalf("unexpected status facet: %+v", page.Facets.Status)
+	}
+
+	var ownerPage domain.BookListPage
+	s.do(http.MethodGet, "/api/v1/books?owner_id="+ownerID.String()+"&status=available,requested&limit=1&with_total=true", "", nil).
+		expect(t, http.StatusOK).decode(t, &ownerPage)
+	if ownerPage.Total == nil || *ownerPage.Total != 2 || len(ownerPage.Items) != 1 || ownerPage.Items[0].ID != guard || !ownerPage.HasMore {
+		t.Fatalf("unexpected owner page: %+v", ownerPage)
+	}
+	if len(ownerPage.Facets.Owner) != 2 || ownerPage.Facets.Owner[0].Label != "Владелец" || ownerPage.Facets.Owner[0].Count != 2 {
+		t.Fatalf("unexpected owner facet: %+v", ownerPage.Facets.Owner)
+	}
+
+	var empty domain.BookListPage
+	s.do(http.MethodGet, "/api/v1/books?created_to=2000-01-01", "", nil).expect(t, http.StatusOK).decode(t, &empty)
+	if empty.HasMore || empty.NextCursor != "" || empty.Items == nil {
+		t.Fatalf("expected empty items list, got %+v", empty)
+	}
+
+	s.do(http.MethodGet, "/api/v1/books?sort=price", "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books?order=up", "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books?location_id=nope", "", nil).expectError(t, http.StatusBadRequest, `invalid location_id "nope"`)
+	s.do(http.MethodGet, "/api/v1/books?created_from=yesterday", "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books?status=deleted", "", nil).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidBookFilter.Error()+`: unknown status "deleted"`)
+	s.do(http.MethodGet, "/api/v1/books?created_from=2024-05-02&created_to=2024-05-01", "", nil).expect(t, http.StatusBadRequest)
+}
+
+func TestCursorPagination(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	var created []uuid.UUID
+	for _, title := range []string{"Первая", "Вторая", "Третья"} {
+		created = append(created, s.createBook(owner, title))
+	}
+
+	// Обход страниц по next_cursor: новые книги сверху, каждая ровно один раз
+	var seen []uuid.UUID
+	url := "/api/v1/books/list?limit=2&with_total=true"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("pagination does not terminate, seen %v", seen)
+		}
+		var page domain.Page[domain.Book]
+		s.do(http.MethodGet, url, "", nil).expect(t, http.StatusOK).decode(t, &page)
+		if page.Total == nil || *page.Total != 3 {
+			t.Fatalf("expected total 3, got %+v", page)
+		}
+		for _, b := range page.Items {
+			seen = append(seen, b.ID)
+		}
+		if !page.HasMore {
+			if page.NextCursor != "" {
+				t.Fatalf("last page must not have next_cursor: %+v", page)
+			}
+			break
+		}
+		url = "/api/v1/books/list?limit=2&with_total=true&cursor=" + page.NextCursor
+	}
+	if len(seen) != 3 || seen[0] != created[2] || seen[1] != created[1] || seen[2] != created[0] {
+		t.Fatalf("unexpected pages order %v, created %v", seen, created)
+	}
+
+	// Курсор другого списка или другой сортировки не принимается
+	var first domain.Page[domain.Book]
+	s.do(http.MethodGet, "/api/v1/books/list?limit=1", "", nil).expect(t, http.StatusOK).decode(t, &first)
+	s.do(http.MethodGet, "/api/v1/books?cursor="+first.NextCursor, "", nil).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidCursor.Error())
+	var byTitle domain.BookListPage
+	s.do(http.MethodGet, "/api/v1/books?sort=title&limit=1", "", nil).expect(t, http.StatusOK).decode(t, &byTitle)
+	s.do(http.MethodGet, "/api/v1/books?sort=title&order=desc&cursor="+byTitle.NextCursor, "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books/list?cursor=not-a-cursor", "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books/list?limit=0", "", nil).expect(t, http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/v1/books/list?limit=101", "", nil).expect(t, http.StatusBadRequest)
+
+	// Истории книги и пользователя тоже отдаются страницами
+	var history domain.Page[domain.BookMovementHistory]
+	s.do(http.MethodGet, "/api/v1/books/"+created[0].String()+"/history?limit=1", owner, nil).
+		expect(t, http.StatusOK).decode(t, &history)
+	if len(history.Items) != 1 || history.Items[0].BookID != created[0] || history.HasMore {
+		t.Fatalf("unexpected book history page: %+v", history)
+	}
+	var mine domain.Page[domain.BookMovementHistory]
+	s.do(http.MethodGet, "/api/v1/users/me/history?limit=2", owner, nil).expect(t, http.StatusOK).decode(t, &mine)
+	if len(mine.Items) != 2 || !mine.HasMore || mine.Items[0].BookID != created[2] {
+		t.Fatalf("unexpected user history page: %+v", mine)
+	}
+	s.do(http.MethodGet, "/api/v1/books/"+uuid.NewString()+"/history", owner, nil).expect(t, http.StatusNotFound)
+
+	// Список пользователей доступен только администратору
+	s.register("admin@example.com", "Администратор")
+	s.setRole("admin@example.com", domain.RoleAdmin)
+	admin := s.login("admin@example.com")
+	s.do(http.MethodGet, "/api/v1/admin/users", owner, nil).expect(t, http.StatusForbidden)
+	var users domain.Page[domain.User]
+	s.do(http.MethodGet, "/api/v1/admin/users?limit=1", admin, nil).expect(t, http.StatusOK).decode(t, &users)
+	if len(users.Items) != 1 || users.Items[0].Email != "owner@example.com" || !users.HasMore {
+		t.Fatalf("unexpected users page: %+v", users)
+	}
+}
+
+func TestBookOwnerManagement(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	reader := s.register("reader@example.com", "Читатель")
+	ownerID, readerID := s.me(owner).ID, s.me(reader).ID
+
+	// Описание меняет владелец, в том числе пока книга в брони
+	loaned := s.createBook(owner, "Пикник на обочине")
+	update := map[string]string{"title": "Пикник на обочине (1972)", "author": "Стругацкие", "condition": "excellent"}
+	s.do(http.MethodPut, "/api/v1/books/"+loaned.String(), reader, update).expect(t, http.StatusForbidden)
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": loaned}).expect(t, http.StatusOK)
+	var updated domain.Book
+	s.do(http.MethodPut, "/api/v1/books/"+loaned.String(), owner, update).expect(t, http.StatusOK).decode(t, &updated)
+	if updated.Title != update["title"] || updated.Author != "Стругацкие" || updated.Condition != domain.ConditionExcellent || updated.Status != domain.BookRequested {
+		t.Fatalf("unexpected updated book: %+v", updated)
+	}
+	s.do(http.MethodPut, "/api/v1/books/"+loaned.String(), owner, map[string]string{"title": "x"}).expect(t, http.StatusBadRequest)
+
+	// Книгу в брони нельзя ни архивировать, ни передать
+	s.do(http.MethodPut, "/api/v1/books/"+loaned.String()+"/archive", owner, nil).
+		expectError(t, http.StatusConflict, domain.ErrInvalidTransition.Error()+": requested -> archived")
+	s.do(http.MethodPut, "/api/v1/books/"+loaned.String()+"/transfer", owner, map[string]any{"new_owner_id": readerID}).
+		expectError(t, http.StatusConflict, domain.ErrBookLocked.Error()+": transferred while requested")
+
+	book := s.createBook(owner, "Трудно быть богом")
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/archive", reader, nil).expect(t, http.StatusForbidden)
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/archive", owner, nil).expect(t, http.StatusOK)
+	if got := s.getBook(book); got.Status != domain.BookArchived {
+		t.Fatalf("expected archived book, got %q", got.Status)
+	}
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": book}).expect(t, http.StatusConflict)
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/archive", owner, nil).expect(t, http.StatusConflict)
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/unarchive", reader, nil).expect(t, http.StatusForbidden)
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/unarchive", owner, nil).expect(t, http.StatusOK)
+
+	// Передача владения: новый владелец управляет книгой, прежний - больше нет
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/transfer", owner, map[string]any{"new_owner_id": ownerID}).
+		expectError(t, http.StatusBadRequest, domain.ErrSameOwner.Error())
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/transfer", owner, map[string]any{"new_owner_id": uuid.New()}).
+		expect(t, http.StatusNotFound)
+
+	// Удаленному или заблокированному пользователю книгу не передать
+	gone := s.register("gone@example.com", "Ушедший")
+	goneID := s.me(gone).ID
+	s.do(http.MethodDelete, "/api/v1/users/me", gone, nil).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/transfer", owner, map[string]any{"new_owner_id": goneID}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidNewOwner.Error()+": "+domain.ErrAccountDeleted.Error())
+	bannedID := s.me(s.register("banned@example.com", "Заблокированный")).ID
+	bannedAt := time.Now()
+	if err := s.repos.Users.UpdateBan(bannedID, domain.UserBan{BannedAt: &bannedAt, BanReason: "спам"}); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/transfer", owner, map[string]any{"new_owner_id": bannedID}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidNewOwner.Error()+": "+domain.ErrUserBanned.Error()+": спам")
+
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/transfer", owner, map[string]any{"new_owner_id": readerID}).expect(t, http.StatusOK)
+	if got := s.getBook(book); got.OwnerID != readerID || got.Status != domain.BookAvailable {
+		t.Fatalf("unexpected transferred book: %+v", got)
+	}
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/archive", owner, nil).expect(t, http.StatusForbidden)
+	s.do(http.MethodPut, "/api/v1/books/"+book.String()+"/archive", reader, nil).expect(t, http.StatusOK)
+
+	// Каждое действие записано в историю
+	var history domain.Page[domain.BookMovementHistory]
+	s.do(http.MethodGet, "/api/v1/books/"+book.String()+"/history", owner, nil).expect(t, http.StatusOK).decode(t, &history)
+	want := []string{domain.ActionArchived, domain.ActionTransferred, domain.ActionUnarchived, domain.ActionArchived, domain.ActionCreated}
+	if len(history.Items) != len(want) {
+		t.Fatalf("expected %d history records, got %+v", len(want), history.Items)
+	}
+	for i, action := range want {
+		if history.Items[i].Action != action {
+			t.Fatalf("history record %d is %q, want %q", i, history.Items[i].Action, action)
+		}
+	}
+	if transfer := history.Items[1]; *transfer.UserID != ownerID || transfer.PreviousStatus != domain.BookAvailable || transfer.NewStatus != domain.BookAvailable {
+		t.Fatalf("unexpected transfer record: %+v", transfer)
+	}
+}
+
+func TestUploads(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	other := s.register("other@example.com", "Другой")
+
+	// Полупрозрачная PNG 2000x1000: полноразмерный вариант ужимается до 1600, миниатюра до 320
+	src := image.NewNRGBA(image.Rect(0, 0, 2000, 1000))
+	for x := 0; x < 2000; x++ {
+		src.Set(x, x%1000, color.NRGBA{R: 200, A: 128})
+	}
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, src); err != nil {
+		t.Fatal(err)
+	}
+
+	s.upload("", pngData.Bytes()).expect(t, http.StatusUnauthorized)
+	var upload domain.Upload
+	s.upload(owner, pngData.Bytes()).expect(t, http.StatusCreated).decode(t, &upload)
+	if upload.ContentType != "image/png" || upload.Width != 1600 || upload.Height != 800 || upload.Size != int64(pngData.Len()) {
+		t.Fatalf("unexpected upload: %+v", upload)
+	}
+	if upload.URL != "/api/v1/uploads/"+upload.ID.String()+"/full" || upload.ThumbURL != "/api/v1/uploads/"+upload.ID.String()+"/thumb" {
+		t.Fatalf("unexpected upload urls: %+v", upload)
+	}
+
+	for _, tt := range []struct {
+		url           string
+		width, height int
+	}{{upload.URL, 1600, 800}, {upload.ThumbURL, 320, 160}} {
+		resp := s.doRaw(http.MethodGet, tt.url, "").expect(t, http.StatusOK)
+		if resp.Header.Get("Content-Type") != "image/jpeg" || !strings.Contains(resp.Header.Get("Cache-Control"), "max-age=31536000") {
+			t.Fatalf("unexpected headers for %s: %v", tt.url, resp.Header)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(resp.Body))
+		if err != nil {
+			t.Fatalf("decode %s: %v", tt.url, err)
+		}
+		if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Fatalf("%s is %dx%d, want %dx%d", tt.url, b.Dx(), b.Dy(), tt.width, tt.height)
+		}
+
+		// Повторный запрос с ETag не передает картинку заново
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		if cached := s.serve(req); cached.Code != http.StatusNotModified || len(cached.Body) != 0 {
+			t.Fatalf("expected 304 for %s, got %d", tt.url, cached.Code)
+		}
+	}
+	s.doRaw(http.MethodGet, "/api/v1/uploads/"+upload.ID.String()+"/original", "").expect(t, http.StatusNotFound)
+	s.doRaw(http.MethodGet, "/api/v1/uploads/"+uuid.NewString()+"/full", "").expect(t, http.StatusNotFound)
+
+	// Тип определяется по содержимому; битые и слишком большие файлы отклоняются
+	s.upload(owner, []byte("definitely not an image")).expectError(t, http.StatusUnsupportedMediaType, domain.ErrUnsupportedImage.Error())
+	s.upload(owner, pngData.Bytes()[:100]).expect(t, http.StatusBadRequest)
+	s.upload(owner, bytes.Repeat([]byte{0xFF}, 2<<20)).expect(t, http.StatusRequestEntityTooLarge)
+
+	// Книга принимает ID загрузки; чужую или несуществующую загрузку использовать нельзя
+	create := map[string]any{"title": "С обложкой", "author": "Автор", "condition": "good", "image_id": upload.ID}
+	s.do(http.MethodPost, "/api/v1/books/create", other, create).expect(t, http.StatusForbidden)
+	create["image_id"] = uuid.New()
+	s.do(http.MethodPost, "/api/v1/books/create", owner, create).expectError(t, http.StatusBadRequest, domain.ErrUnknownUpload.Error())
+	create["image_id"] = upload.ID
+	s.do(http.MethodPost, "/api/v1/books/create", owner, create).expect(t, http.StatusCreated)
+	book := s.getBook(s.createBook(owner, "Без обложки"))
+	if book.ImageID != nil {
+		t.Fatalf("book without image_id must not have an image: %+v", book)
+	}
+
+	var updated domain.Book
+	update := map[string]any{"title": book.Title, "author": book.Author, "condition": "good", "image_id": upload.ID}
+	s.do(http.MethodPut, "/api/v1/books/"+book.ID.String(), owner, update).expect(t, http.StatusOK).decode(t, &updated)
+	if updated.ImageID == nil || *updated.ImageID != upload.ID || updated.ImageURL != upload.URL {
+		t.Fatalf("update must attach the image: %+v", updated)
+	}
+	// Тот же image_url без image_id сохраняет обложку, другой адрес ее заменяет
+	update = map[string]any{"title": book.Title, "author": book.Author, "condition": "good", "image_url": upload.URL}
+	s.do(http.MethodPut, "/api/v1/books/"+book.ID.String(), owner, update).expect(t, http.StatusOK).decode(t, &updated)
+	if updated.ImageID == nil || *updated.ImageID != upload.ID {
+		t.Fatalf("unchanged image_url must keep the image: %+v", updated)
+	}
+	update["image_url"] = "https://example.com/cover.jpg"
+	updated = domain.Book{}
+	s.do(http.MethodPut, "/api/v1/books/"+book.ID.String(), owner, update).expect(t, http.StatusOK).decode(t, &updated)
+	if updated.ImageID != nil || updated.ImageURL != "https://example.com/cover.jpg" {
+		t.Fatalf("new image_url must replace the image: %+v", updated)
+	}
+
+	// Возврат не трогает обложку, даже если клиент прислал пустой image_url
+	update = map[string]any{"title": book.Title, "author": book.Author, "condition": "good", "image_id": upload.ID}
+	s.do(http.MethodPut, "/api/v1/books/"+book.ID.String(), owner, update).expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/books/request", other, map[string]any{"book_id": book.ID}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/borrow", other, map[string]any{"book_id": book.ID}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/return", other, map[string]any{"book_id": book.ID, "condition": "bad", "image_url": ""}).
+		expect(t, http.StatusOK)
+	returned := s.getBook(book.ID)
+	if returned.ImageID == nil || *returned.ImageID != upload.ID || returned.ImageURL != upload.URL {
+		t.Fatalf("return must keep the uploaded cover: %+v", returned)
+	}
+}
+
+func TestCreateBookByISBN(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+
+	// Описание издания для предзаполнения формы: ISBN-10 и ISBN-13 с дефисами равнозначны
+	var meta domain.BookMetadata
+	s.do(http.MethodGet, "/api/v1/books/isbn/5-17-090587-4", "", nil).expect(t, http.StatusOK).decode(t, &meta)
+	if meta.ISBN != "9785170905874" || meta.Title != "Мастер и Маргарита" || meta.Author != "Михаил Булгаков" {
+		t.Fatalf("unexpected metadata: %+v", meta)
+	}
+	s.do(http.MethodGet, "/api/v1/books/isbn/9780000000002", "", nil).expect(t, http.StatusNotFound)
+	s.do(http.MethodGet, "/api/v1/books/isbn/9785170905875", "", nil).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidISBN.Error())
+
+	findBook := func(title string) domain.Book {
+		t.Helper()
+		var books domain.Page[domain.Book]
+		s.do(http.MethodGet, "/api/v1/books/list?limit=100", "", nil).expect(t, http.StatusOK).decode(t, &books)
+		for _, b := range books.Items {
+			if b.Title == title {
+				return s.getBook(b.ID)
+			}
+		}
+		t.Fatalf("book %q not found in list", title)
+		return domain.Book{}
+	}
+
+	// Достаточно одного ISBN: название, автор, описание и обложка берутся из набора данных
+	s.do(http.MethodPost, "/api/v1/books/create", owner, map[string]string{"isbn": "978-5-17-090587-4"}).expect(t, http.StatusCreated)
+	master := findBook("Мастер и Маргарита")
+	if master.ISBN != "9785170905874" || master.Author != "Михаил Булгаков" || master.Description != "Роман" ||
+		master.ImageURL != "https://covers.example.com/master.jpg" || master.Condition != domain.ConditionGood {
+		t.Fatalf("book must be filled from metadata: %+v", master)
+	}
+
+	// Поля из запроса важнее описания издания; ISBN-10 хранится как ISBN-13
+	s.do(http.MethodPost, "/api/v1/books/create", owner, map[string]string{
+		"isbn": "0 306 40615 2", "title": "Своя книга", "author": "Свой автор", "condition": "excellent",
+	}).expect(t, http.StatusCreated)
+	own := findBook("Своя книга")
+	if own.ISBN != "9780306406157" || own.Author != "Свой автор" || own.Condition != domain.ConditionExcellent {
+		t.Fatalf("request fields must win over metadata: %+v", own)
+	}
+
+	s.do(http.MethodPost, "/api/v1/books/create", owner, map[string]string{"isbn": "978-5-17-090587-5"}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidISBN.Error())
+	s.do(http.MethodPost, "/api/v1/books/create", owner, map[string]string{"isbn": "9780000000002"}).
+		expectError(t, http.StatusBadRequest, domain.ErrBookDetails.Error())
+	s.do(http.MethodPost, "/api/v1/books/create", owner, map[string]string{"title": "Без автора"}).
+		expectError(t, http.StatusBadRequest, domain.ErrBookDetails.Error())
+
+	// Неизвестный ISBN не мешает создать книгу с названием и автором
+	s.do(http.MethodPost, "/api/v1/books/create", owner, map[string]string{
+		"isbn": "9780000000002", "title": "Редкое издание", "author": "Неизвестен",
+	}).expect(t, http.StatusCreated)
+	if rare := findBook("Редкое издание"); rare.ISBN != "9780000000002" {
+		t.Fatalf("unknown ISBN must still be stored, got %q", rare.ISBN)
+	}
+
+	// При редактировании ISBN тоже проверяется и нормализуется
+	update := map[string]string{"title": own.Title, "author": own.Author, "condition": "good", "isbn": "0-306-40615-2"}
+	var updated domain.Book
+	s.do(http.MethodPut, "/api/v1/books/"+own.ID.String(), owner, update).expect(t, http.StatusOK).decode(t, &updated)
+	if updated.ISBN != "9780306406157" {
+		t.Fatalf("updated ISBN must be normalized, got %q", updated.ISBN)
+	}
+	update["isbn"] = "0-306-40615-3"
+	s.do(http.MethodPut, "/api/v1/books/"+own.ID.String(), owner, update).expect(t, http.StatusBadRequest)
+}
+
+func TestWorks(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	reader := s.register("reader@example.com", "Читатель")
+	library := &domain.Location{Name: "Библиотека №4", Address: "Ленина, 1"}
+	cafe := &domain.Location{Name: "Кафе", Address: "Мира, 2"}
+	for _, l := range []*domain.Location{library, cafe} {
+		if err := s.repos.Locations.Create(l); err != nil {
+			t.Fatalf("create location: %v", err)
+		}
+	}
+
+	createCopy := func(title, author string, location *domain.Location) domain.Book {
+		t.Helper()
+		s.do(http.MethodPost, "/api/v1/books/create", owner, map[string]any{
+			"title": title, "author": author, "current_location_id": location.ID,
+		}).expect(t, http.StatusCreated)
+		var books domain.Page[domain.Book]
+		s.do(http.MethodGet, "/api/v1/books/list?limit=100", "", nil).expect(t, http.StatusOK).decode(t, &books)
+		for _, b := range books.Items {
+			if b.Title == title {
+				return s.getBook(b.ID)
+			}
+		}
+		t.Fatalf("book %q not found in list", title)
+		return domain.Book{}
+	}
+
+	// Название и автор сравниваются без учета регистра и лишних пробелов - это одно произведение
+	first := createCopy("Солярис", "Станислав Лем", library)
+	second := createCopy("СОЛЯРИС", "станислав  лем", library)
+	third := createCopy("Солярис ", "Станислав Лем", cafe)
+	eden := createCopy("Эдем", "Станислав Лем", library)
+	if first.WorkID == nil || second.WorkID == nil || third.WorkID == nil || eden.WorkID == nil {
+		t.Fatalf("every copy must be linked to a work")
+	}
+	workID := *first.WorkID
+	if *second.WorkID != workID || *third.WorkID != workID || *eden.WorkID == workID {
+		t.Fatalf("unexpected works: %s %s %s %s", workID, *second.WorkID, *third.WorkID, *eden.WorkID)
+	}
+
+	// Экземпляр на руках не считается свободным
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": third.ID}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/borrow", reader, map[string]any{"book_id": third.ID}).expect(t, http.StatusOK)
+	var work domain.WorkDetails
+	s.do(http.MethodGet, "/api/v1/works/"+workID.String(), "", nil).expect(t, http.StatusOK).decode(t, &work)
+	if work.Title != "Солярис" || work.Total != 3 || work.Available != 2 || len(work.Locations) != 1 ||
+		work.Locations[0].LocationID != library.ID || work.Locations[0].Available != 2 || work.Locations[0].Name != library.Name {
+		t.Fatalf("unexpected work: %+v", work)
+	}
+	s.do(http.MethodPut, "/api/v1/books/return", reader, map[string]any{
+		"book_id": third.ID, "condition": "good", "current_location_id": cafe.ID,
+	}).expect(t, http.StatusOK)
+	s.do(http.MethodGet, "/api/v1/works/"+workID.String(), "", nil).expect(t, http.StatusOK).decode(t, &work)
+	if work.Available != 3 || len(work.Locations) != 2 || work.Locations[1].LocationID != cafe.ID {
+		t.Fatalf("returned copy must be available at the cafe: %+v", work)
+	}
+
+	// Отзыв - на произведение: прочитавший один экземпляр может оценить любой, но только один раз
+	s.do(http.MethodPost, "/api/v1/books/"+second.ID.String()+"/reviews", reader, map[string]any{"rating": 5}).expect(t, http.StatusCreated)
+	s.do(http.MethodPost, "/api/v1/books/"+first.ID.String()+"/reviews", reader, map[string]any{"rating": 4}).
+		expectError(t, http.StatusConflict, domain.ErrReviewExists.Error())
+	s.do(http.MethodPost, "/api/v1/books/"+eden.ID.String()+"/reviews", reader, map[string]any{"rating": 4}).expect(t, http.StatusForbidden)
+	var reviews []domain.Review
+	s.do(http.MethodGet, "/api/v1/books/"+first.ID.String()+"/reviews", owner, nil).expect(t, http.StatusOK).decode(t, &reviews)
+	if len(reviews) != 1 || reviews[0].BookID != second.ID {
+		t.Fatalf("reviews of any copy must include the whole work: %+v", reviews)
+	}
+	s.do(http.MethodGet, "/api/v1/works/"+workID.String()+"/reviews", "", nil).expect(t, http.StatusOK).decode(t, &reviews)
+	if len(reviews) != 1 {
+		t.Fatalf("expected 1 work review, got %d", len(reviews))
+	}
+	s.do(http.MethodGet, "/api/v1/works/"+workID.String(), "", nil).expect(t, http.StatusOK).decode(t, &work)
+	if work.AverageRating == nil || *work.AverageRating != 5 || work.ReviewsCount != 1 {
+		t.Fatalf("unexpected work rating: %v of %d", work.AverageRating, work.ReviewsCount)
+	}
+
+	// Поиск возвращает произведение один раз
+	var found domain.BookSearchPage
+	s.do(http.MethodGet, "/api/v1/books/search?q=солярис", "", nil).expect(t, http.StatusOK).decode(t, &found)
+	if found.Total != 1 || len(found.Items) != 1 || found.Items[0].WorkID == nil || *found.Items[0].WorkID != workID ||
+		found.Items[0].Copies != 3 || found.Items[0].AvailableCopies != 3 {
+		t.Fatalf("search must collapse copies of a work: %+v", found)
+	}
+
+	// Смена названия переносит экземпляр к другому произведению
+	s.do(http.MethodPut, "/api/v1/books/"+eden.ID.String(), owner, map[string]string{
+		"title": "Солярис", "author": "Станислав Лем", "condition": "good",
+	}).expect(t, http.StatusOK)
+	var copies []domain.Book
+	s.do(http.MethodGet, "/api/v1/works/"+workID.String()+"/books", "", nil).expect(t, http.StatusOK).decode(t, &copies)
+	if len(copies) != 4 || copies[0].ID != eden.ID {
+		t.Fatalf("expected 4 copies with the renamed one first, got %d", len(copies))
+	}
+
+	s.do(http.MethodGet, "/api/v1/works/"+uuid.NewString(), "", nil).expect(t, http.StatusNotFound)
+	s.do(http.MethodGet, "/api/v1/works/nope", "", nil).expectError(t, http.StatusBadRequest, "invalid work ID")
+}
+
+func TestAccountManagement(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	s.register("taken@example.com", "Занятый")
+	var tokens domain.TokenResponse
+	s.do(http.MethodPost, "/api/v1/users/registration", "", map[string]string{
+		"email": "reader@example.com", "password": "secret123", "name": "Читатель",
+	}).expect(t, http.StatusCreated).decode(t, &tokens)
+	reader := tokens.AccessToken
+
+	// Профиль: почта уникальна, имя проверяется как при регистрации
+	s.do(http.MethodPut, "/api/v1/users/me", reader, map[string]string{"name": "Читатель", "email": "taken@example.com"}).
+		expectError(t, http.StatusConflict, domain.ErrEmailTaken.Error())
+	s.do(http.MethodPut, "/api/v1/users/me", reader, map[string]string{"name": "Я", "email": "reader@example.com"}).
+		expect(t, http.StatusBadRequest)
+	var user domain.User
+	s.do(http.MethodPut, "/api/v1/users/me", reader, map[string]string{"name": "Новый читатель", "email": "new@example.com"}).
+		expect(t, http.StatusOK).decode(t, &user)
+	if user.Name != "Новый читатель" || user.Email != "new@example.com" || user.EmailVerifiedAt != nil {
+		t.Fatalf("unexpected profile after update: %+v", user)
+	}
+	s.do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": s.mailToken("new@example.com", "verify-email")}).
+		expect(t, http.StatusOK)
+
+	// Пароль: нужен текущий; после смены старый refresh-токен не работает
+	s.do(http.MethodPost, "/api/v1/users/me/password", reader, map[string]string{"old_password": "wrong", "new_password": "changed123"}).
+		expectError(t, http.StatusForbidden, domain.ErrWrongPassword.Error())
+	s.do(http.MethodPost, "/api/v1/users/me/password", reader, map[string]string{"old_password": "secret123", "new_password": "short"}).
+		expect(t, http.StatusBadRequest)
+	var changed domain.TokenResponse
+	s.do(http.MethodPost, "/api/v1/users/me/password", reader, map[string]string{"old_password": "secret123", "new_password": "changed123"}).
+		expect(t, http.StatusOK).decode(t, &changed)
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}).
+		expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": changed.RefreshToken}).
+		expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "new@example.com", "password": "secret123"}).
+		expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "new@example.com", "password": "changed123"}).
+		expect(t, http.StatusOK)
+
+	// Пока книга на руках, удалить аккаунт нельзя
+	borrowed := s.createBook(owner, "Взятая")
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": borrowed}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/borrow", reader, map[string]any{"book_id": borrowed}).expect(t, http.StatusOK)
+	s.do(http.MethodDelete, "/api/v1/users/me", reader, nil).expectError(t, http.StatusConflict, domain.ErrActiveLoans.Error())
+	s.do(http.MethodPut, "/api/v1/books/return", reader, map[string]any{
+		"book_id": borrowed, "condition": "good",
+	}).expect(t, http.StatusOK)
+
+	// Бронь отменяется, а из очереди пользователь выходит
+	reserved := s.createBook(owner, "Забронированная")
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": reserved}).expect(t, http.StatusOK)
+	queued := s.createBook(owner, "В очереди")
+	s.do(http.MethodPost, "/api/v1/books/request", owner, map[string]any{"book_id": queued}).expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/books/"+queued.String()+"/waitlist", reader, nil).expect(t, http.StatusCreated)
+
+	// Очередь видна всем, но без почты и прочих личных данных ждущего
+	resp := s.do(http.MethodGet, "/api/v1/books/"+queued.String()+"/waitlist", owner, nil).expect(t, http.StatusOK)
+	if bytes.Contains(resp.Body, []byte("@")) || bytes.Contains(resp.Body, []byte("email")) {
+		t.Fatalf("waitlist must not expose user data: %s", resp.Body)
+	}
+	var places []domain.WaitlistPlace
+	resp.decode(t, &places)
+	if len(places) != 1 || places[0].Position != 1 || places[0].UserName == "" {
+		t.Fatalf("unexpected waitlist %+v", places)
+	}
+
+	readerID := s.me(reader).ID
+	s.do(http.MethodDelete, "/api/v1/users/me", reader, nil).expect(t, http.StatusOK)
+
+	if book := s.getBook(reserved); book.Status != domain.BookAvailable {
+		t.Fatalf("reservation must be cancelled, book is %s", book.Status)
+	}
+	var waitlist []domain.WaitlistPlace
+	s.do(http.MethodGet, "/api/v1/books/"+queued.String()+"/waitlist", owner, nil).expect(t, http.StatusOK).decode(t, &waitlist)
+	if len(waitlist) != 0 {
+		t.Fatalf("deleted user must leave waitlists, got %+v", waitlist)
+	}
+
+	// Строка пользователя остается для истории, но личных данных в ней нет
+	deleted, err := s.repos.Users.GetByID(readerID)
+	if err != nil {
+		t.Fatalf("get deleted user: %v", err)
+	}
+	if deleted.DeletedAt == nil || deleted.Email == "new@example.com" || deleted.Name == "Новый читатель" || deleted.Password != "" {
+		t.Fatalf("user must be anonymized: %+v", deleted)
+	}
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "new@example.com", "password": "changed123"}).
+		expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": changed.RefreshToken}).
+		expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPut, "/api/v1/users/me", reader, map[string]string{"name": "Вернувшийся", "email": "back@example.com"}).
+		expectError(t, http.StatusUnauthorized, domain.ErrAccountDeleted.Error())
+	s.do(http.MethodDelete, "/api/v1/users/me", reader, nil).expect(t, http.StatusUnauthorized)
+
+	// Почта освободилась - с ней можно зарегистрироваться заново
+	newcomer := s.register("new@example.com", "Новый читатель")
+
+	// Владелец не может уйти, пока его книга у кого-то в брони или на руках
+	s.do(http.MethodPost, "/api/v1/books/request", newcomer, map[string]any{"book_id": borrowed}).expect(t, http.StatusOK)
+	s.do(http.MethodDelete, "/api/v1/users/me", owner, nil).expectError(t, http.StatusConflict, domain.ErrOwnedBooksInUse.Error())
+	s.do(http.MethodPut, "/api/v1/books/borrow", newcomer, map[string]any{"book_id": borrowed}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/return", newcomer, map[string]any{
+		"book_id": borrowed, "condition": "good",
+	}).expect(t, http.StatusOK)
+
+	// Свободные книги уходящего владельца, включая отмененную им самим бронь, уходят в архив
+	s.do(http.MethodDelete, "/api/v1/users/me", owner, nil).expect(t, http.StatusOK)
+	for _, id := range []uuid.UUID{borrowed, reserved, queued} {
+		if status := s.getBook(id).Status; status != domain.BookArchived {
+			t.Fatalf("book of deleted owner must be archived, got %s", status)
+		}
+	}
+	s.do(http.MethodPost, "/api/v1/books/request", newcomer, map[string]any{"book_id": reserved}).expect(t, http.StatusConflict)
+}
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	book := s.createBook(owner, "Пикник на обочине")
+
+	var tokens domain.TokenResponse
+	s.do(http.MethodPost, "/api/v1/users/registration", "", map[string]string{
+		"email": "reader@example.com", "password": "secret123", "name": "Читатель",
+	}).expect(t, http.StatusCreated).decode(t, &tokens)
+	reader := tokens.AccessToken
+	if user := s.me(reader); user.EmailVerifiedAt != nil {
+		t.Fatalf("new user must not be verified: %+v", user)
+	}
+
+	// Без подтвержденной почты книги брать нельзя
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": book}).
+		expectError(t, http.StatusForbidden, domain.ErrEmailNotVerified.Error())
+
+	// Повторное письмо заменяет прежнюю ссылку
+	first := s.mailToken("reader@example.com", "verify-email")
+	s.do(http.MethodPost, "/api/v1/users/me/verify-email", reader, nil).expect(t, http.StatusOK)
+	second := s.mailToken("reader@example.com", "verify-email")
+	if first == second {
+		t.Fatal("resend must issue a new token")
+	}
+	s.do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": first}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidAccountToken.Error())
+	s.do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": second}).expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": second}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidAccountToken.Error())
+	s.do(http.MethodPost, "/api/v1/users/me/verify-email", reader, nil).
+		expectError(t, http.StatusConflict, domain.ErrEmailAlreadyVerified.Error())
+	if user := s.me(reader); user.EmailVerifiedAt == nil {
+		t.Fatalf("email must be verified: %+v", user)
+	}
+	s.do(http.MethodPost, "/api/v1/books/request", reader, map[string]any{"book_id": book}).expect(t, http.StatusOK)
+
+	// Сброс пароля: ответ не выдает, есть ли такая почта
+	s.do(http.MethodPost, "/api/v1/users/password/forgot", "", map[string]string{"email": "nobody@example.com"}).expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/users/password/forgot", "", map[string]string{"email": "reader@example.com"}).expect(t, http.StatusOK)
+	reset := s.mailToken("reader@example.com", "reset-password")
+
+	// Токен годится только для своего действия
+	s.do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": reset}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidAccountToken.Error())
+	s.do(http.MethodPost, "/api/v1/users/password/reset", "", map[string]string{"token": reset, "new_password": "short"}).
+		expect(t, http.StatusBadRequest)
+	s.do(http.MethodPost, "/api/v1/users/password/reset", "", map[string]string{"token": reset, "new_password": "changed123"}).
+		expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/users/password/reset", "", map[string]string{"token": reset, "new_password": "another123"}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidAccountToken.Error())
+
+	// После сброса все сессии завершены, работает только новый пароль
+	s.do(http.MethodPost, "/api/v1/users/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}).
+		expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "reader@example.com", "password": "secret123"}).
+		expect(t, http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "reader@example.com", "password": "changed123"}).
+		expect(t, http.StatusOK)
+
+	// Ссылка на сброс перестает работать после смены почты
+	s.do(http.MethodPost, "/api/v1/users/password/forgot", "", map[string]string{"email": "reader@example.com"}).expect(t, http.StatusOK)
+	stale := s.mailToken("reader@example.com", "reset-password")
+	s.do(http.MethodPut, "/api/v1/users/me", reader, map[string]string{"name": "Читатель", "email": "moved@example.com"}).expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/users/password/reset", "", map[string]string{"token": stale, "new_password": "another123"}).
+		expectError(t, http.StatusBadRequest, domain.ErrInvalidAccountToken.Error())
+}
+
+func TestWaitlistRequiresVerifiedEmail(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	holder := s.register("holder@example.com", "Держатель")
+	next := s.register("next@example.com", "Следующий")
+	book := s.createBook(owner, "Трудно быть богом")
+	s.do(http.MethodPost, "/api/v1/books/request", holder, map[string]any{"book_id": book}).expect(t, http.StatusOK)
+
+	// Встать в очередь без подтвержденной почты нельзя: очередь заканчивается бронью
+	var tokens domain.TokenResponse
+	s.do(http.MethodPost, "/api/v1/users/registration", "", map[string]string{
+		"email": "reader@example.com", "password": "secret123", "name": "Читатель",
+	}).expect(t, http.StatusCreated).decode(t, &tokens)
+	reader := tokens.AccessToken
+	s.do(http.MethodPost, "/api/v1/books/"+book.String()+"/waitlist", reader, nil).
+		expectError(t, http.StatusForbidden, domain.ErrEmailNotVerified.Error())
+
+	// Подтвердил - встал первым; потом сменил почту и снова стал неподтвержденным
+	s.do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": s.mailToken("reader@example.com", "verify-email")}).
+		expect(t, http.StatusOK)
+	s.do(http.MethodPost, "/api/v1/books/"+book.String()+"/waitlist", reader, nil).expect(t, http.StatusCreated)
+	s.do(http.MethodPost, "/api/v1/books/"+book.String()+"/waitlist", next, nil).expect(t, http.StatusCreated)
+	s.do(http.MethodPut, "/api/v1/users/me", reader, map[string]string{"name": "Читатель", "email": "changed@example.com"}).
+		expect(t, http.StatusOK)
+
+	// Книга достается следующему, а неподтвержденный выбывает из очереди
+	s.do(http.MethodPut, "/api/v1/books/borrow", holder, map[string]any{"book_id": book}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/return", holder, map[string]any{
+		"book_id": book, "condition": "good",
+	}).expect(t, http.StatusOK)
+	if got := s.getBook(book); got.Status != domain.BookRequested {
+		t.Fatalf("book must be reserved for the next user, got %s", got.Status)
+	}
+	var places []domain.WaitlistPlace
+	s.do(http.MethodGet, "/api/v1/books/"+book.String()+"/waitlist", owner, nil).expect(t, http.StatusOK).decode(t, &places)
+	if len(places) != 0 {
+		t.Fatalf("unverified user must leave the waitlist, got %+v", places)
+	}
+	s.do(http.MethodPut, "/api/v1/books/borrow", reader, map[string]any{"book_id": book}).expect(t, http.StatusForbidden)
+	s.do(http.MethodPut, "/api/v1/books/borrow", next, map[string]any{"book_id": book}).expect(t, http.StatusOK)
+}
+
+func TestWaitlistHandOff(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com", "Владелец")
+	holder := s.register("holder@example.com", "Держатель")
+	first := s.register("first@example.com", "Первый")
+	second := s.register("second@example.com", "Второй")
+	stale := s.register("stale@example.com", "Выбывший")
+	bookID := s.createBook(owner, "Понедельник начинается в субботу")
+	waitlist := "/api/v1/books/" + bookID.String() + "/waitlist"
+
+	// reservation возвращает бронь пользователя на книгу, если она есть
+	reservation := func(token string) *domain.Exchange {
+		t.Helper()
+		var page domain.Page[domain.Exchange]
+		s.do(http.MethodGet, "/api/v1/exchanges/my", token, nil).expect(t, http.StatusOK).decode(t, &page)
+		for i := range page.Items {
+			if page.Items[i].BookID == bookID && page.Items[i].Status == domain.ExchangeRequested {
+				return &page.Items[i]
+			}
+		}
+		return nil
+	}
+	// queue возвращает имена в очереди по порядку
+	queue := func() []string {
+		t.Helper()
+		var places []domain.WaitlistPlace
+		s.do(http.MethodGet, waitlist, owner, nil).expect(t, http.StatusOK).decode(t, &places)
+		names := make([]string, 0, len(places))
+		for i, place := range places {
+			if place.Position != i+1 {
+				t.Fatalf("positions must be sequential, got %+v", places)
+			}
+			names = append(names, place.UserName)
+		}
+		return names
+	}
+	expectQueue := func(want ...string) {
+		t.Helper()
+		if got := queue(); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("waitlist is %v, want %v", got, want)
+		}
+	}
+
+	s.do(http.MethodPost, "/api/v1/books/request", holder, map[string]any{"book_id": bookID}).expect(t, http.StatusOK)
+	s.do(http.MethodPost, waitlist, first, nil).expect(t, http.StatusCreated)
+	s.do(http.MethodPost, waitlist, second, nil).expect(t, http.StatusCreated)
+	expectQueue("Первый", "Второй")
+
+	// Отмена брони: книга уходит первому в очереди со свежим сроком
+	held := reservation(holder)
+	if held == nil {
+		t.Fatal("holder must have a reservation")
+	}
+	s.do(http.MethodPut, "/api/v1/exchanges/"+held.ID.String()+"/cancel", holder, nil).expect(t, http.StatusOK)
+	promoted := reservation(first)
+	if promoted == nil || promoted.ExpiresAt == nil || !promoted.ExpiresAt.After(time.Now()) {
+		t.Fatalf("first in line must get a fresh reservation, got %+v", promoted)
+	}
+	if status := s.getBook(bookID).Status; status != domain.BookRequested {
+		t.Fatalf("book must stay reserved, got %s", status)
+	}
+	expectQueue("Второй")
+
+	s.do(http.MethodPost, waitlist, stale, nil).expect(t, http.StatusCreated)
+	s.do(http.MethodPost, waitlist, holder, nil).expect(t, http.StatusCreated)
+	expectQueue("Второй", "Выбывший", "Держатель")
+
+	// Истечение брони: фоновая задача отменяет ее и отдает книгу следующему
+	expired, err := s.repos.Exchanges.GetByID(promoted.ID)
+	if err != nil {
+		t.Fatalf("get reservation: %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+	if err := s.repos.Exchanges.Update(expired); err != nil {
+		t.Fatalf("expire reservation: %v", err)
+	}
+	if err := s.exchanges.CancelExpiredExchanges(context.Background()); err != nil {
+		t.Fatalf("cancel expired: %v", err)
+	}
+	if reservation(first) != nil || reservation(second) == nil {
+		t.Fatal("expired reservation must pass to the second user")
+	}
+	expectQueue("Выбывший", "Держатель")
+
+	// Блокировка в обход модерации оставляет в очереди устаревшую запись: при возврате
+	// она снимается, а книга достается следующему по порядку
+	staleUser, err := s.repos.Users.GetByEmail("stale@example.com")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	now := time.Now()
+	if err := s.repos.Users.UpdateBan(staleUser.ID, domain.UserBan{BannedAt: &now, BanReason: "спам"}); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+	s.do(http.MethodPut, "/api/v1/books/borrow", second, map[string]any{"book_id": bookID}).expect(t, http.StatusOK)
+	s.do(http.MethodPut, "/api/v1/books/return", second, map[string]any{
+		"book_id": bookID, "condition": "good",
+	}).expect(t, http.StatusOK)
+	if reservation(holder) == nil {
+		t.Fatal("returned book must pass to the next eligible user")
+	}
+	expectQueue()
+
+	// Каждая передача по очереди записана в историю
+	history, err := s.repos.Movements.GetByBookID(bookID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	var handOffs int
+	for _, m := range history {
+		if m.Notes == "Book assigned to the next user in the waitlist" {
+			handOffs++
+		}
+	}
+	if handOffs != 3 {
+		t.Fatalf("expected 3 waitlist hand-offs in history, got %d", handOffs)
+	}
+}
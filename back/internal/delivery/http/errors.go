@@ -0,0 +1,78 @@
+package http
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errorStatus сопоставляет ошибку бизнес-логики с HTTP-статусом
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, domain.ErrBlobNotFound),
+		errors.Is(err, domain.ErrMetadataNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidRating),
+		errors.Is(err, domain.ErrEmptySearchQuery),
+		errors.Is(err, domain.ErrInvalidBookFilter),
+		errors.Is(err, domain.ErrInvalidCursor),
+		errors.Is(err, domain.ErrSameOwner),
+		errors.Is(err, domain.ErrInvalidNewOwner),
+		errors.Is(err, domain.ErrInvalidImage),
+		errors.Is(err, domain.ErrUnknownUpload),
+		errors.Is(err, domain.ErrInvalidISBN),
+		errors.Is(err, domain.ErrBookDetails),
+		errors.Is(err, domain.ErrInvalidAccountToken),
+		errors.Is(err, domain.ErrInvalidRole),
+		errors.Is(err, domain.ErrInvalidBan):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrForbidden),
+		errors.Is(err, domain.ErrReviewNotAllowed),
+		errors.Is(err, domain.ErrWrongPassword),
+		errors.Is(err, domain.ErrEmailNotVerified),
+		errors.Is(err, domain.ErrUserBanned),
+		errors.Is(err, domain.ErrSelfModeration):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrAccountDeleted),
+		errors.Is(err, domain.ErrInvalidRefreshToken),
+		errors.Is(err, domain.ErrRefreshTokenExpired),
+		errors.Is(err, domain.ErrRefreshTokenReused):
+		return http.StatusUnauthorized
+	case errors.Is(err, gorm.ErrDuplicatedKey),
+		errors.Is(err, domain.ErrReviewExists),
+		errors.Is(err, domain.ErrBookNotAvailable),
+		errors.Is(err, domain.ErrBookConflict),
+		errors.Is(err, domain.ErrInvalidTransition),
+		errors.Is(err, domain.ErrBookLocked),
+		errors.Is(err, domain.ErrWaitlistNotNeeded),
+		errors.Is(err, domain.ErrWaitlistClosed),
+		errors.Is(err, domain.ErrAlreadyInWaitlist),
+		errors.Is(err, domain.ErrExchangeNotActive),
+		errors.Is(err, domain.ErrExchangeConflict),
+		errors.Is(err, domain.ErrReservationExpired),
+		errors.Is(err, domain.ErrExchangeExtendLimit),
+		errors.Is(err, domain.ErrRenewalLimit),
+		errors.Is(err, domain.ErrRenewalBlocked),
+		errors.Is(err, domain.ErrLoanOverdue),
+		errors.Is(err, domain.ErrEmailTaken),
+		errors.Is(err, domain.ErrActiveLoans),
+		errors.Is(err, domain.ErrOwnedBooksInUse),
+		errors.Is(err, domain.ErrEmailAlreadyVerified):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, domain.ErrUnsupportedImage):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondError отправляет ошибку клиенту с подходящим статусом
+func respondError(c *gin.Context, err error) {
+	c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+}
@@ -0,0 +1,304 @@
+package http_test
+
+import (
+	"bookvito/config"
+	delivery "bookvito/internal/delivery/http"
+	"bookvito/internal/domain"
+	"bookvito/internal/repository/blob"
+	"bookvito/internal/repository/mailer"
+	"bookvito/internal/repository/memory"
+	"bookvito/internal/repository/metadata"
+	"bookvito/internal/usecase"
+	"bookvito/pkg/scheduler"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const testJWTSecret = "test-secret"
+
+// testISBNDataset - набор данных офлайн-провайдера описаний изданий
+const testISBNDataset = `{"isbn": "978-5-17-090587-4", "title": "Мастер и Маргарита", "author": "Михаил Булгаков", "description": "Роман", "cover_url": "https://covers.example.com/master.jpg"}
+{"isbn": "0-306-40615-2", "title": "Test Book", "author": "Test Author"}
+`
+
+// testServer - весь API в одном процессе: настоящий роутер и use cases поверх репозиториев в памяти
+type testServer struct {
+	t       *testing.T
+	router  *gin.Engine
+	repos   *domain.Repositories
+	mailDir string // Письма FileMailer
+
+	exchanges *usecase.ExchangeUseCase // Фоновые задачи броней тесты запускают напрямую
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		JWTSecret:     testJWTSecret,
+		LoanPeriod:    14 * 24 * time.Hour,
+		RenewalPeriod: 7 * 24 * time.Hour,
+		MaxRenewals:   2,
+		UploadMaxSize: 1 << 20,
+		AppURL:        "https://bookvito.test",
+	}
+
+	store := memory.NewStore()
+	repos := memory.NewRepositories(store)
+	uow := memory.NewUnitOfWork(store)
+
+	datasetPath := filepath.Join(t.TempDir(), "isbn.jsonl")
+	if err := os.WriteFile(datasetPath, []byte(testISBNDataset), 0o644); err != nil {
+		t.Fatalf("write ISBN dataset: %v", err)
+	}
+	bookMetadata, err := metadata.NewOfflineProvider(datasetPath)
+	if err != nil {
+		t.Fatalf("load ISBN dataset: %v", err)
+	}
+
+	mailDir := t.TempDir()
+	mails, err := mailer.NewFileMailer(mailDir, "noreply@bookvito.test")
+	if err != nil {
+		t.Fatalf("create mailer: %v", err)
+	}
+
+	userUC := usecase.NewUserUseCase(repos.Users, repos.Sessions, repos.Tokens, repos.Movements, mails, uow, cfg.JWTSecret, cfg.AppURL)
+	bookUC := usecase.NewBookUseCase(repos.Books, repos.Works, repos.Movements, repos.Exchanges, repos.Users, repos.Uploads, bookMetadata, uow, cfg.LoanPeriod)
+	exchangeUC := usecase.NewExchangeUseCase(repos.Exchanges, repos.Books, repos.Users, repos.Movements, uow, cfg.RenewalPeriod, cfg.MaxRenewals)
+	locationUC := usecase.NewLocationUseCase(repos.Locations, repos.Users)
+	reviewUC := usecase.NewReviewUseCase(repos.Reviews, repos.Books, repos.Movements)
+	workUC := usecase.NewWorkUseCase(repos.Works, repos.Books, repos.Reviews)
+	waitlistUC := usecase.NewWaitlistUseCase(repos.Waitlist, repos.Users, uow)
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+	uploadUC := usecase.NewUploadUseCase(repos.Uploads, blobs, cfg.UploadMaxSize)
+
+	router := gin.New()
+	delivery.NewRouter(router, userUC, bookUC, exchangeUC, locationUC, reviewUC, workUC, waitlistUC, uploadUC, scheduler.New(nil), cfg)
+
+	return &testServer{t: t, router: router, repos: repos, mailDir: mailDir, exchanges: exchangeUC}
+}
+
+// response - ответ API с уже прочитанным телом
+type response struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// do выполняет запрос; body сериализуется в JSON, token (если не пустой) уходит в Authorization: Bearer
+func (s *testServer) do(method, path, token string, body any) *response {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("marshal request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return s.serve(req)
+}
+
+// doRaw выполняет запрос с произвольным заголовком Authorization
+func (s *testServer) doRaw(method, path, authorization string) *response {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return s.serve(req)
+}
+
+// upload отправляет data как файл в multipart-поле "file" на POST /uploads
+func (s *testServer) upload(token string, data []byte) *response {
+	s.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "cover")
+	if err != nil {
+		s.t.Fatalf("create form file: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/uploads", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return s.serve(req)
+}
+
+// serve прогоняет готовый запрос через роутер
+func (s *testServer) serve(req *http.Request) *response {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return &response{Code: rec.Code, Header: rec.Header(), Body: rec.Body.Bytes()}
+}
+
+// register регистрирует пользователя, подтверждает почту по письму и возвращает access token
+func (s *testServer) register(email, name string) string {
+	s.t.Helper()
+	resp := s.do(http.MethodPost, "/api/v1/users/registration", "", map[string]string{
+		"email": email, "password": "secret123", "name": name,
+	})
+	resp.expect(s.t, http.StatusCreated)
+	var tokens domain.TokenResponse
+	resp.decode(s.t, &tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		s.t.Fatalf("registration returned empty tokens: %s", resp.Body)
+	}
+	s.do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": s.mailToken(email, "verify-email")}).
+		expect(s.t, http.StatusOK)
+	return tokens.AccessToken
+}
+
+var mailLink = regexp.MustCompile(`https://bookvito\.test/([a-z-]+)\?token=([A-Za-z0-9_-]+)`)
+
+// mailToken возвращает токен из последнего письма на адрес to со ссылкой на страницу page
+func (s *testServer) mailToken(to, page string) string {
+	s.t.Helper()
+	files, err := filepath.Glob(filepath.Join(s.mailDir, "*.eml"))
+	if err != nil {
+		s.t.Fatalf("list mail: %v", err)
+	}
+	// Имена писем упорядочены по времени отправки: ищем с конца
+	for i := len(files) - 1; i >= 0; i-- {
+		f, err := os.Open(files[i])
+		if err != nil {
+			s.t.Fatalf("open mail: %v", err)
+		}
+		msg, err := mail.ReadMessage(f)
+		if err != nil {
+			f.Close()
+			s.t.Fatalf("parse mail %s: %v", files[i], err)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		f.Close()
+		if err != nil {
+			s.t.Fatalf("decode mail %s: %v", files[i], err)
+		}
+		if msg.Header.Get("To") != to {
+			continue
+		}
+		if m := mailLink.FindSubmatch(body); m != nil && string(m[1]) == page {
+			return string(m[2])
+		}
+	}
+	s.t.Fatalf("no %s mail to %s", page, to)
+	return ""
+}
+
+// login входит по почте и паролю из register и возвращает access token
+func (s *testServer) login(email string) string {
+	s.t.Helper()
+	resp := s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{
+		"email": email, "password": "secret123",
+	})
+	resp.expect(s.t, http.StatusOK)
+	var tokens domain.TokenResponse
+	resp.decode(s.t, &tokens)
+	return tokens.AccessToken
+}
+
+// setRole меняет роль напрямую в хранилище; она действует сразу, и для выданных токенов тоже
+func (s *testServer) setRole(email string, role domain.UserRole) {
+	s.t.Helper()
+	user, err := s.repos.Users.GetByEmail(email)
+	if err != nil {
+		s.t.Fatalf("get user %s: %v", email, err)
+	}
+	if err := s.repos.Users.UpdateRole(user.ID, role); err != nil {
+		s.t.Fatalf("update role: %v", err)
+	}
+}
+
+// me возвращает профиль владельца токена
+func (s *testServer) me(token string) domain.User {
+	s.t.Helper()
+	resp := s.do(http.MethodGet, "/api/v1/users/me", token, nil)
+	resp.expect(s.t, http.StatusOK)
+	var user domain.User
+	resp.decode(s.t, &user)
+	return user
+}
+
+// createBook создает книгу и находит ее ID в общем списке (POST /create не возвращает книгу)
+func (s *testServer) createBook(token, title string) uuid.UUID {
+	s.t.Helper()
+	resp := s.do(http.MethodPost, "/api/v1/books/create", token, map[string]string{
+		"title": title, "author": "Автор", "condition": "good",
+	})
+	resp.expect(s.t, http.StatusCreated)
+
+	var books domain.Page[domain.Book]
+	s.do(http.MethodGet, "/api/v1/books/list?limit=100", "", nil).expect(s.t, http.StatusOK).decode(s.t, &books)
+	for _, b := range books.Items {
+		if b.Title == title {
+			return b.ID
+		}
+	}
+	s.t.Fatalf("created book %q not found in list", title)
+	return uuid.Nil
+}
+
+func (s *testServer) getBook(id uuid.UUID) domain.Book {
+	s.t.Helper()
+	var book domain.Book
+	s.do(http.MethodGet, "/api/v1/books/"+id.String(), "", nil).expect(s.t, http.StatusOK).decode(s.t, &book)
+	return book
+}
+
+func (r *response) expect(t *testing.T, code int) *response {
+	t.Helper()
+	if r.Code != code {
+		t.Fatalf("expected status %d, got %d: %s", code, r.Code, r.Body)
+	}
+	return r
+}
+
+func (r *response) decode(t *testing.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("decode response %s: %v", r.Body, err)
+	}
+}
+
+// expectError проверяет статус и поле error в теле ответа
+func (r *response) expectError(t *testing.T, code int, message string) {
+	t.Helper()
+	r.expect(t, code)
+	var body struct {
+		Error string `json:"error"`
+	}
+	r.decode(t, &body)
+	if body.Error != message {
+		t.Fatalf("expected error %q, got %q", message, body.Error)
+	}
+}
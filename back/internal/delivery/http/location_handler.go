@@ -28,8 +28,8 @@ func (h *LocationHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if !checkAdminRole(c) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only admin can create locations"})
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -38,8 +38,8 @@ func (h *LocationHandler) Create(c *gin.Context) {
 		Address: req.Address,
 	}
 
-	if err := h.locationUC.Create(location); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.locationUC.Create(userID, location); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "location created successfully"})
@@ -92,15 +92,14 @@ func (h *LocationHandler) Update(c *gin.Context) {
 		Name    string `json:"name" binding:"required"`
 		Address string `json:"address" binding:"required"`
 	}
-	if !checkAdminRole(c) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only admin can create locations"})
-		return
-	}
-
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
 
 	location := &domain.Location{
 		ID:      id,
@@ -108,8 +107,8 @@ func (h *LocationHandler) Update(c *gin.Context) {
 		Address: req.Address,
 	}
 
-	if err := h.locationUC.Update(location); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.locationUC.Update(userID, location); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -117,12 +116,6 @@ func (h *LocationHandler) Update(c *gin.Context) {
 }
 
 func (h *LocationHandler) Delete(c *gin.Context) {
-
-	if !checkAdminRole(c) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only admin can create locations"})
-		return
-	}
-
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
@@ -130,22 +123,15 @@ func (h *LocationHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.locationUC.Delete(id); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "location not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "location deleted successfully"})
-}
-
-func checkAdminRole(c *gin.Context) bool {
-	userRole, exists := c.Get("role")
-	if !exists {
-		return false
+	if err := h.locationUC.Delete(userID, id); err != nil {
+		respondError(c, err)
+		return
 	}
-	return userRole == "admin"
+
+	c.JSON(http.StatusOK, gin.H{"message": "location deleted successfully"})
 }
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"bookvito/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PageParams - параметры курсорной пагинации: ?cursor=<next_cursor>&limit=20&with_total=true
+type PageParams struct {
+	Cursor    string `form:"cursor"`
+	Limit     int    `form:"limit,default=20" binding:"min=1,max=100"`
+	WithTotal bool   `form:"with_total"`
+}
+
+func (p PageParams) request() domain.PageRequest {
+	return domain.PageRequest{Cursor: p.Cursor, Limit: p.Limit, WithTotal: p.WithTotal}
+}
+
+// bindPage разбирает параметры страницы; при ошибке сам отвечает 400
+func bindPage(c *gin.Context) (domain.PageRequest, bool) {
+	var params PageParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return domain.PageRequest{}, false
+	}
+	return params.request(), true
+}
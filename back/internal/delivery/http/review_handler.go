@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bookvito/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReviewHandler struct {
+	reviewUC domain.ReviewUseCase
+}
+
+func NewReviewHandler(reviewUC domain.ReviewUseCase) *ReviewHandler {
+	return &ReviewHandler{reviewUC: reviewUC}
+}
+
+type ReviewRequest struct {
+	Rating int16  `json:"rating" binding:"required,min=1,max=5"`
+	Text   string `json:"text"`
+}
+
+func (h *ReviewHandler) Create(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	review, err := h.reviewUC.CreateReview(bookID, userID, req.Rating, req.Text)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+func (h *ReviewHandler) List(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	reviews, err := h.reviewUC.GetBookReviews(bookID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+func (h *ReviewHandler) Update(c *gin.Context) {
+	reviewID, err := uuid.Parse(c.Param("reviewId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review ID"})
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	review, err := h.reviewUC.UpdateReview(reviewID, userID, req.Rating, req.Text)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+func (h *ReviewHandler) Delete(c *gin.Context) {
+	reviewID, err := uuid.Parse(c.Param("reviewId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review ID"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.reviewUC.DeleteReview(reviewID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "review deleted successfully"})
+}
@@ -7,12 +7,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(router *gin.Engine, userUC domain.UserUseCase, bookUC domain.BookUseCase, exchangeUC domain.ExchangeUseCase, locationUC domain.LocationUseCase, cfg *config.Config) {
+func NewRouter(router *gin.Engine, userUC domain.UserUseCase, bookUC domain.BookUseCase, exchangeUC domain.ExchangeUseCase, locationUC domain.LocationUseCase, reviewUC domain.ReviewUseCase, workUC domain.WorkUseCase, waitlistUC domain.WaitlistUseCase, uploadUC domain.UploadUseCase, jobs JobStatusProvider, cfg *config.Config) {
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	auth := AuthMiddleware(cfg.JWTSecret, userUC)
+
 	api := router.Group("/api/v1")
 	{
 
@@ -22,12 +24,21 @@ func NewRouter(router *gin.Engine, userUC domain.UserUseCase, bookUC domain.Book
 			users.POST("/registration", userHandler.Register)
 			users.POST("/login", userHandler.Login)
 			users.POST("/refresh", userHandler.Refresh)
-			// TODO: изменение пароля
+			users.POST("/logout", userHandler.Logout)
+			users.POST("/verify-email", userHandler.VerifyEmail)
+			users.POST("/password/forgot", userHandler.ForgotPassword)
+			users.POST("/password/reset", userHandler.ResetPassword)
 
 			authed := users.Group("/")
-			authed.Use(AuthMiddleware(cfg.JWTSecret))
+			authed.Use(auth)
 			authed.GET("/me", userHandler.GetByID)
-			// TODO: получить все брони, историю обменов и т.д.
+			authed.PUT("/me", userHandler.UpdateMe)
+			authed.DELETE("/me", userHandler.DeleteMe)
+			authed.POST("/me/password", userHandler.ChangePassword)
+			authed.POST("/me/verify-email", userHandler.ResendVerification)
+			authed.GET("/me/history", userHandler.GetMyMovementHistory)
+			authed.GET("/me/sessions", userHandler.GetMySessions)
+			authed.POST("/logout-all", userHandler.LogoutAll)
 
 		}
 
@@ -35,25 +46,92 @@ func NewRouter(router *gin.Engine, userUC domain.UserUseCase, bookUC domain.Book
 		{
 			bookHandler := NewBookHandler(bookUC)
 
+			books.GET("", bookHandler.List)
 			books.GET("/summary", bookHandler.GetSummaryList)
 			books.GET("/list", bookHandler.GetList)
+			books.GET("/search", bookHandler.Search)
+			books.GET("/isbn/:isbn", bookHandler.LookupISBN)
 			books.GET("/:id", bookHandler.GetByID)
 
 			// Защищенные маршруты (требуют токен)
 			authed := books.Group("/")
-			authed.Use(AuthMiddleware(cfg.JWTSecret))
+			authed.Use(auth)
 			authed.POST("/create", bookHandler.Create)
 			authed.POST("/request", bookHandler.Request)
 			authed.PUT("/borrow", bookHandler.Borrow)
 			authed.PUT("/return", bookHandler.Return)
 			authed.DELETE("/delete", bookHandler.Delete)
+			authed.GET("/:id/history", bookHandler.GetBookMovementHistory)
+			authed.PUT("/:id", bookHandler.Update)
+			authed.PUT("/:id/archive", bookHandler.Archive)
+			authed.PUT("/:id/unarchive", bookHandler.Unarchive)
+			authed.PUT("/:id/transfer", bookHandler.Transfer)
+
+			reviewHandler := NewReviewHandler(reviewUC)
+			authed.GET("/:id/reviews", reviewHandler.List)
+			authed.POST("/:id/reviews", reviewHandler.Create)
+			authed.PUT("/:id/reviews/:reviewId", reviewHandler.Update)
+			authed.DELETE("/:id/reviews/:reviewId", reviewHandler.Delete)
+
+			waitlistHandler := NewWaitlistHandler(waitlistUC)
+			authed.GET("/:id/waitlist", waitlistHandler.List)
+			authed.POST("/:id/waitlist", waitlistHandler.Join)
+			authed.DELETE("/:id/waitlist", waitlistHandler.Leave)
+		}
+
+		works := api.Group("/works")
+		{
+			workHandler := NewWorkHandler(workUC)
+			works.GET("/:id", workHandler.GetByID)
+			works.GET("/:id/books", workHandler.ListBooks)
+			works.GET("/:id/reviews", workHandler.ListReviews)
+		}
+
+		exchanges := api.Group("/exchanges")
+		exchanges.Use(auth)
+		{
+			exchangeHandler := NewExchangeHandler(exchangeUC)
+			exchanges.GET("/my", exchangeHandler.GetMy)
+			exchanges.GET("/overdue", RequirePermission(domain.PermExchangeReadAll), exchangeHandler.GetOverdue)
+			exchanges.GET("/book/:bookId", exchangeHandler.GetByBook)
+			exchanges.GET("/:id", exchangeHandler.GetByID)
+			exchanges.PUT("/:id/cancel", exchangeHandler.Cancel)
+			exchanges.PUT("/:id/extend", exchangeHandler.Extend)
 		}
+
+		admin := api.Group("/admin")
+		admin.Use(auth)
+		{
+			adminHandler := NewAdminHandler(jobs, userUC, exchangeUC)
+			admin.GET("/jobs", RequirePermission(domain.PermJobsRead), adminHandler.ListJobs)
+
+			readUsers, ban := RequirePermission(domain.PermUserRead), RequirePermission(domain.PermUserBan)
+			admin.GET("/users", readUsers, adminHandler.ListUsers)
+			admin.GET("/users/:id", readUsers, adminHandler.GetUser)
+			admin.GET("/users/:id/history", readUsers, adminHandler.GetUserHistory)
+			admin.GET("/users/:id/exchanges", RequirePermission(domain.PermExchangeReadAll), adminHandler.ListUserExchanges)
+			admin.PUT("/users/:id/role", RequirePermission(domain.PermUserManageRoles), adminHandler.ChangeRole)
+			admin.POST("/users/:id/ban", ban, adminHandler.Ban)
+			admin.DELETE("/users/:id/ban", ban, adminHandler.Unban)
+		}
+
+		uploads := api.Group("/uploads")
+		{
+			uploadHandler := NewUploadHandler(uploadUC, cfg.UploadMaxSize)
+			uploads.GET("/:id/:variant", uploadHandler.Get)
+			uploads.POST("", auth, uploadHandler.Create)
+		}
+
 		locations := api.Group("/locations")
 		{
 			locationHandler := NewLocationHandler(locationUC)
 			locations.GET("/:id", locationHandler.GetByID)
 			locations.GET("/getAll", locationHandler.GetAll)
 
+			write := RequirePermission(domain.PermLocationWrite)
+			locations.POST("", auth, write, locationHandler.Create)
+			locations.PUT("/:id", auth, write, locationHandler.Update)
+			locations.DELETE("/:id", auth, write, locationHandler.Delete)
 		}
 	}
 }
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Варианты картинки не меняются после загрузки, поэтому их можно кешировать навсегда
+const imageCacheControl = "public, max-age=31536000, immutable"
+
+type UploadHandler struct {
+	uploadUC domain.UploadUseCase
+	maxSize  int64
+}
+
+func NewUploadHandler(uploadUC domain.UploadUseCase, maxSize int64) *UploadHandler {
+	return &UploadHandler{uploadUC: uploadUC, maxSize: maxSize}
+}
+
+// Create принимает multipart/form-data с картинкой в поле "file"
+func (h *UploadHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	// Запас на заголовки multipart; сам файл проверяет use case
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+1<<20)
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, domain.ErrImageTooLarge)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"file\" is required"})
+		return
+	}
+	if header.Size > h.maxSize {
+		respondError(c, domain.ErrImageTooLarge)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	upload, err := h.uploadUC.UploadImage(c.Request.Context(), userID, file)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// Get отдает вариант картинки (thumb или full) с заголовками кеширования
+func (h *UploadHandler) Get(c *gin.Context) {
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid upload ID"})
+		return
+	}
+	variant := domain.ImageVariant(c.Param("variant"))
+
+	etag := `"` + uploadID.String() + "-" + string(variant) + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Header("Cache-Control", imageCacheControl)
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	body, info, err := h.uploadUC.OpenImage(c.Request.Context(), uploadID, variant)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, body, map[string]string{
+		"Cache-Control": imageCacheControl,
+		"ETag":          etag,
+		"Last-Modified": info.ModTime.UTC().Format(http.TimeFormat),
+	})
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bookvito/internal/domain"
+	"errors"
 	"net/http"
 
 	// "strconv"
@@ -47,6 +48,7 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required,min=5,max=50"`
+	Device   string `json:"device"` // Название устройства для списка сессий; по умолчанию User-Agent
 }
 
 func (h *UserHandler) Register(c *gin.Context) {
@@ -56,7 +58,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	tokens, err := h.userUC.RegisterUser(req.Email, req.Password, req.Name)
+	tokens, err := h.userUC.RegisterUser(req.Email, req.Password, req.Name, clientInfo(c, req.Device))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -68,6 +70,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	Device   string `json:"device"`
 }
 
 func (h *UserHandler) Login(c *gin.Context) {
@@ -77,7 +80,11 @@ func (h *UserHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	tokens, err := h.userUC.LoginUser(req.Email, req.Password)
+	tokens, err := h.userUC.LoginUser(req.Email, req.Password, clientInfo(c, req.Device))
+	if errors.Is(err, domain.ErrUserBanned) {
+		respondError(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -96,14 +103,64 @@ func (h *UserHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	tokens, err := h.userUC.RefreshToken(req.RefreshToken)
+	tokens, err := h.userUC.RefreshToken(req.RefreshToken, clientInfo(c, ""))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, tokens)
 }
 
+// Logout завершает сессию по ее refresh-токену; access-токен не нужен, он мог уже истечь
+func (h *UserHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userUC.Logout(req.RefreshToken); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// LogoutAll завершает все сессии текущего пользователя
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.userUC.LogoutAll(userID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions logged out successfully"})
+}
+
+// GetMySessions возвращает активные сессии текущего пользователя
+func (h *UserHandler) GetMySessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	sessions, err := h.userUC.ListSessions(userID, currentSessionID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// clientInfo описывает клиента для сессии: название устройства из запроса или User-Agent
+func clientInfo(c *gin.Context, device string) domain.ClientInfo {
+	if device == "" {
+		device = c.Request.UserAgent()
+	}
+	return domain.ClientInfo{Device: device, IP: c.ClientIP()}
+}
+
 func (h *UserHandler) GetMyMovementHistory(c *gin.Context) {
 	userID, ok := c.Get("userId")
 	if !ok {
@@ -111,11 +168,147 @@ func (h *UserHandler) GetMyMovementHistory(c *gin.Context) {
 		return
 	}
 
-	history, err := h.userUC.GetUserMovementHistory(userID.(string))
+	page, ok := bindPage(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.userUC.GetUserMovementHistory(userID.(string), page)
 	if err != nil {
 		// В usecase уже есть проверка на формат UUID, но на всякий случай
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, history)
 }
+
+type UpdateProfileRequest struct {
+	Name  string `json:"name" binding:"required,min=5,max=50"`
+	Email string `json:"email" binding:"required,email"`
+}
+
+// UpdateMe меняет имя и почту текущего пользователя
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userUC.UpdateProfile(userID, req.Name, req.Email)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePassword меняет пароль и выдает новую пару токенов; все прежние сессии завершаются
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.userUC.ChangePassword(userID, req.OldPassword, req.NewPassword, clientInfo(c, ""))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// DeleteMe удаляет аккаунт текущего пользователя
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.userUC.DeleteAccount(userID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted successfully"})
+}
+
+type AccountTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail подтверждает почту по токену из письма
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req AccountTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.userUC.VerifyEmail(req.Token); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+// ResendVerification повторно отправляет письмо для подтверждения почты
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.userUC.ResendVerification(userID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword отправляет письмо для сброса пароля. Ответ одинаковый для любой почты.
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.userUC.RequestPasswordReset(req.Email); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "if the account exists, a password reset email has been sent"})
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ResetPassword задает новый пароль по токену из письма
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.userUC.ResetPassword(req.Token, req.NewPassword); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"bookvito/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WaitlistHandler struct {
+	waitlistUC domain.WaitlistUseCase
+}
+
+func NewWaitlistHandler(waitlistUC domain.WaitlistUseCase) *WaitlistHandler {
+	return &WaitlistHandler{waitlistUC: waitlistUC}
+}
+
+func (h *WaitlistHandler) Join(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.waitlistUC.JoinWaitlist(bookID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *WaitlistHandler) Leave(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.waitlistUC.LeaveWaitlist(bookID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "left the waitlist successfully"})
+}
+
+func (h *WaitlistHandler) List(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	places, err := h.waitlistUC.GetWaitlist(bookID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, places)
+}
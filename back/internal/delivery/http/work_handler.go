@@ -0,0 +1,72 @@
+package http
+
+import (
+	"bookvito/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WorkHandler struct {
+	workUC domain.WorkUseCase
+}
+
+func NewWorkHandler(workUC domain.WorkUseCase) *WorkHandler {
+	return &WorkHandler{workUC: workUC}
+}
+
+// GetByID возвращает произведение: сколько экземпляров свободно и в каких пунктах
+func (h *WorkHandler) GetByID(c *gin.Context) {
+	workID, ok := parseWorkID(c)
+	if !ok {
+		return
+	}
+
+	work, err := h.workUC.GetWork(workID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, work)
+}
+
+func (h *WorkHandler) ListBooks(c *gin.Context) {
+	workID, ok := parseWorkID(c)
+	if !ok {
+		return
+	}
+
+	books, err := h.workUC.GetWorkBooks(workID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, books)
+}
+
+func (h *WorkHandler) ListReviews(c *gin.Context) {
+	workID, ok := parseWorkID(c)
+	if !ok {
+		return
+	}
+
+	reviews, err := h.workUC.GetWorkReviews(workID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+func parseWorkID(c *gin.Context) (uuid.UUID, bool) {
+	workID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid work ID"})
+		return uuid.Nil, false
+	}
+	return workID, true
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenPurpose - для чего выдан одноразовый токен из письма
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenResetPassword TokenPurpose = "reset_password"
+)
+
+// AccountToken - одноразовый токен с ограниченным сроком из письма пользователю.
+// Сам токен уходит только в письмо, в базе хранится его SHA-256.
+type AccountToken struct {
+	ID        uuid.UUID    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID    `gorm:"type:uuid;not null;index"`
+	Purpose   TokenPurpose `gorm:"type:varchar(20);not null"`
+	TokenHash string       `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Email     string       `gorm:"not null"` // Адрес, на который ушло письмо; после смены почты токен недействителен
+	CreatedAt time.Time    `gorm:"autoCreateTime"`
+	ExpiresAt time.Time    `gorm:"not null"`
+	UsedAt    *time.Time   // Когда токен использован или заменен новым
+}
+
+// Usable сообщает, можно ли еще использовать токен
+func (t *AccountToken) Usable(at time.Time) bool {
+	return t.UsedAt == nil && at.Before(t.ExpiresAt)
+}
+
+// Mail - текстовое письмо одному получателю
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма. Реализации - SMTP и запись писем в файлы или в лог для разработки и тестов.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
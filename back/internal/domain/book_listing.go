@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookSort - поле сортировки каталога
+type BookSort string
+
+const (
+	BookSortTitle     BookSort = "title"
+	BookSortCreatedAt BookSort = "created_at"
+	BookSortRating    BookSort = "rating"
+)
+
+// DefaultDesc - направление сортировки по умолчанию: новые и высоко оцененные сверху, названия по алфавиту
+func (s BookSort) DefaultDesc() bool {
+	return s != BookSortTitle
+}
+
+// Valid сообщает, поддерживается ли сортировка
+func (s BookSort) Valid() bool {
+	switch s {
+	case BookSortTitle, BookSortCreatedAt, BookSortRating:
+		return true
+	}
+	return false
+}
+
+// BookFilter - параметры каталога. Внутри одного фильтра значения объединяются через ИЛИ,
+// разные фильтры - через И. Пустой фильтр не ограничивает выборку.
+type BookFilter struct {
+	Statuses    []BookStatus // Удаленные книги в каталог не попадают при любом фильтре
+	Conditions  []BookCondition
+	Authors     []string // Без учета регистра
+	LocationIDs []uuid.UUID
+	OwnerIDs    []uuid.UUID
+	CreatedFrom *time.Time // created_at >= CreatedFrom
+	CreatedTo   *time.Time // created_at < CreatedTo
+
+	Sort  BookSort
+	Desc  bool
+	After *Cursor // Курсор вида BookFilter.CursorKind()
+	Limit int
+}
+
+// CursorKind - вид курсора каталога: при другой сортировке старый курсор недействителен
+func (f BookFilter) CursorKind() string {
+	dir := "asc"
+	if f.Desc {
+		dir = "desc"
+	}
+	return CursorBooks + ":" + string(f.Sort) + ":" + dir
+}
+
+// Cursor - позиция карточки в каталоге с сортировкой фильтра
+func (f BookFilter) Cursor(item *BookListItem) Cursor {
+	c := Cursor{Kind: f.CursorKind(), ID: item.ID}
+	switch f.Sort {
+	case BookSortTitle:
+		title := item.Title
+		c.Text = &title
+	case BookSortRating:
+		c.Number = item.AverageRating
+	default:
+		createdAt := item.CreatedAt
+		c.Time = &createdAt
+	}
+	return c
+}
+
+// BookListItem - карточка книги в каталоге
+type BookListItem struct {
+	ID                uuid.UUID     `json:"id"`
+	WorkID            *uuid.UUID    `json:"work_id"`
+	Title             string        `json:"title"`
+	Author            string        `json:"author"`
+	ImageURL          string        `json:"image_url"`
+	Status            BookStatus    `json:"status"`
+	Condition         BookCondition `json:"condition"`
+	OwnerID           uuid.UUID     `json:"owner_id"`
+	CurrentLocationID *uuid.UUID    `json:"current_location_id"`
+	CreatedAt         time.Time     `json:"created_at"`
+	AverageRating     *float64      `json:"average_rating"` // nil, если отзывов нет
+	ReviewsCount      int64         `json:"reviews_count"`
+}
+
+// FacetCount - сколько книг подходит под значение фильтра
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"` // Человекочитаемое имя (название пункта, имя владельца)
+	Count int64  `json:"count"`
+}
+
+// BookFacets - счетчики для чипов фильтров. Счетчики поля считаются с учетом всех
+// остальных фильтров, но без фильтра по самому полю - чтобы было видно альтернативы.
+type BookFacets struct {
+	Status    []FacetCount `json:"status"`
+	Condition []FacetCount `json:"condition"`
+	Author    []FacetCount `json:"author"`
+	Location  []FacetCount `json:"location"`
+	Owner     []FacetCount `json:"owner"`
+}
+
+// MaxFacetValues - сколько самых частых значений возвращать для автора, пункта и владельца
+const MaxFacetValues = 20
+
+// BookListPage - страница каталога вместе со счетчиками для фильтров
+type BookListPage struct {
+	Page[*BookListItem]
+	Facets *BookFacets `json:"facets"`
+}
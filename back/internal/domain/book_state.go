@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Actor - в какой роли пользователь (или система) меняет статус книги
+type Actor string
+
+const (
+	ActorOwner     Actor = "owner"     // Владелец книги
+	ActorRequester Actor = "requester" // Тот, кто держит текущую бронь (запросил или взял книгу)
+	ActorModer     Actor = "moder"
+	ActorAdmin     Actor = "admin"
+	ActorSystem    Actor = "system" // Фоновые задачи
+)
+
+// Действия, которые записываются в BookMovementHistory.Action
+const (
+	ActionCreated          = "created"
+	ActionRequested        = "requested"
+	ActionRequestCancelled = "request_cancelled"
+	ActionBorrowed         = "borrowed"
+	ActionReturned         = "returned"
+	ActionOverdue          = "overdue" // Срок возврата истек; статус книги не меняется
+	ActionRenewed          = "renewed" // Выдача продлена; статус книги не меняется
+	ActionArchived         = "archived"
+	ActionUnarchived       = "unarchived"
+	ActionDeleted          = "deleted"
+	ActionUpdated          = "updated"     // Владелец изменил данные книги; статус не меняется
+	ActionTransferred      = "transferred" // Книга передана другому владельцу; статус не меняется
+)
+
+// BookTransition - разрешенный переход статуса книги
+type BookTransition struct {
+	From   BookStatus
+	To     BookStatus
+	Action string  // Что записать в историю перемещений
+	Actors []Actor // Кто может выполнить переход
+}
+
+// bookTransitions - единственный источник правды о том, как может меняться статус книги
+var bookTransitions = []BookTransition{
+	{From: "", To: BookAvailable, Action: ActionCreated, Actors: []Actor{ActorOwner}},
+	{From: BookAvailable, To: BookRequested, Action: ActionRequested, Actors: []Actor{ActorRequester, ActorSystem}}, // system - выдача следующему в очереди
+	{From: BookRequested, To: BookAvailable, Action: ActionRequestCancelled, Actors: []Actor{ActorRequester, ActorModer, ActorAdmin, ActorSystem}},
+	{From: BookRequested, To: BookBorrowed, Action: ActionBorrowed, Actors: []Actor{ActorRequester}},
+	{From: BookBorrowed, To: BookAvailable, Action: ActionReturned, Actors: []Actor{ActorRequester, ActorOwner, ActorModer, ActorAdmin}},
+	{From: BookBorrowed, To: BookDeleted, Action: ActionDeleted, Actors: []Actor{ActorRequester, ActorModer, ActorAdmin}},
+	{From: BookAvailable, To: BookArchived, Action: ActionArchived, Actors: []Actor{ActorOwner, ActorModer, ActorAdmin}},
+	{From: BookArchived, To: BookAvailable, Action: ActionUnarchived, Actors: []Actor{ActorOwner, ActorModer, ActorAdmin}},
+}
+
+// BookTransitions возвращает копию таблицы переходов (например, для документации API)
+func BookTransitions() []BookTransition {
+	transitions := make([]BookTransition, len(bookTransitions))
+	copy(transitions, bookTransitions)
+	return transitions
+}
+
+// TransitionBook проверяет переход from -> to для пользователя, выступающего в ролях actors.
+// Возвращает ErrInvalidTransition, если такого перехода нет, и ErrForbidden,
+// если переход есть, но ни одна из ролей не может его выполнить.
+func TransitionBook(from, to BookStatus, actors ...Actor) (*BookTransition, error) {
+	for i := range bookTransitions {
+		t := &bookTransitions[i]
+		if t.From != from || t.To != to {
+			continue
+		}
+		for _, allowed := range t.Actors {
+			for _, actor := range actors {
+				if actor == allowed {
+					return t, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%w: %s -> %s", ErrForbidden, from, to)
+	}
+	return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
+
+// Movement создает запись истории для этого перехода
+func (t *BookTransition) Movement(bookID uuid.UUID, userID *uuid.UUID) *BookMovementHistory {
+	return &BookMovementHistory{
+		BookID:         bookID,
+		UserID:         userID,
+		Action:         t.Action,
+		PreviousStatus: t.From,
+		NewStatus:      t.To,
+	}
+}
+
+// BookEdit - разрешенное действие над книгой, которое не меняет ее статус
+type BookEdit struct {
+	Action   string
+	Statuses []BookStatus // В каких статусах книги действие доступно
+	Actors   []Actor      // Кто может выполнить действие
+}
+
+// bookEdits - правила для действий владельца, не затрагивающих статус книги
+var bookEdits = []BookEdit{
+	{Action: ActionUpdated, Statuses: []BookStatus{BookAvailable, BookRequested, BookBorrowed, BookArchived}, Actors: []Actor{ActorOwner, ActorModer, ActorAdmin}},
+	{Action: ActionTransferred, Statuses: []BookStatus{BookAvailable, BookArchived}, Actors: []Actor{ActorOwner, ActorAdmin}}, // Не на руках и не в брони
+}
+
+// EditBook проверяет действие action над книгой в статусе status, как TransitionBook:
+// ErrBookLocked, если в этом статусе действие недоступно, и ErrForbidden,
+// если ни одна из ролей не может его выполнить.
+func EditBook(status BookStatus, action string, actors ...Actor) (*BookEdit, error) {
+	for i := range bookEdits {
+		e := &bookEdits[i]
+		if e.Action != action {
+			continue
+		}
+		if !slices.Contains(e.Statuses, status) {
+			return nil, fmt.Errorf("%w: %s while %s", ErrBookLocked, action, status)
+		}
+		for _, actor := range actors {
+			if slices.Contains(e.Actors, actor) {
+				return e, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrForbidden, action)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrBookLocked, action)
+}
+
+// Movement создает запись истории для этого действия над книгой в статусе status
+func (e *BookEdit) Movement(bookID uuid.UUID, userID *uuid.UUID, status BookStatus) *BookMovementHistory {
+	return &BookMovementHistory{
+		BookID:         bookID,
+		UserID:         userID,
+		Action:         e.Action,
+		PreviousStatus: status,
+		NewStatus:      status,
+	}
+}
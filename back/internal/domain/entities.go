@@ -59,11 +59,13 @@ type User struct {
 	Name      string     `gorm:"not null" json:"name"`                        // Имя
 	Role      UserRole   `gorm:"type:varchar(20);default:'user'" json:"role"` // Роль
 	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`            // Дата создания
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`                        // Когда аккаунт удален (данные обезличены, строка остается для истории)
 	Exchanges []Exchange `gorm:"foreignKey:UserID" json:"exchanges,omitempty"`
 	Reviews   []Review   `gorm:"foreignKey:UserID" json:"reviews,omitempty"`
 
-	RefreshToken          string    `json:"-"` // Поле для Refresh токена
-	RefreshTokenExpiresAt time.Time `json:"-"` // Время жизни Refresh токена
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // Когда подтверждена текущая почта; nil - не подтверждена
+
+	UserBan `gorm:"embedded"`
 }
 
 // Book represents a book (Книга)
@@ -71,14 +73,17 @@ type Book struct {
 	ID                uuid.UUID     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	OwnerID           uuid.UUID     `gorm:"type:uuid;not null" json:"owner_id"` // ID владельца книги
 	Owner             *User         `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	WorkID            *uuid.UUID    `gorm:"type:uuid;index" json:"work_id"`                     // Произведение, экземпляром которого является книга
 	Title             string        `gorm:"not null" json:"title"`                              // Название
 	Author            string        `gorm:"not null" json:"author"`                             // Автор
+	ISBN              string        `gorm:"type:varchar(13);index" json:"isbn,omitempty"`       // ISBN-13 издания (см. NormalizeISBN)
 	Description       string        `gorm:"type:text" json:"description"`                       // Описание
 	Condition         BookCondition `gorm:"type:varchar(20);default:'good'" json:"condition"`   // Состояние
 	Status            BookStatus    `gorm:"type:varchar(20);default:'available'" json:"status"` // Бронь (состояние)
 	CurrentLocationID *uuid.UUID    `gorm:"type:uuid" json:"current_location_id"`               // Текущая позиция (ссылка на пункт выдачи)
 	CurrentLocation   *Location     `gorm:"foreignKey:CurrentLocationID" json:"current_location,omitempty"`
 	ImageURL          string        `json:"image_url"`                        // Картинка
+	ImageID           *uuid.UUID    `gorm:"type:uuid" json:"image_id"`        // Загруженная обложка (ImageURL указывает на нее)
 	UpdatedAt         time.Time     `gorm:"autoUpdateTime" json:"updated_at"` // Последнее изменение
 	CreatedAt         time.Time     `gorm:"autoCreateTime" json:"created_at"`
 	Reviews           []Review      `gorm:"foreignKey:BookID" json:"reviews,omitempty"` // Отзывы
@@ -86,10 +91,38 @@ type Book struct {
 }
 
 type BookSummary struct {
-	ID       uuid.UUID `json:"id" db:"id"`
-	ImageURL string    `json:"image_url" db:"image_url"`
-	Title    string    `json:"title" db:"title"`
-	Author   string    `json:"author" db:"author"`
+	ID        uuid.UUID `json:"id" db:"id"`
+	ImageURL  string    `json:"image_url" db:"image_url"`
+	Title     string    `json:"title" db:"title"`
+	Author    string    `json:"author" db:"author"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"` // Для курсора страницы
+}
+
+// BookSearchResult - произведение, найденное полнотекстовым поиском. Экземпляры одного
+// произведения схлопываются в один результат; ID и поля экземпляра - у лучшего из них
+// (сначала свободные, затем самые релевантные).
+type BookSearchResult struct {
+	ID                uuid.UUID     `json:"id"`
+	WorkID            *uuid.UUID    `json:"work_id"`
+	Title             string        `json:"title"`
+	Author            string        `json:"author"`
+	ImageURL          string        `json:"image_url"`
+	Status            BookStatus    `json:"status"`
+	Condition         BookCondition `json:"condition"`
+	CurrentLocationID *uuid.UUID    `json:"current_location_id"`
+	Rank              float64       `json:"rank"`             // Релевантность, чем больше - тем выше в выдаче
+	Headline          string        `json:"headline"`         // Название с подсвеченными совпадениями (<mark>...</mark>)
+	Snippet           string        `json:"snippet"`          // Фрагмент описания с подсвеченными совпадениями
+	Copies            int64         `json:"copies"`           // Экземпляры произведения (без удаленных)
+	AvailableCopies   int64         `json:"available_copies"` // Из них свободные
+}
+
+// BookSearchPage - страница результатов поиска
+type BookSearchPage struct {
+	Items  []*BookSearchResult `json:"items"`
+	Total  int64               `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
 }
 
 // Exchange represents a book reservation/borrowing (Бронирование)
@@ -102,6 +135,9 @@ type Exchange struct {
 	Status     ExchangeStatus `gorm:"type:varchar(20);default:'requested'" json:"status"` // Статус
 	BookedAt   time.Time      `gorm:"autoCreateTime" json:"booked_at"`                    // Когда забронировано
 	ExpiresAt  *time.Time     `json:"expires_at"`                                         // Время, до которого бронь действительна
+	BorrowedAt *time.Time     `json:"borrowed_at"`                                        // Когда книгу забрали
+	DueAt      *time.Time     `gorm:"index" json:"due_at"`                                // Срок возврата книги
+	Renewals   int            `gorm:"not null;default:0" json:"renewals"`                 // Сколько раз выдачу продлевали
 	LocationID *uuid.UUID     `gorm:"type:uuid" json:"location_id"`                       // Пункт выдачи
 	Location   *Location      `gorm:"foreignKey:LocationID" json:"location,omitempty"`
 }
@@ -109,9 +145,9 @@ type Exchange struct {
 // Review represents a book review (Отзывы)
 type Review struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	BookID    uuid.UUID `gorm:"type:uuid;not null" json:"book_id"` // Книга
+	BookID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_reviews_book_user" json:"book_id"` // Книга
 	Book      Book      `gorm:"foreignKey:BookID" json:"book,omitempty"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"` // Пользователь
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_reviews_book_user" json:"user_id"` // Пользователь (один отзыв на книгу)
 	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Rating    int16     `gorm:"type:smallint;not null" json:"rating"` // Оценка (маленький int)
 	Text      string    `gorm:"type:text" json:"text"`                // Текст отзыва
@@ -137,3 +173,22 @@ type BookMovementHistory struct {
 	NewStatus      BookStatus `gorm:"type:varchar(20)" json:"new_status"`      // Новый статус книги
 	CreatedAt      time.Time  `gorm:"autoCreateTime;index" json:"created_at"`  // Время перемещения
 }
+
+// WaitlistEntry represents a place in the queue for a book (Очередь на книгу)
+type WaitlistEntry struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	BookID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_waitlist_book_user" json:"book_id"` // Книга
+	Book      *Book     `gorm:"foreignKey:BookID" json:"book,omitempty"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_waitlist_book_user" json:"user_id"` // Кто ждет (один раз на книгу)
+	User      *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"` // Время встать в очередь (определяет порядок)
+	Position  int       `gorm:"-" json:"position,omitempty"`            // Место в очереди, начиная с 1 (не хранится)
+}
+
+// WaitlistPlace - место в очереди в том виде, в каком его видят другие пользователи:
+// без почты и служебных полей ждущего
+type WaitlistPlace struct {
+	Position int       `json:"position"`
+	UserName string    `json:"user_name"`
+	JoinedAt time.Time `json:"joined_at"`
+}
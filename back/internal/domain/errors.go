@@ -0,0 +1,62 @@
+package domain
+
+import "errors"
+
+// Ошибки бизнес-логики, которые delivery-слой переводит в HTTP-статусы
+var (
+	ErrForbidden = errors.New("action is not allowed for this user")
+
+	ErrEmailTaken      = errors.New("user with this email already exists")
+	ErrWrongPassword   = errors.New("current password is incorrect")
+	ErrActiveLoans     = errors.New("return borrowed books before deleting the account")
+	ErrOwnedBooksInUse = errors.New("books you own are reserved or on loan, wait until they are returned")
+	ErrAccountDeleted  = errors.New("account has been deleted")
+
+	ErrUserBanned     = errors.New("account is banned")
+	ErrInvalidRole    = errors.New("unknown user role")
+	ErrInvalidBan     = errors.New("ban needs a reason and an expiry in the future")
+	ErrSelfModeration = errors.New("you cannot change your own role or ban yourself")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, the session is revoked")
+
+	ErrInvalidAccountToken  = errors.New("token is invalid, expired or has already been used")
+	ErrEmailNotVerified     = errors.New("confirm your email before requesting books")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+
+	ErrInvalidCursor    = errors.New("invalid or foreign page cursor")
+	ErrInvalidRating    = errors.New("rating must be between 1 and 5")
+	ErrReviewExists     = errors.New("user has already reviewed this book")
+	ErrReviewNotAllowed = errors.New("only users who borrowed the book can review it")
+
+	ErrBookNotAvailable  = errors.New("book is not available for request")
+	ErrBookConflict      = errors.New("book was modified by another request")
+	ErrInvalidTransition = errors.New("book status transition is not allowed")
+	ErrBookLocked        = errors.New("action is not allowed in the current book status")
+	ErrSameOwner         = errors.New("book already belongs to this user")
+	ErrInvalidNewOwner   = errors.New("book cannot be transferred to this user")
+	ErrEmptySearchQuery  = errors.New("search query is empty")
+	ErrInvalidBookFilter = errors.New("invalid book filter")
+	ErrInvalidISBN       = errors.New("invalid ISBN")
+	ErrMetadataNotFound  = errors.New("no book metadata found for this ISBN")
+	ErrBookDetails       = errors.New("title and author are required unless a known ISBN is given")
+
+	ErrWaitlistNotNeeded = errors.New("book is available, request it instead of joining the waitlist")
+	ErrWaitlistClosed    = errors.New("book is not accepting a waitlist")
+	ErrAlreadyInWaitlist = errors.New("user is already in the waitlist or holds this book")
+
+	ErrExchangeNotActive   = errors.New("exchange is not an active reservation")
+	ErrExchangeConflict    = errors.New("exchange was modified by another request")
+	ErrReservationExpired  = errors.New("reservation has expired")
+	ErrExchangeExtendLimit = errors.New("reservation cannot be extended any further")
+	ErrRenewalLimit        = errors.New("loan has reached the maximum number of renewals")
+	ErrRenewalBlocked      = errors.New("loan cannot be renewed while other users are waiting for the book")
+	ErrLoanOverdue         = errors.New("overdue loan cannot be renewed, please return the book")
+
+	ErrUnsupportedImage = errors.New("unsupported image type, use JPEG, PNG, GIF or WebP")
+	ErrImageTooLarge    = errors.New("image is too large")
+	ErrInvalidImage     = errors.New("image cannot be decoded")
+	ErrBlobNotFound     = errors.New("file not found")
+	ErrUnknownUpload    = errors.New("image upload not found")
+)
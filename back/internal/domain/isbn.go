@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"context"
+	"strings"
+)
+
+// NormalizeISBN проверяет контрольную цифру ISBN-10 или ISBN-13 и возвращает ISBN-13
+// без разделителей: одно издание с разной записью ISBN хранится одинаково.
+func NormalizeISBN(s string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == ' ':
+			return -1
+		case r == 'x':
+			return 'X'
+		}
+		return r
+	}, strings.TrimSpace(s))
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", ErrInvalidISBN
+		}
+		isbn := "978" + digits[:9]
+		return isbn + string(isbn13CheckDigit(isbn)), nil
+	case 13:
+		if !allDigits(digits) || (!strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979")) {
+			return "", ErrInvalidISBN
+		}
+		if isbn13CheckDigit(digits[:12]) != digits[12] {
+			return "", ErrInvalidISBN
+		}
+		return digits, nil
+	default:
+		return "", ErrInvalidISBN
+	}
+}
+
+// validISBN10: сумма цифр с весами 10..1 делится на 11; последняя цифра может быть X (10)
+func validISBN10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var d int
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			d = int(s[i] - '0')
+		case s[i] == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += d * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// isbn13CheckDigit считает контрольную цифру по первым 12 цифрам (веса 1 и 3 по очереди)
+func isbn13CheckDigit(s string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// BookMetadata - описание издания по ISBN из внешнего источника
+type BookMetadata struct {
+	ISBN        string `json:"isbn"`
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	Description string `json:"description,omitempty"`
+	CoverURL    string `json:"cover_url,omitempty"`
+}
+
+// MetadataProvider находит описание издания по ISBN-13 (результат NormalizeISBN).
+// Возвращает ErrMetadataNotFound, если издание неизвестно.
+type MetadataProvider interface {
+	LookupISBN(ctx context.Context, isbn string) (*BookMetadata, error)
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserBan - блокировка пользователя. Пустая структура - пользователь не заблокирован.
+// Меняется только через UserRepository.UpdateBan.
+type UserBan struct {
+	BannedAt    *time.Time `json:"banned_at,omitempty"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"` // nil - бессрочно; истекшая блокировка снимается сама
+	BanReason   string     `gorm:"not null;default:''" json:"ban_reason,omitempty"`
+	BannedBy    *uuid.UUID `gorm:"type:uuid" json:"banned_by,omitempty"` // Модератор или администратор
+}
+
+// Banned сообщает, действует ли блокировка в момент at
+func (b UserBan) Banned(at time.Time) bool {
+	return b.BannedAt != nil && (b.BannedUntil == nil || at.Before(*b.BannedUntil))
+}
+
+// CheckAccess возвращает ошибку, если пользователь в момент at не может пользоваться аккаунтом
+func (u *User) CheckAccess(at time.Time) error {
+	if u.DeletedAt != nil {
+		return ErrAccountDeleted
+	}
+	if !u.Banned(at) {
+		return nil
+	}
+	if u.BannedUntil == nil {
+		return fmt.Errorf("%w: %s", ErrUserBanned, u.BanReason)
+	}
+	return fmt.Errorf("%w until %s: %s", ErrUserBanned, u.BannedUntil.UTC().Format(time.RFC3339), u.BanReason)
+}
+
+// UserFilter - отбор пользователей в админке
+type UserFilter struct {
+	Query string   // Подстрока почты или имени без учета регистра
+	Role  UserRole // Пустая - любая роль
+}
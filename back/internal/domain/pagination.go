@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Списки отдаются страницами по курсору (keyset-пагинация): следующая страница начинается
+// строго после последней записи предыдущей, поэтому новые записи не сдвигают страницы.
+
+// Виды курсоров: курсор одного списка нельзя подставить в другой
+const (
+	CursorUsers     = "users"
+	CursorBooks     = "books"
+	CursorExchanges = "exchanges"
+	CursorMovements = "movements"
+)
+
+// Cursor - позиция в списке: ключ сортировки последней записи страницы и ее ID.
+// Заполняется одно из полей ключа - в зависимости от сортировки списка.
+type Cursor struct {
+	Kind   string     `json:"k"`
+	Time   *time.Time `json:"t,omitempty"`
+	Text   *string    `json:"s,omitempty"`
+	Number *float64   `json:"n,omitempty"` // nil - NULL (например, у книги нет оценок)
+	ID     uuid.UUID  `json:"id"`
+}
+
+// TimeCursor - курсор списка, упорядоченного по времени и ID
+func TimeCursor(kind string, t time.Time, id uuid.UUID) Cursor {
+	return Cursor{Kind: kind, Time: &t, ID: id}
+}
+
+// Encode превращает курсор в непрозрачную строку для клиента
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает строку из Encode; пустая строка - первая страница
+func DecodeCursor(s, kind string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Kind != kind {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Размер страницы по умолчанию и максимальный
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest - запрос страницы от клиента
+type PageRequest struct {
+	Cursor    string // NextCursor предыдущей страницы; пустой - первая страница
+	Limit     int
+	WithTotal bool // Посчитать общее число записей (отдельный запрос)
+}
+
+// PageQuery - запрос страницы к репозиторию: записи строго после After в порядке списка.
+// Отрицательный Limit - без ограничения.
+type PageQuery struct {
+	After *Cursor
+	Limit int
+}
+
+// Page - страница списка
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Total      *int64 `json:"total,omitempty"`
+}
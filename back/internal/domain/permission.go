@@ -0,0 +1,69 @@
+package domain
+
+import "fmt"
+
+// Permission - действие, которое разрешается роли целиком, независимо от того, чья это книга или бронь.
+// Права владельца и участника брони проверяются отдельно в сценариях (см. book_state.go).
+type Permission string
+
+const (
+	PermLocationWrite   Permission = "location:write"    // Создание, изменение и удаление пунктов выдачи
+	PermBookModerate    Permission = "book:moderate"     // Правка и архивирование чужих книг
+	PermBookManage      Permission = "book:manage"       // Все переходы книги, включая передачу владельцу
+	PermExchangeReadAll Permission = "exchange:read_all" // Просмотр чужих броней и просроченных выдач
+	PermUserRead        Permission = "user:read"         // Поиск пользователей, их профили и история
+	PermUserBan         Permission = "user:ban"          // Блокировка пользователей
+	PermUserManageRoles Permission = "user:manage_roles" // Назначение ролей
+	PermJobsRead        Permission = "jobs:read"         // Состояние фоновых задач
+)
+
+// rolePermissions - права каждой роли. Обычный пользователь не имеет ни одного:
+// все его действия ограничены собственными книгами и бронями.
+var rolePermissions = map[UserRole][]Permission{
+	RoleUser: nil,
+	RoleModer: {
+		PermBookModerate,
+		PermExchangeReadAll,
+		PermUserRead,
+		PermUserBan,
+	},
+	RoleAdmin: {
+		PermLocationWrite,
+		PermBookModerate,
+		PermBookManage,
+		PermExchangeReadAll,
+		PermUserRead,
+		PermUserBan,
+		PermUserManageRoles,
+		PermJobsRead,
+	},
+}
+
+// Valid сообщает, известна ли роль
+func (r UserRole) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions возвращает права роли; у неизвестной роли прав нет
+func (r UserRole) Permissions() []Permission {
+	return append([]Permission(nil), rolePermissions[r]...)
+}
+
+// Can сообщает, есть ли у роли право p
+func (r UserRole) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize возвращает ErrForbidden с названием недостающего права
+func (r UserRole) Authorize(p Permission) error {
+	if r.Can(p) {
+		return nil
+	}
+	return fmt.Errorf("%w: requires %s", ErrForbidden, p)
+}
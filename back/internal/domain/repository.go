@@ -1,16 +1,52 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // UserRepository defines methods for user data access
 type UserRepository interface {
 	Create(user *User) error
 	GetByID(id uuid.UUID) (*User, error)
 	GetByEmail(email string) (*User, error)
+	// Update сохраняет профиль. Роль и блокировку не трогает: их меняют только UpdateRole
+	// и UpdateBan, чтобы параллельное сохранение профиля не откатило решение модератора.
 	Update(user *User) error
+	UpdateRole(id uuid.UUID, role UserRole) error
+	UpdateBan(id uuid.UUID, ban UserBan) error
 	Delete(id uuid.UUID) error
-	List(limit, offset int) ([]*User, error)
-	GetByRefreshToken(refreshToken string) (*User, error)
+	// List возвращает страницу пользователей от старых к новым (created_at, id)
+	List(filter UserFilter, page PageQuery) ([]*User, error)
+	Count(filter UserFilter) (int64, error)
+}
+
+// AccountTokenRepository defines methods for one-time email token data access
+type AccountTokenRepository interface {
+	Create(token *AccountToken) error
+	GetByHash(tokenHash string) (*AccountToken, error)
+	// Use помечает токен использованным, только если он еще не использован.
+	// Иначе возвращает ErrInvalidAccountToken: токен уже погасил параллельный запрос.
+	Use(id uuid.UUID, at time.Time) error
+	// InvalidateByUserID гасит все неиспользованные токены пользователя с этим назначением
+	InvalidateByUserID(userID uuid.UUID, purpose TokenPurpose, at time.Time) error
+}
+
+// SessionRepository defines methods for session data access
+type SessionRepository interface {
+	Create(session *Session) error
+	GetByID(id uuid.UUID) (*Session, error)
+	// Rotate сохраняет новый хэш токена, время и адрес использования, только если в базе
+	// у сессии все еще хэш previousHash и она не отозвана. Иначе возвращает ErrRefreshTokenReused:
+	// токен уже обменяли параллельным запросом.
+	Rotate(session *Session, previousHash string) error
+	// Revoke и RevokeByUserID отзывают сессии; уже отозванные не меняются
+	Revoke(id uuid.UUID, at time.Time) error
+	RevokeByUserID(userID uuid.UUID, at time.Time) error
+	// ListActiveByUserID возвращает неотозванные и неистекшие на момент at сессии,
+	// недавно использованные - первыми
+	ListActiveByUserID(userID uuid.UUID, at time.Time) ([]*Session, error)
 }
 
 // BookRepository defines methods for book data access
@@ -18,24 +54,61 @@ type BookRepository interface {
 	Create(book *Book) error
 	GetByID(id uuid.UUID) (*Book, error)
 	Update(book *Book) error
+	// UpdateIfStatus сохраняет книгу, только если в базе у нее все еще статус expected.
+	// Если статус успели изменить параллельно, возвращает ErrBookConflict.
+	UpdateIfStatus(book *Book, expected BookStatus) error
 	Delete(bookID uuid.UUID) error
-	List(limit, offset int) ([]*Book, error)
-	GetSummaryList(limit, offset int) ([]*BookSummary, error)
-	Search(query string, limit, offset int) ([]*Book, error)
+	// List и GetSummaryList возвращают страницу книг от новых к старым (created_at, id)
+	List(page PageQuery) ([]*Book, error)
+	GetSummaryList(page PageQuery) ([]*BookSummary, error)
+	Count() (int64, error)
+	// Search ищет книги по названию, автору и описанию (с учетом словоформ и опечаток),
+	// самые релевантные - первыми. Удаленные книги не возвращаются. Второе значение - общее число найденных.
+	Search(query string, limit, offset int) ([]*BookSearchResult, int64, error)
+	// Filter возвращает страницу каталога по фильтрам и сортировке BookFilter.
+	// Удаленные книги не возвращаются.
+	Filter(filter BookFilter) ([]*BookListItem, error)
+	CountFiltered(filter BookFilter) (int64, error)
+	// Facets считает книги по значениям каждого фильтра; см. BookFacets
+	Facets(filter BookFilter) (*BookFacets, error)
 	GetByStatus(status BookStatus, limit, offset int) ([]*Book, error)
 	GetByLocationID(locationID uuid.UUID) ([]*Book, error)
+	// GetByWorkID возвращает экземпляры произведения, кроме удаленных, от новых к старым
+	GetByWorkID(workID uuid.UUID) ([]*Book, error)
+	// GetByOwnerID возвращает книги владельца, кроме удаленных, от новых к старым
+	GetByOwnerID(ownerID uuid.UUID) ([]*Book, error)
+}
+
+// WorkRepository defines methods for catalog work data access
+type WorkRepository interface {
+	Create(work *Work) error
+	GetByID(id uuid.UUID) (*Work, error)
+	GetByKey(key string) (*Work, error)
+	CountCopies(workID uuid.UUID) (*WorkCopies, error)
+	// AvailableLocations возвращает пункты со свободными экземплярами: больше экземпляров - выше
+	AvailableLocations(workID uuid.UUID) ([]*WorkLocation, error)
 }
 
 // ExchangeRepository defines methods for exchange data access
 type ExchangeRepository interface {
 	Create(exchange *Exchange) error
 	GetByID(id uuid.UUID) (*Exchange, error)
-	GetByUserID(userID uuid.UUID) ([]*Exchange, error)
 	GetByBookID(bookID uuid.UUID) ([]*Exchange, error)
+	// ListByUserID и ListByBookID возвращают страницу бронирований от новых к старым (booked_at, id)
+	ListByUserID(userID uuid.UUID, page PageQuery) ([]*Exchange, error)
+	ListByBookID(bookID uuid.UUID, page PageQuery) ([]*Exchange, error)
+	CountByUserID(userID uuid.UUID) (int64, error)
+	CountByBookID(bookID uuid.UUID) (int64, error)
 	Update(exchange *Exchange) error
+	// UpdateIfStatus сохраняет бронь, только если в базе у нее все еще статус expected.
+	// Иначе возвращает ErrExchangeConflict: бронь успели изменить параллельно (выдача, возврат, фоновая задача).
+	UpdateIfStatus(exchange *Exchange, expected ExchangeStatus) error
 	Delete(id uuid.UUID) error
-	List(limit, offset int) ([]*Exchange, error)
+	List(page PageQuery) ([]*Exchange, error)
 	GetExpired() ([]*Exchange, error)
+	// GetOverdue возвращает выданные книги, срок возврата которых уже прошел
+	GetOverdue() ([]*Exchange, error)
+	GetByStatus(status ExchangeStatus) ([]*Exchange, error)
 }
 
 // LocationRepository defines methods for location data access
@@ -53,11 +126,19 @@ type ReviewRepository interface {
 	Create(review *Review) error
 	GetByID(id uuid.UUID) (*Review, error)
 	GetByBookID(bookID uuid.UUID) ([]Review, error)
+	// GetByWorkID возвращает отзывы на все экземпляры произведения от новых к старым
+	GetByWorkID(workID uuid.UUID) ([]Review, error)
 	GetByUserID(userID uuid.UUID) ([]Review, error)
 	Update(review *Review) error
 	Delete(id uuid.UUID) error
 }
 
+// UploadRepository defines methods for uploaded image data access
+type UploadRepository interface {
+	Create(upload *Upload) error
+	GetByID(id uuid.UUID) (*Upload, error)
+}
+
 // BookMovementHistoryRepository defines methods for book movement history data access
 type BookMovementHistoryRepository interface {
 	Create(movement *BookMovementHistory) error
@@ -66,5 +147,20 @@ type BookMovementHistoryRepository interface {
 	GetByBookID(bookID uuid.UUID) ([]*BookMovementHistory, error)
 	GetByExchangeID(exchangeID uuid.UUID) ([]*BookMovementHistory, error)
 	GetByUserID(userID uuid.UUID) ([]*BookMovementHistory, error)
-	List(limit, offset int) ([]*BookMovementHistory, error)
+	// ListByBookID, ListByUserID и List возвращают страницу истории от новых записей к старым (created_at, id)
+	ListByBookID(bookID uuid.UUID, page PageQuery) ([]*BookMovementHistory, error)
+	ListByUserID(userID uuid.UUID, page PageQuery) ([]*BookMovementHistory, error)
+	List(page PageQuery) ([]*BookMovementHistory, error)
+	CountByBookID(bookID uuid.UUID) (int64, error)
+	CountByUserID(userID uuid.UUID) (int64, error)
+}
+
+// WaitlistRepository defines methods for book waitlist data access
+type WaitlistRepository interface {
+	Create(entry *WaitlistEntry) error
+	Delete(id uuid.UUID) error
+	// GetByBookID возвращает очередь на книгу в порядке FIFO
+	GetByBookID(bookID uuid.UUID) ([]*WaitlistEntry, error)
+	GetByUserID(userID uuid.UUID) ([]*WaitlistEntry, error)
+	GetByBookAndUser(bookID, userID uuid.UUID) (*WaitlistEntry, error)
 }
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session - вход пользователя с одного устройства. Refresh-токен сессии меняется при каждом
+// обновлении (ротация); в базе хранится только хэш текущего токена. Если клиент предъявит
+// один из прежних токенов сессии, его, вероятно, украли - сессия отзывается целиком.
+type Session struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"-"`
+	TokenHash  string     `gorm:"type:varchar(64);not null" json:"-"` // SHA-256 текущего refresh-токена
+	Device     string     `gorm:"type:varchar(200)" json:"device"`    // Название устройства от клиента или User-Agent
+	IP         string     `gorm:"type:varchar(45)" json:"ip"`         // Адрес последнего использования
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt time.Time  `gorm:"not null" json:"last_used_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`                // Выход из сессии или обнаруженный повтор токена
+	Current    bool       `gorm:"-" json:"current"` // Сессия, которой выдан токен текущего запроса
+}
+
+// Active сообщает, можно ли еще обновлять токены сессии
+func (s *Session) Active(at time.Time) bool {
+	return s.RevokedAt == nil && at.Before(s.ExpiresAt)
+}
+
+// ClientInfo - откуда пришел запрос на вход или обновление токенов
+type ClientInfo struct {
+	Device string
+	IP     string
+}
@@ -0,0 +1,23 @@
+package domain
+
+// Repositories набор репозиториев, работающих в рамках одной транзакции
+type Repositories struct {
+	Users     UserRepository
+	Sessions  SessionRepository
+	Tokens    AccountTokenRepository
+	Books     BookRepository
+	Works     WorkRepository
+	Exchanges ExchangeRepository
+	Locations LocationRepository
+	Reviews   ReviewRepository
+	Movements BookMovementHistoryRepository
+	Waitlist  WaitlistRepository
+	Uploads   UploadRepository
+}
+
+// UnitOfWork выполняет несколько операций с репозиториями атомарно.
+// Все изменения, сделанные через переданные в fn репозитории, фиксируются вместе;
+// если fn вернула ошибку, транзакция откатывается целиком.
+type UnitOfWork interface {
+	Do(fn func(repos *Repositories) error) error
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Upload - загруженная картинка (обложка книги). Исходный файл не хранится:
+// в BlobStore лежат его варианты - миниатюра и полноразмерная картинка в JPEG.
+type Upload struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	OwnerID     uuid.UUID `gorm:"type:uuid;not null;index" json:"owner_id"`      // Кто загрузил
+	ContentType string    `gorm:"type:varchar(50);not null" json:"content_type"` // Тип исходного файла
+	Size        int64     `gorm:"not null" json:"size"`                          // Размер исходного файла, байт
+	Width       int       `gorm:"not null" json:"width"`                         // Ширина полноразмерной картинки
+	Height      int       `gorm:"not null" json:"height"`                        // Высота полноразмерной картинки
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	URL         string    `gorm:"-" json:"url"`           // Полноразмерная картинка
+	ThumbURL    string    `gorm:"-" json:"thumbnail_url"` // Миниатюра
+}
+
+// FillURLs заполняет адреса вариантов картинки
+func (u *Upload) FillURLs() {
+	u.URL = ImageURL(u.ID, ImageFull)
+	u.ThumbURL = ImageURL(u.ID, ImageThumb)
+}
+
+// ImageVariant - вариант загруженной картинки
+type ImageVariant string
+
+const (
+	ImageThumb ImageVariant = "thumb"
+	ImageFull  ImageVariant = "full"
+)
+
+// Valid сообщает, есть ли такой вариант
+func (v ImageVariant) Valid() bool {
+	return v == ImageThumb || v == ImageFull
+}
+
+// ImageURL - адрес, по которому API отдает вариант картинки
+func ImageURL(uploadID uuid.UUID, variant ImageVariant) string {
+	return "/api/v1/uploads/" + uploadID.String() + "/" + string(variant)
+}
+
+// BlobKey - ключ варианта картинки в BlobStore
+func BlobKey(uploadID uuid.UUID, variant ImageVariant) string {
+	return "uploads/" + uploadID.String() + "/" + string(variant) + ".jpg"
+}
+
+// BlobInfo - метаданные файла в BlobStore
+type BlobInfo struct {
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// BlobStore хранит файлы по ключу вида "dir/name.ext". Первая реализация - локальная
+// файловая система; S3-совместимое хранилище должно проходить тот же набор тестов.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get возвращает ErrBlobNotFound, если файла нет; вызывающий закрывает ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	// Delete не считает ошибкой отсутствие файла
+	Delete(ctx context.Context, key string) error
+}
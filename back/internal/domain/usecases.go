@@ -1,62 +1,131 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // UserUseCase интерфейс для работы с пользователями
 type UserUseCase interface {
-	RegisterUser(email, password, name string) (*TokenResponse, error)
-	LoginUser(email, password string) (*TokenResponse, error)
+	RegisterUser(email, password, name string, client ClientInfo) (*TokenResponse, error)
+	LoginUser(email, password string, client ClientInfo) (*TokenResponse, error)
 	GetUserByID(id string) (*User, error)
-	// UpdateUser(user *User) error
-	// DeleteUser(id string) error
-	// ListUsers(limit, offset int) ([]*User, error)
-	RefreshToken(refreshToken string) (*TokenResponse, error)
-	GetUserMovementHistory(userID string) ([]*BookMovementHistory, error)
+	RefreshToken(refreshToken string, client ClientInfo) (*TokenResponse, error)
+	GetUserMovementHistory(userID string, page PageRequest) (*Page[*BookMovementHistory], error)
+
+	// Сессии: у каждого входа свой refresh-токен
+	Logout(refreshToken string) error
+	LogoutAll(userID uuid.UUID) error
+	ListSessions(userID uuid.UUID, currentSessionID uuid.UUID) ([]*Session, error)
+
+	// Подтверждение почты и восстановление пароля по одноразовым токенам из писем
+	VerifyEmail(token string) error
+	ResendVerification(userID uuid.UUID) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+
+	// Управление своим аккаунтом
+	UpdateProfile(userID uuid.UUID, name, email string) (*User, error)
+	ChangePassword(userID uuid.UUID, oldPassword, newPassword string, client ClientInfo) (*TokenResponse, error)
+	DeleteAccount(userID uuid.UUID) error
+
+	// CheckAccess - проверка владельца токена на каждый запрос: аккаунт не удален и не заблокирован
+	CheckAccess(userID uuid.UUID) (*User, error)
+
+	// Админка: actorID - кто выполняет действие, право проверяется по его текущей роли в базе.
+	// ListUsers, GetUser и GetUserHistory требуют PermUserRead, ChangeRole - PermUserManageRoles,
+	// BanUser и UnbanUser - PermUserBan.
+	ListUsers(actorID uuid.UUID, filter UserFilter, page PageRequest) (*Page[*User], error)
+	GetUser(actorID, userID uuid.UUID) (*User, error)
+	GetUserHistory(actorID, userID uuid.UUID, page PageRequest) (*Page[*BookMovementHistory], error)
+	ChangeRole(actorID, userID uuid.UUID, role UserRole) (*User, error)
+	BanUser(actorID, userID uuid.UUID, reason string, until *time.Time) (*User, error)
+	UnbanUser(actorID, userID uuid.UUID) (*User, error)
 }
 
 // BookUseCase интерфейс для работы с книгами
 type BookUseCase interface {
 	CreateBook(book *Book) error
-	GetSummaryBooksList() ([]*BookSummary, error)
-	GetBooksList() ([]*Book, error)
+	GetSummaryBooksList(page PageRequest) (*Page[*BookSummary], error)
+	GetBooksList(page PageRequest) (*Page[*Book], error)
 	GetBookByID(bookID uuid.UUID) (*Book, error)
 	DeleteBook(bookID uuid.UUID, userID uuid.UUID) error
 	Request(bookID uuid.UUID, userID uuid.UUID) error
 	Borrow(bookID uuid.UUID, userID uuid.UUID) error
 	Return(updatedBook *Book, userID uuid.UUID) error
+	SearchBooks(query string, limit, offset int) (*BookSearchPage, error)
+	ListBooks(filter BookFilter, page PageRequest) (*BookListPage, error)
+	LookupISBN(isbn string) (*BookMetadata, error)
+
+	// Управление книгой владельцем
+	UpdateBook(updated *Book, userID uuid.UUID) (*Book, error)
+	ArchiveBook(bookID uuid.UUID, userID uuid.UUID) error
+	UnarchiveBook(bookID uuid.UUID, userID uuid.UUID) error
+	TransferBook(bookID uuid.UUID, userID uuid.UUID, newOwnerID uuid.UUID) error
 
 	// GetBookByID(id uuid.UUID) (*Book, error)
-	// UpdateBook(book *Book) error
 	// DeleteBook(id uuid.UUID) error
 	// ListBooks(limit, offset int) ([]*Book, error)
-	// SearchBooks(query string) ([]*Book, error)
 	// GetBooksByOwner(ownerID uuid.UUID) ([]*Book, error)
 	// GetBooksByLocation(locationID uuid.UUID) ([]*Book, error)
 	// GetAvailableBooks() ([]*Book, error)
 
 	// Методы для работы с историей перемещений
-	GetBookMovementHistory(bookID uuid.UUID) ([]*BookMovementHistory, error)
+	GetBookMovementHistory(bookID uuid.UUID, page PageRequest) (*Page[*BookMovementHistory], error)
 }
 
 // ExchangeUseCase интерфейс для работы с обменом книг
 type ExchangeUseCase interface {
-	// CreateExchangeRequest(userID, bookID uuid.UUID, locationID *uuid.UUID) (*Exchange, error)
-	// GetExchangeByID(id uuid.UUID) (*Exchange, error)
-	// GetExchangesByUser(userID uuid.UUID) ([]*Exchange, error)
-	// GetExchangesByBook(bookID uuid.UUID) ([]*Exchange, error)
-	// ApproveExchange(exchangeID uuid.UUID, dueDate *time.Time) error
-	// BorrowBook(exchangeID uuid.UUID) error
-	// ReturnBook(exchangeID uuid.UUID) error
-	// CancelExchange(exchangeID uuid.UUID) error
-	// ListExchanges(limit, offset int) ([]*Exchange, error)
+	GetExchangeByID(exchangeID, userID uuid.UUID) (*Exchange, error)
+	GetUserExchanges(userID uuid.UUID, page PageRequest) (*Page[*Exchange], error)
+	ListUserExchanges(actorID, userID uuid.UUID, page PageRequest) (*Page[*Exchange], error) // Требует PermExchangeReadAll
+	GetBookExchanges(bookID, userID uuid.UUID, page PageRequest) (*Page[*Exchange], error)
+	CancelExchange(exchangeID, userID uuid.UUID) error
+	ExtendExchange(exchangeID, userID uuid.UUID) (*Exchange, error)
+	CancelExpiredExchanges(ctx context.Context) error
+	MarkOverdueExchanges(ctx context.Context) error
+	GetOverdueExchanges(userID uuid.UUID) ([]*Exchange, error) // Требует PermExchangeReadAll
+}
+
+// ReviewUseCase интерфейс для работы с отзывами
+type ReviewUseCase interface {
+	CreateReview(bookID, userID uuid.UUID, rating int16, text string) (*Review, error)
+	UpdateReview(reviewID, userID uuid.UUID, rating int16, text string) (*Review, error)
+	DeleteReview(reviewID, userID uuid.UUID) error
+	GetBookReviews(bookID uuid.UUID) ([]Review, error)
+}
+
+// WorkUseCase интерфейс для работы с произведениями каталога
+type WorkUseCase interface {
+	GetWork(workID uuid.UUID) (*WorkDetails, error)
+	GetWorkBooks(workID uuid.UUID) ([]*Book, error)
+	GetWorkReviews(workID uuid.UUID) ([]Review, error)
+}
+
+// UploadUseCase интерфейс для загрузки картинок
+type UploadUseCase interface {
+	UploadImage(ctx context.Context, ownerID uuid.UUID, r io.Reader) (*Upload, error)
+	OpenImage(ctx context.Context, uploadID uuid.UUID, variant ImageVariant) (io.ReadCloser, *BlobInfo, error)
+}
+
+// WaitlistUseCase интерфейс для работы с очередью на книгу
+type WaitlistUseCase interface {
+	JoinWaitlist(bookID, userID uuid.UUID) (*WaitlistEntry, error)
+	LeaveWaitlist(bookID, userID uuid.UUID) error
+	// GetWaitlist возвращает публичный вид очереди: место и имя, без личных данных
+	GetWaitlist(bookID uuid.UUID) ([]*WaitlistPlace, error)
 }
 
 type LocationUseCase interface {
-	Create(location *Location) error
+	// Create, Update и Delete требуют права PermLocationWrite
+	Create(userID uuid.UUID, location *Location) error
 	GetByID(id uuid.UUID) (*Location, error)
 	GetAll() ([]Location, error)
-	Update(location *Location) error
-	Delete(id uuid.UUID) error
+	Update(userID uuid.UUID, location *Location) error
+	Delete(userID, id uuid.UUID) error
 }
 
 // TokenResponse структура ответа с токенами
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Work - произведение в каталоге (Произведение). Book - это один физический экземпляр;
+// экземпляры с одинаковыми названием и автором ссылаются на одно произведение,
+// поэтому отзывы, оценки и поиск считаются по произведению, а не по экземпляру.
+type Work struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Key         string    `gorm:"type:text;not null;uniqueIndex" json:"-"` // WorkKey(Title, Author)
+	Title       string    `gorm:"not null" json:"title"`
+	Author      string    `gorm:"not null" json:"author"`
+	Description string    `gorm:"type:text" json:"description"`
+	ISBN        string    `gorm:"type:varchar(13)" json:"isbn,omitempty"` // ISBN первого добавленного издания
+	CoverURL    string    `json:"cover_url"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// WorkKey - ключ произведения: название и автор без учета регистра, лишних пробелов и "ё".
+// Миграция 0007_works заполняет works.key тем же выражением.
+func WorkKey(title, author string) string {
+	return normalizeWorkField(author) + "|" + normalizeWorkField(title)
+}
+
+func normalizeWorkField(s string) string {
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	return strings.ReplaceAll(s, "ё", "е")
+}
+
+// NewWork создает произведение по первому экземпляру
+func NewWork(book *Book) *Work {
+	return &Work{
+		Key:         WorkKey(book.Title, book.Author),
+		Title:       book.Title,
+		Author:      book.Author,
+		Description: book.Description,
+		ISBN:        book.ISBN,
+		CoverURL:    book.ImageURL,
+	}
+}
+
+// WorkCopies - число экземпляров произведения (без удаленных)
+type WorkCopies struct {
+	Total     int64 `json:"copies"`
+	Available int64 `json:"available_copies"`
+}
+
+// WorkLocation - пункт выдачи, где есть свободные экземпляры произведения
+type WorkLocation struct {
+	LocationID uuid.UUID `json:"location_id"`
+	Name       string    `json:"name"`
+	Address    string    `json:"address"`
+	Available  int64     `json:"available"`
+}
+
+// WorkDetails - карточка произведения: "N экземпляров свободно в этих пунктах"
+type WorkDetails struct {
+	Work
+	WorkCopies
+	Locations     []*WorkLocation `json:"locations"`      // Больше свободных экземпляров - выше
+	AverageRating *float64        `json:"average_rating"` // По отзывам на все экземпляры; nil, если отзывов нет
+	ReviewsCount  int64           `json:"reviews_count"`
+}
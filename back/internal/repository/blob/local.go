@@ -0,0 +1,104 @@
+// Package blob implements domain.BlobStore. LocalStore keeps files on the local
+// filesystem; an S3-compatible store must pass the same contract.RunBlobStore suite.
+package blob
+
+import (
+	"bookvito/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore хранит файлы в каталоге root; ключ "a/b.jpg" - это файл root/a/b.jpg
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates the root directory if needed and returns a store over it
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create blob directory: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// Пишем во временный файл и переименовываем: читатель не увидит файл наполовину
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *domain.BlobInfo, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, domain.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	// Тип восстанавливается по расширению ключа
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return f, &domain.BlobInfo{ContentType: contentType, Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path переводит ключ в путь внутри root; ключи с ".." и абсолютные пути отклоняются
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || !fs.ValidPath(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
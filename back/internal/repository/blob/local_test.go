@@ -0,0 +1,17 @@
+package blob
+
+import (
+	"bookvito/internal/domain"
+	"bookvito/internal/repository/contract"
+	"testing"
+)
+
+func TestLocalStoreContract(t *testing.T) {
+	contract.RunBlobStore(t, func(t *testing.T) domain.BlobStore {
+		store, err := NewLocalStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("create store: %v", err)
+		}
+		return store
+	})
+}
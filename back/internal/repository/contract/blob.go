@@ -0,0 +1,65 @@
+package contract
+
+import (
+	"bookvito/internal/domain"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+// RunBlobStore проверяет поведение, общее для всех реализаций domain.BlobStore.
+// newStore вызывается для каждого подтеста и должен возвращать пустое хранилище.
+func RunBlobStore(t *testing.T, newStore func(t *testing.T) domain.BlobStore) {
+	ctx := context.Background()
+
+	t.Run("PutGet", func(t *testing.T) {
+		store := newStore(t)
+		mustNoErr(t, store.Put(ctx, "uploads/a/full.jpg", strings.NewReader("jpeg bytes"), "image/jpeg"))
+
+		data, info := readBlob(t, store, "uploads/a/full.jpg")
+		if data != "jpeg bytes" || info.ContentType != "image/jpeg" || info.Size != int64(len(data)) || info.ModTime.IsZero() {
+			t.Fatalf("unexpected blob %q %+v", data, info)
+		}
+
+		// Повторная запись заменяет файл целиком
+		mustNoErr(t, store.Put(ctx, "uploads/a/full.jpg", strings.NewReader("new"), "image/jpeg"))
+		if data, _ := readBlob(t, store, "uploads/a/full.jpg"); data != "new" {
+			t.Fatalf("Put must overwrite, got %q", data)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		store := newStore(t)
+		_, _, err := store.Get(ctx, "uploads/missing.jpg")
+		mustErrIs(t, err, domain.ErrBlobNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+		mustNoErr(t, store.Put(ctx, "uploads/b/thumb.jpg", strings.NewReader("x"), "image/jpeg"))
+		mustNoErr(t, store.Delete(ctx, "uploads/b/thumb.jpg"))
+		_, _, err := store.Get(ctx, "uploads/b/thumb.jpg")
+		mustErrIs(t, err, domain.ErrBlobNotFound)
+		mustNoErr(t, store.Delete(ctx, "uploads/b/thumb.jpg"))
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		store := newStore(t)
+		for _, key := range []string{"", "/etc/passwd", "../escape.jpg", "uploads/../../escape.jpg"} {
+			if err := store.Put(ctx, key, strings.NewReader("x"), "image/jpeg"); err == nil {
+				t.Fatalf("Put(%q) must fail", key)
+			}
+		}
+	})
+}
+
+func readBlob(t *testing.T, store domain.BlobStore, key string) (string, *domain.BlobInfo) {
+	t.Helper()
+	rc, info, err := store.Get(context.Background(), key)
+	mustNoErr(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	mustNoErr(t, err)
+	return string(data), info
+}
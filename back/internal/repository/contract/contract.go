@@ -0,0 +1,1164 @@
+// Package contract contains the behaviour every domain repository backend must share.
+// Backends run it from their own tests: contract.Run(t, newBackend).
+package contract
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Backend - набор репозиториев над одним хранилищем. NewBackend в Run вызывается
+// для каждого подтеста и должен возвращать пустое хранилище.
+type Backend struct {
+	Repos      *domain.Repositories
+	UnitOfWork domain.UnitOfWork
+}
+
+// base - точка отсчета для явных CreatedAt/BookedAt, чтобы порядок не зависел от скорости теста
+var base = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// Run runs the whole contract suite against the backend
+func Run(t *testing.T, newBackend func(t *testing.T) Backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b Backend)
+	}{
+		{"Users", testUsers},
+		{"UsersListOrder", testUsersListOrder},
+		{"UsersModeration", testUsersModeration},
+		{"Sessions", testSessions},
+		{"AccountTokens", testAccountTokens},
+		{"Books", testBooks},
+		{"BooksListOrder", testBooksListOrder},
+		{"BooksFilters", testBooksFilters},
+		{"BooksCatalog", testBooksCatalog},
+		{"Works", testWorks},
+		{"Exchanges", testExchanges},
+		{"ExchangesQueries", testExchangesQueries},
+		{"Locations", testLocations},
+		{"Reviews", testReviews},
+		{"Movements", testMovements},
+		{"Waitlist", testWaitlist},
+		{"Uploads", testUploads},
+		{"UnitOfWork", testUnitOfWork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newBackend(t))
+		})
+	}
+}
+
+func testUsers(t *testing.T, b Backend) {
+	users := b.Repos.Users
+	user := createUser(t, b, "reader@example.com", base)
+	if user.ID == uuid.Nil {
+		t.Fatal("Create must assign an ID")
+	}
+
+	got, err := users.GetByID(user.ID)
+	mustNoErr(t, err)
+	if got.Email != user.Email || got.Role != domain.RoleUser {
+		t.Fatalf("unexpected user %+v", got)
+	}
+	got, err = users.GetByEmail("reader@example.com")
+	mustNoErr(t, err)
+	if got.ID != user.ID {
+		t.Fatalf("GetByEmail returned %s, want %s", got.ID, user.ID)
+	}
+	_, err = users.GetByEmail("nobody@example.com")
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+
+	duplicate := &domain.User{Email: "reader@example.com", Password: "hash", Name: "Другой"}
+	mustErrIs(t, users.Create(duplicate), gorm.ErrDuplicatedKey)
+
+	got.Name = "Новое имя"
+	mustNoErr(t, users.Update(got))
+	got, err = users.GetByID(user.ID)
+	mustNoErr(t, err)
+	if got.ID != user.ID || got.Name != "Новое имя" {
+		t.Fatalf("unexpected user after update %+v", got)
+	}
+	if got.DeletedAt != nil || got.EmailVerifiedAt != nil {
+		t.Fatalf("new user must be neither deleted nor verified: %+v", got)
+	}
+	verifiedAt := base.Add(time.Hour)
+	got.EmailVerifiedAt = &verifiedAt
+	mustNoErr(t, users.Update(got))
+	got, err = users.GetByID(user.ID)
+	mustNoErr(t, err)
+	if got.EmailVerifiedAt == nil || !got.EmailVerifiedAt.Equal(verifiedAt) {
+		t.Fatalf("expected email_verified_at %v, got %v", verifiedAt, got.EmailVerifiedAt)
+	}
+
+	deletedAt := base.Add(2 * time.Hour)
+	got.DeletedAt = &deletedAt
+	mustNoErr(t, users.Update(got))
+	got, err = users.GetByID(user.ID)
+	mustNoErr(t, err)
+	if got.DeletedAt == nil || !got.DeletedAt.Equal(deletedAt) {
+		t.Fatalf("expected deleted_at %v, got %v", deletedAt, got.DeletedAt)
+	}
+
+	mustNoErr(t, users.Delete(user.ID))
+	_, err = users.GetByID(user.ID)
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testUsersListOrder(t *testing.T, b Backend) {
+	// Пользователи - от старых к новым
+	third := createUser(t, b, "c@example.com", base.Add(2*time.Hour))
+	first := createUser(t, b, "a@example.com", base)
+	second := createUser(t, b, "b@example.com", base.Add(time.Hour))
+
+	all, err := b.Repos.Users.List(domain.UserFilter{}, domain.PageQuery{Limit: -1})
+	mustNoErr(t, err)
+	expectIDs(t, "List", userIDs(all), first.ID, second.ID, third.ID)
+
+	page, err := b.Repos.Users.List(domain.UserFilter{}, domain.PageQuery{After: timeCursor(first.CreatedAt, first.ID), Limit: 1})
+	mustNoErr(t, err)
+	expectIDs(t, "List after first", userIDs(page), second.ID)
+
+	page, err = b.Repos.Users.List(domain.UserFilter{}, domain.PageQuery{After: timeCursor(third.CreatedAt, third.ID), Limit: 10})
+	mustNoErr(t, err)
+	expectIDs(t, "List after last", userIDs(page))
+
+	// Записи с одинаковым временем различаются по id: курсор не теряет и не повторяет их
+	twin := createUser(t, b, "twin@example.com", second.CreatedAt)
+	lo, hi := idOrder(second.ID, twin.ID)
+	page, err = b.Repos.Users.List(domain.UserFilter{}, domain.PageQuery{After: timeCursor(second.CreatedAt, lo), Limit: 10})
+	mustNoErr(t, err)
+	expectIDs(t, "List after equal time", userIDs(page), hi, third.ID)
+
+	count, err := b.Repos.Users.Count(domain.UserFilter{})
+	mustNoErr(t, err)
+	if count != 4 {
+		t.Fatalf("expected 4 users, got %d", count)
+	}
+}
+
+func testUsersModeration(t *testing.T, b Backend) {
+	users := b.Repos.Users
+	moder := createUser(t, b, "moder@example.com", base)
+	reader := createUser(t, b, "Reader_1@example.com", base.Add(time.Hour))
+	other := createUser(t, b, "other@example.com", base.Add(2*time.Hour))
+	other.Name = "Анна Читающая"
+	mustNoErr(t, users.Update(other))
+
+	mustNoErr(t, users.UpdateRole(moder.ID, domain.RoleModer))
+	mustErrIs(t, users.UpdateRole(uuid.New(), domain.RoleModer), gorm.ErrRecordNotFound)
+
+	until := base.Add(48 * time.Hour)
+	bannedAt := base.Add(3 * time.Hour)
+	ban := domain.UserBan{BannedAt: &bannedAt, BannedUntil: &until, BanReason: "спам", BannedBy: &moder.ID}
+	mustNoErr(t, users.UpdateBan(reader.ID, ban))
+	mustErrIs(t, users.UpdateBan(uuid.New(), ban), gorm.ErrRecordNotFound)
+
+	got, err := users.GetByID(reader.ID)
+	mustNoErr(t, err)
+	if got.BannedAt == nil || !got.BannedAt.Equal(bannedAt) || got.BannedUntil == nil || !got.BannedUntil.Equal(until) ||
+		got.BanReason != "спам" || got.BannedBy == nil || *got.BannedBy != moder.ID {
+		t.Fatalf("unexpected ban %+v", got.UserBan)
+	}
+	if !got.Banned(base.Add(24*time.Hour)) || got.Banned(until) {
+		t.Fatalf("ban must last until %v: %+v", until, got.UserBan)
+	}
+
+	// Сохранение профиля со старой копией не откатывает роль и блокировку
+	reader.Name = "Читатель"
+	reader.Role = domain.RoleAdmin
+	mustNoErr(t, users.Update(reader))
+	got, err = users.GetByID(reader.ID)
+	mustNoErr(t, err)
+	if got.Name != "Читатель" || got.Role != domain.RoleUser || got.BannedAt == nil {
+		t.Fatalf("Update must keep role and ban: %+v", got)
+	}
+	stale := *moder
+	mustNoErr(t, users.Update(&stale))
+	got, err = users.GetByID(moder.ID)
+	mustNoErr(t, err)
+	if got.Role != domain.RoleModer {
+		t.Fatalf("Update must keep role, got %s", got.Role)
+	}
+
+	mustNoErr(t, users.UpdateBan(reader.ID, domain.UserBan{}))
+	got, err = users.GetByID(reader.ID)
+	mustNoErr(t, err)
+	if got.UserBan != (domain.UserBan{}) {
+		t.Fatalf("expected ban to be lifted, got %+v", got.UserBan)
+	}
+
+	// Поиск по подстроке почты или имени без учета регистра; % и _ ищутся буквально
+	tests := []struct {
+		name   string
+		filter domain.UserFilter
+		want   []uuid.UUID
+	}{
+		{"all", domain.UserFilter{}, []uuid.UUID{moder.ID, reader.ID, other.ID}},
+		{"email", domain.UserFilter{Query: "READER"}, []uuid.UUID{reader.ID}},
+		{"name", domain.UserFilter{Query: "читающая"}, []uuid.UUID{other.ID}},
+		{"underscore", domain.UserFilter{Query: "r_1"}, []uuid.UUID{reader.ID}},
+		{"wildcard", domain.UserFilter{Query: "r%1"}, nil},
+		{"role", domain.UserFilter{Role: domain.RoleModer}, []uuid.UUID{moder.ID}},
+		{"query and role", domain.UserFilter{Query: "example", Role: domain.RoleUser}, []uuid.UUID{reader.ID, other.ID}},
+	}
+	for _, tt := range tests {
+		list, err := users.List(tt.filter, domain.PageQuery{Limit: -1})
+		mustNoErr(t, err)
+		expectIDs(t, "List "+tt.name, userIDs(list), tt.want...)
+		count, err := users.Count(tt.filter)
+		mustNoErr(t, err)
+		if count != int64(len(tt.want)) {
+			t.Fatalf("Count %s: expected %d, got %d", tt.name, len(tt.want), count)
+		}
+	}
+	page, err := users.List(domain.UserFilter{Query: "example"}, domain.PageQuery{After: timeCursor(moder.CreatedAt, moder.ID), Limit: 1})
+	mustNoErr(t, err)
+	expectIDs(t, "List filtered page", userIDs(page), reader.ID)
+}
+
+func testSessions(t *testing.T, b Backend) {
+	sessions := b.Repos.Sessions
+	user := createUser(t, b, "reader@example.com", base)
+	other := createUser(t, b, "other@example.com", base)
+
+	phone := &domain.Session{UserID: user.ID, TokenHash: "hash-1", Device: "phone", IP: "10.0.0.1", LastUsedAt: base, ExpiresAt: base.Add(48 * time.Hour)}
+	mustNoErr(t, sessions.Create(phone))
+	if phone.ID == uuid.Nil || phone.CreatedAt.IsZero() {
+		t.Fatalf("Create must assign an ID and created_at: %+v", phone)
+	}
+	tablet := &domain.Session{UserID: user.ID, TokenHash: "hash-2", Device: "tablet", LastUsedAt: base.Add(time.Hour), ExpiresAt: base.Add(48 * time.Hour)}
+	mustNoErr(t, sessions.Create(tablet))
+	expired := &domain.Session{UserID: user.ID, TokenHash: "hash-3", LastUsedAt: base, ExpiresAt: base.Add(time.Minute)}
+	mustNoErr(t, sessions.Create(expired))
+	foreign := &domain.Session{UserID: other.ID, TokenHash: "hash-4", LastUsedAt: base, ExpiresAt: base.Add(48 * time.Hour)}
+	mustNoErr(t, sessions.Create(foreign))
+
+	got, err := sessions.GetByID(phone.ID)
+	mustNoErr(t, err)
+	if got.UserID != user.ID || got.TokenHash != "hash-1" || got.Device != "phone" || got.IP != "10.0.0.1" || got.RevokedAt != nil {
+		t.Fatalf("unexpected session %+v", got)
+	}
+	_, err = sessions.GetByID(uuid.New())
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+
+	// Активные сессии - недавно использованные первыми, без истекших и чужих
+	active, err := sessions.ListActiveByUserID(user.ID, base.Add(time.Hour))
+	mustNoErr(t, err)
+	expectIDs(t, "ListActiveByUserID", sessionIDs(active), tablet.ID, phone.ID)
+
+	// Ротация проходит только с текущим хэшем
+	got.TokenHash = "hash-1b"
+	got.IP = "10.0.0.2"
+	got.LastUsedAt = base.Add(2 * time.Hour)
+	got.ExpiresAt = base.Add(72 * time.Hour)
+	mustNoErr(t, sessions.Rotate(got, "hash-1"))
+	mustErrIs(t, sessions.Rotate(got, "hash-1"), domain.ErrRefreshTokenReused)
+	got, err = sessions.GetByID(phone.ID)
+	mustNoErr(t, err)
+	if got.TokenHash != "hash-1b" || got.IP != "10.0.0.2" || !got.LastUsedAt.Equal(base.Add(2*time.Hour)) || !got.ExpiresAt.Equal(base.Add(72*time.Hour)) {
+		t.Fatalf("unexpected session after rotate %+v", got)
+	}
+	active, err = sessions.ListActiveByUserID(user.ID, base.Add(time.Hour))
+	mustNoErr(t, err)
+	expectIDs(t, "ListActiveByUserID after rotate", sessionIDs(active), phone.ID, tablet.ID)
+
+	// Отозванную сессию нельзя ротировать, повторный отзыв не меняет время
+	revokedAt := base.Add(3 * time.Hour)
+	mustNoErr(t, sessions.Revoke(phone.ID, revokedAt))
+	mustNoErr(t, sessions.Revoke(phone.ID, revokedAt.Add(time.Hour)))
+	got, err = sessions.GetByID(phone.ID)
+	mustNoErr(t, err)
+	if got.RevokedAt == nil || !got.RevokedAt.Equal(revokedAt) {
+		t.Fatalf("expected revoked_at %v, got %v", revokedAt, got.RevokedAt)
+	}
+	mustErrIs(t, sessions.Rotate(got, "hash-1b"), domain.ErrRefreshTokenReused)
+
+	mustNoErr(t, sessions.RevokeByUserID(user.ID, revokedAt))
+	active, err = sessions.ListActiveByUserID(user.ID, base)
+	mustNoErr(t, err)
+	expectIDs(t, "ListActiveByUserID after RevokeByUserID", sessionIDs(active))
+	active, err = sessions.ListActiveByUserID(other.ID, base)
+	mustNoErr(t, err)
+	expectIDs(t, "ListActiveByUserID of another user", sessionIDs(active), foreign.ID)
+}
+
+func testAccountTokens(t *testing.T, b Backend) {
+	tokens := b.Repos.Tokens
+	user := createUser(t, b, "reader@example.com", base)
+
+	verify := &domain.AccountToken{UserID: user.ID, Purpose: domain.TokenVerifyEmail, TokenHash: "hash-1", Email: user.Email, ExpiresAt: base.Add(time.Hour)}
+	mustNoErr(t, tokens.Create(verify))
+	if verify.ID == uuid.Nil || verify.CreatedAt.IsZero() {
+		t.Fatalf("Create must assign an ID and created_at: %+v", verify)
+	}
+	duplicate := &domain.AccountToken{UserID: user.ID, Purpose: domain.TokenResetPassword, TokenHash: "hash-1", Email: user.Email, ExpiresAt: base}
+	mustErrIs(t, tokens.Create(duplicate), gorm.ErrDuplicatedKey)
+
+	got, err := tokens.GetByHash("hash-1")
+	mustNoErr(t, err)
+	if got.ID != verify.ID || got.UserID != user.ID || got.Purpose != domain.TokenVerifyEmail || got.Email != user.Email ||
+		!got.ExpiresAt.Equal(verify.ExpiresAt) || got.UsedAt != nil {
+		t.Fatalf("unexpected token %+v", got)
+	}
+	_, err = tokens.GetByHash("unknown")
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+
+	// Токен гасится один раз
+	usedAt := base.Add(time.Minute)
+	mustNoErr(t, tokens.Use(verify.ID, usedAt))
+	mustErrIs(t, tokens.Use(verify.ID, usedAt), domain.ErrInvalidAccountToken)
+	got, err = tokens.GetByHash("hash-1")
+	mustNoErr(t, err)
+	if got.UsedAt == nil || !got.UsedAt.Equal(usedAt) {
+		t.Fatalf("expected used_at %v, got %v", usedAt, got.UsedAt)
+	}
+
+	// InvalidateByUserID не трогает токены с другим назначением
+	reset := &domain.AccountToken{UserID: user.ID, Purpose: domain.TokenResetPassword, TokenHash: "hash-2", Email: user.Email, ExpiresAt: base.Add(time.Hour)}
+	mustNoErr(t, tokens.Create(reset))
+	next := &domain.AccountToken{UserID: user.ID, Purpose: domain.TokenVerifyEmail, TokenHash: "hash-3", Email: user.Email, ExpiresAt: base.Add(time.Hour)}
+	mustNoErr(t, tokens.Create(next))
+	mustNoErr(t, tokens.InvalidateByUserID(user.ID, domain.TokenVerifyEmail, base.Add(2*time.Minute)))
+	got, err = tokens.GetByHash("hash-3")
+	mustNoErr(t, err)
+	if got.UsedAt == nil {
+		t.Fatal("InvalidateByUserID must invalidate unused tokens with the purpose")
+	}
+	got, err = tokens.GetByHash("hash-2")
+	mustNoErr(t, err)
+	if got.UsedAt != nil {
+		t.Fatalf("InvalidateByUserID must keep tokens with another purpose, got %v", got.UsedAt)
+	}
+	got, err = tokens.GetByHash("hash-1")
+	mustNoErr(t, err)
+	if !got.UsedAt.Equal(usedAt) {
+		t.Fatalf("InvalidateByUserID must not change used tokens, got %v", got.UsedAt)
+	}
+}
+
+func testBooks(t *testing.T, b Backend) {
+	books := b.Repos.Books
+	owner := createUser(t, b, "owner@example.com", base)
+	location := createLocation(t, b, "library", "Ленина, 1")
+
+	book := &domain.Book{OwnerID: owner.ID, Title: "Dune", Author: "Herbert", ISBN: "9780441172719", CurrentLocationID: &location.ID}
+	mustNoErr(t, books.Create(book))
+	if book.ID == uuid.Nil {
+		t.Fatal("Create must assign an ID")
+	}
+
+	got, err := books.GetByID(book.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.BookAvailable || got.Condition != domain.ConditionGood {
+		t.Fatalf("expected defaults available/good, got %s/%s", got.Status, got.Condition)
+	}
+	if got.ISBN != "9780441172719" {
+		t.Fatalf("GetByID must return the ISBN, got %q", got.ISBN)
+	}
+	if got.CurrentLocation == nil || got.CurrentLocation.ID != location.ID {
+		t.Fatalf("GetByID must preload CurrentLocation, got %+v", got.CurrentLocation)
+	}
+
+	// Update не должен перезаписывать внешний ключ подгруженной связью
+	got.Title = "Dune Messiah"
+	got.CurrentLocationID = nil
+	mustNoErr(t, books.Update(got))
+	got, err = books.GetByID(book.ID)
+	mustNoErr(t, err)
+	if got.Title != "Dune Messiah" || got.CurrentLocationID != nil {
+		t.Fatalf("unexpected book after update: title %q, location %v", got.Title, got.CurrentLocationID)
+	}
+
+	got.Status = domain.BookRequested
+	mustNoErr(t, books.UpdateIfStatus(got, domain.BookAvailable))
+	got.Status = domain.BookBorrowed
+	mustErrIs(t, books.UpdateIfStatus(got, domain.BookAvailable), domain.ErrBookConflict)
+	got, err = books.GetByID(book.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.BookRequested {
+		t.Fatalf("conflicting update must not be saved, status %s", got.Status)
+	}
+
+	// Параллельные запросы одной свободной книги: условное обновление выигрывает ровно одно
+	got.Status = domain.BookAvailable
+	mustNoErr(t, books.UpdateIfStatus(got, domain.BookRequested))
+	const racers = 8
+	start := make(chan struct{})
+	errs := make(chan error, racers)
+	var wg sync.WaitGroup
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			racer := *got
+			racer.Status = domain.BookRequested
+			<-start
+			errs <- books.UpdateIfStatus(&racer, domain.BookAvailable)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	var wins int
+	for err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case !errors.Is(err, domain.ErrBookConflict):
+			t.Fatalf("concurrent UpdateIfStatus: unexpected error %v", err)
+		}
+	}
+	if wins != 1 {
+		t.Fatalf("exactly one concurrent UpdateIfStatus must win, got %d", wins)
+	}
+
+	mustNoErr(t, books.Delete(book.ID))
+	_, err = books.GetByID(book.ID)
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+	mustErrIs(t, books.Delete(book.ID), gorm.ErrRecordNotFound)
+}
+
+func testBooksListOrder(t *testing.T, b Backend) {
+	// Книги - от новых к старым
+	owner := createUser(t, b, "owner@example.com", base)
+	oldest := createBook(t, b, owner, "Oldest", base)
+	newest := createBook(t, b, owner, "Newest", base.Add(2*time.Hour))
+	middle := createBook(t, b, owner, "Middle", base.Add(time.Hour))
+
+	all, err := b.Repos.Books.List(domain.PageQuery{Limit: -1})
+	mustNoErr(t, err)
+	expectIDs(t, "List", bookIDs(all), newest.ID, middle.ID, oldest.ID)
+	if all[0].Title != "Newest" {
+		t.Fatalf("List must return card fields, got %+v", all[0])
+	}
+
+	if !all[0].CreatedAt.Equal(newest.CreatedAt) {
+		t.Fatalf("List must return created_at for the cursor, got %s", all[0].CreatedAt)
+	}
+
+	page, err := b.Repos.Books.List(domain.PageQuery{After: timeCursor(newest.CreatedAt, newest.ID), Limit: 2})
+	mustNoErr(t, err)
+	expectIDs(t, "List after newest", bookIDs(page), middle.ID, oldest.ID)
+
+	summaries, err := b.Repos.Books.GetSummaryList(domain.PageQuery{Limit: 2})
+	mustNoErr(t, err)
+	var summaryIDs []uuid.UUID
+	for _, s := range summaries {
+		summaryIDs = append(summaryIDs, s.ID)
+	}
+	expectIDs(t, "GetSummaryList", summaryIDs, newest.ID, middle.ID)
+	summaries, err = b.Repos.Books.GetSummaryList(domain.PageQuery{After: timeCursor(middle.CreatedAt, middle.ID), Limit: 2})
+	mustNoErr(t, err)
+	if len(summaries) != 1 || summaries[0].ID != oldest.ID || !summaries[0].CreatedAt.Equal(oldest.CreatedAt) {
+		t.Fatalf("unexpected summary page after middle: %+v", summaries)
+	}
+
+	count, err := b.Repos.Books.Count()
+	mustNoErr(t, err)
+	if count != 3 {
+		t.Fatalf("expected 3 books, got %d", count)
+	}
+}
+
+func testBooksFilters(t *testing.T, b Backend) {
+	books := b.Repos.Books
+	owner := createUser(t, b, "owner@example.com", base)
+	location := createLocation(t, b, "library", "Ленина, 1")
+
+	dune := createBook(t, b, owner, "Dune", base)
+	dune.Description = "Desert planet"
+	dune.CurrentLocationID = &location.ID
+	mustNoErr(t, books.Update(dune))
+
+	solaris := createBook(t, b, owner, "Solaris", base.Add(time.Hour))
+	solaris.Status = domain.BookArchived
+	mustNoErr(t, books.Update(solaris))
+
+	found, total, err := books.Search("dUNE", -1, 0)
+	mustNoErr(t, err)
+	expectIDs(t, "Search by title", searchIDs(found), dune.ID)
+	if total != 1 || found[0].Headline != "<mark>Dune</mark>" {
+		t.Fatalf("unexpected search result: total %d, headline %q", total, found[0].Headline)
+	}
+	found, _, err = books.Search("planet", -1, 0)
+	mustNoErr(t, err)
+	expectIDs(t, "Search by description", searchIDs(found), dune.ID)
+
+	// Пагинация поиска: total считает все совпадения, а не только страницу
+	messiah := createBook(t, b, owner, "Dune Messiah", base.Add(2*time.Hour))
+	found, total, err = books.Search("dune", 1, 0)
+	mustNoErr(t, err)
+	if total != 2 || len(found) != 1 {
+		t.Fatalf("expected 1 of 2 results, got %d of %d", len(found), total)
+	}
+	next, _, err := books.Search("dune", 1, 1)
+	mustNoErr(t, err)
+	expectIDs(t, "Search pages", []uuid.UUID{found[0].ID, next[0].ID}, pageOrder(found[0].ID, dune.ID, messiah.ID)...)
+
+	// Удаленные книги в поиск не попадают
+	messiah.Status = domain.BookDeleted
+	mustNoErr(t, books.Update(messiah))
+	found, _, err = books.Search("dune", -1, 0)
+	mustNoErr(t, err)
+	expectIDs(t, "Search without deleted", searchIDs(found), dune.ID)
+
+	archived, err := books.GetByStatus(domain.BookArchived, -1, 0)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByStatus", bookIDs(archived), solaris.ID)
+
+	atLocation, err := books.GetByLocationID(location.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByLocationID", bookIDs(atLocation), dune.ID)
+
+	createBook(t, b, createUser(t, b, "other@example.com", base), "Stalker", base)
+	owned, err := books.GetByOwnerID(owner.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByOwnerID", bookIDs(owned), solaris.ID, dune.ID)
+}
+
+func testBooksCatalog(t *testing.T, b Backend) {
+	books := b.Repos.Books
+	owner := createUser(t, b, "owner@example.com", base)
+	second := createUser(t, b, "second@example.com", base)
+	reader := createUser(t, b, "reader@example.com", base)
+	other := createUser(t, b, "other@example.com", base)
+	library := createLocation(t, b, "library", "Ленина, 1")
+	cafe := createLocation(t, b, "cafe", "Мира, 2")
+
+	catalogBook := func(owner *domain.User, title, author string, condition domain.BookCondition, status domain.BookStatus, location *domain.Location, createdAt time.Time) *domain.Book {
+		t.Helper()
+		book := createBook(t, b, owner, title, createdAt)
+		book.Author = author
+		book.Condition = condition
+		book.Status = status
+		if location != nil {
+			book.CurrentLocationID = &location.ID
+		}
+		mustNoErr(t, books.Update(book))
+		return book
+	}
+	anna := catalogBook(owner, "Anna Karenina", "Tolstoy", domain.ConditionExcellent, domain.BookAvailable, library, base)
+	war := catalogBook(owner, "War and Peace", "Tolstoy", domain.ConditionGood, domain.BookBorrowed, cafe, base.Add(time.Hour))
+	solaris := catalogBook(second, "Solaris", "Lem", domain.ConditionGood, domain.BookAvailable, library, base.Add(2*time.Hour))
+	dune := catalogBook(second, "Dune", "Herbert", domain.ConditionBad, domain.BookArchived, nil, base.Add(3*time.Hour))
+	catalogBook(owner, "Lost", "Lem", domain.ConditionGood, domain.BookDeleted, library, base.Add(4*time.Hour))
+
+	for _, review := range []*domain.Review{
+		{BookID: anna.ID, UserID: reader.ID, Rating: 5},
+		{BookID: anna.ID, UserID: other.ID, Rating: 3},
+		{BookID: solaris.ID, UserID: reader.ID, Rating: 5},
+		{BookID: dune.ID, UserID: reader.ID, Rating: 2},
+	} {
+		mustNoErr(t, b.Repos.Reviews.Create(review))
+	}
+
+	filter := func(f domain.BookFilter) ([]*domain.BookListItem, int64) {
+		t.Helper()
+		if f.Sort == "" {
+			f.Sort, f.Desc = domain.BookSortCreatedAt, true
+		}
+		if f.Limit == 0 {
+			f.Limit = -1
+		}
+		items, err := books.Filter(f)
+		mustNoErr(t, err)
+		total, err := books.CountFiltered(f)
+		mustNoErr(t, err)
+		return items, total
+	}
+
+	// Удаленные книги в каталог не попадают
+	items, total := filter(domain.BookFilter{})
+	expectIDs(t, "Filter newest first", listIDs(items), dune.ID, solaris.ID, war.ID, anna.ID)
+	if total != 4 {
+		t.Fatalf("expected total 4, got %d", total)
+	}
+	if items[3].AverageRating == nil || *items[3].AverageRating != 4 || items[3].ReviewsCount != 2 {
+		t.Fatalf("unexpected rating of %q: %v (%d reviews)", items[3].Title, items[3].AverageRating, items[3].ReviewsCount)
+	}
+	if items[2].AverageRating != nil || items[2].ReviewsCount != 0 {
+		t.Fatalf("book without reviews must have no rating, got %v (%d reviews)", items[2].AverageRating, items[2].ReviewsCount)
+	}
+
+	items, _ = filter(domain.BookFilter{Sort: domain.BookSortTitle})
+	expectIDs(t, "Filter by title", listIDs(items), anna.ID, dune.ID, solaris.ID, war.ID)
+	items, _ = filter(domain.BookFilter{Sort: domain.BookSortTitle, Desc: true})
+	expectIDs(t, "Filter by title desc", listIDs(items), war.ID, solaris.ID, dune.ID, anna.ID)
+
+	// Книги без отзывов - в конце при любом направлении
+	items, _ = filter(domain.BookFilter{Sort: domain.BookSortRating, Desc: true})
+	expectIDs(t, "Filter by rating desc", listIDs(items), solaris.ID, anna.ID, dune.ID, war.ID)
+	items, _ = filter(domain.BookFilter{Sort: domain.BookSortRating})
+	expectIDs(t, "Filter by rating asc", listIDs(items), dune.ID, anna.ID, solaris.ID, war.ID)
+
+	items, _ = filter(domain.BookFilter{Statuses: []domain.BookStatus{domain.BookAvailable}, Authors: []string{"TOLSTOY"}})
+	expectIDs(t, "Filter by status and author", listIDs(items), anna.ID)
+	items, _ = filter(domain.BookFilter{Conditions: []domain.BookCondition{domain.ConditionGood}, LocationIDs: []uuid.UUID{library.ID}})
+	expectIDs(t, "Filter by condition and location", listIDs(items), solaris.ID)
+	items, _ = filter(domain.BookFilter{OwnerIDs: []uuid.UUID{second.ID}})
+	expectIDs(t, "Filter by owner", listIDs(items), dune.ID, solaris.ID)
+	items, _ = filter(domain.BookFilter{Statuses: []domain.BookStatus{domain.BookBorrowed, domain.BookArchived}})
+	expectIDs(t, "Filter by several statuses", listIDs(items), dune.ID, war.ID)
+
+	from, to := base.Add(time.Hour), base.Add(3*time.Hour)
+	items, _ = filter(domain.BookFilter{CreatedFrom: &from, CreatedTo: &to})
+	expectIDs(t, "Filter by created range", listIDs(items), solaris.ID, war.ID)
+
+	items, total = filter(domain.BookFilter{After: timeCursor(dune.CreatedAt, dune.ID), Limit: 2})
+	expectIDs(t, "Filter page", listIDs(items), solaris.ID, war.ID)
+	if total != 4 {
+		t.Fatalf("total must count all matches, got %d", total)
+	}
+
+	// Курсор по каждой сортировке: страница продолжается сразу после записи курсора
+	for _, tt := range []struct {
+		sort domain.BookSort
+		desc bool
+		want []uuid.UUID
+	}{
+		{domain.BookSortTitle, false, []uuid.UUID{anna.ID, dune.ID, solaris.ID, war.ID}},
+		{domain.BookSortTitle, true, []uuid.UUID{war.ID, solaris.ID, dune.ID, anna.ID}},
+		{domain.BookSortCreatedAt, false, []uuid.UUID{anna.ID, war.ID, solaris.ID, dune.ID}},
+		{domain.BookSortRating, true, []uuid.UUID{solaris.ID, anna.ID, dune.ID, war.ID}},
+		{domain.BookSortRating, false, []uuid.UUID{dune.ID, anna.ID, solaris.ID, war.ID}},
+	} {
+		f := domain.BookFilter{Sort: tt.sort, Desc: tt.desc, Limit: 1}
+		var got []uuid.UUID
+		for range tt.want {
+			items, _ := filter(f)
+			if len(items) != 1 {
+				t.Fatalf("sort %s desc=%v: page after %v is empty", tt.sort, tt.desc, got)
+			}
+			got = append(got, items[0].ID)
+			c := f.Cursor(items[0])
+			f.After = &c
+		}
+		items, _ := filter(f)
+		got = append(got, listIDs(items)...)
+		expectIDs(t, fmt.Sprintf("Filter pages by %s desc=%v", tt.sort, tt.desc), got, tt.want...)
+	}
+
+	facets, err := books.Facets(domain.BookFilter{})
+	mustNoErr(t, err)
+	expectFacets(t, "status", facets.Status,
+		domain.FacetCount{Value: "available", Count: 2},
+		domain.FacetCount{Value: "archived", Count: 1},
+		domain.FacetCount{Value: "borrowed", Count: 1})
+	expectFacets(t, "condition", facets.Condition,
+		domain.FacetCount{Value: "good", Count: 2},
+		domain.FacetCount{Value: "bad", Count: 1},
+		domain.FacetCount{Value: "excellent", Count: 1})
+	expectFacets(t, "author", facets.Author,
+		domain.FacetCount{Value: "Tolstoy", Count: 2},
+		domain.FacetCount{Value: "Herbert", Count: 1},
+		domain.FacetCount{Value: "Lem", Count: 1})
+	expectFacets(t, "location", facets.Location,
+		domain.FacetCount{Value: library.ID.String(), Label: "library", Count: 2},
+		domain.FacetCount{Value: cafe.ID.String(), Label: "cafe", Count: 1})
+	expectFacets(t, "owner", facets.Owner, sortedFacets(
+		domain.FacetCount{Value: owner.ID.String(), Label: owner.Name, Count: 2},
+		domain.FacetCount{Value: second.ID.String(), Label: second.Name, Count: 2})...)
+
+	// Счетчики поля не учитывают фильтр по самому полю, но учитывают остальные
+	facets, err = books.Facets(domain.BookFilter{
+		Statuses:   []domain.BookStatus{domain.BookAvailable},
+		Conditions: []domain.BookCondition{domain.ConditionGood},
+	})
+	mustNoErr(t, err)
+	expectFacets(t, "status with condition", facets.Status,
+		domain.FacetCount{Value: "available", Count: 1},
+		domain.FacetCount{Value: "borrowed", Count: 1})
+	expectFacets(t, "condition with status", facets.Condition,
+		domain.FacetCount{Value: "excellent", Count: 1},
+		domain.FacetCount{Value: "good", Count: 1})
+	expectFacets(t, "author with both", facets.Author, domain.FacetCount{Value: "Lem", Count: 1})
+	expectFacets(t, "owner with both", facets.Owner, domain.FacetCount{Value: second.ID.String(), Label: second.Name, Count: 1})
+}
+
+func testWorks(t *testing.T, b Backend) {
+	works, books := b.Repos.Works, b.Repos.Books
+	owner := createUser(t, b, "owner@example.com", base)
+	reader := createUser(t, b, "reader@example.com", base)
+	library := createLocation(t, b, "library", "Ленина, 1")
+	cafe := createLocation(t, b, "cafe", "Мира, 2")
+
+	work := &domain.Work{Key: domain.WorkKey("Solaris", "Lem"), Title: "Solaris", Author: "Lem"}
+	mustNoErr(t, works.Create(work))
+	got, err := works.GetByKey(domain.WorkKey("  SOLARIS ", "lem"))
+	mustNoErr(t, err)
+	if got.ID != work.ID || got.Title != "Solaris" || got.CreatedAt.IsZero() {
+		t.Fatalf("unexpected work %+v", got)
+	}
+	mustErrIs(t, works.Create(&domain.Work{Key: work.Key, Title: "Solaris", Author: "Lem"}), gorm.ErrDuplicatedKey)
+	_, err = works.GetByKey(domain.WorkKey("Eden", "Lem"))
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+	_, err = works.GetByID(uuid.New())
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+
+	workCopy := func(status domain.BookStatus, location *domain.Location, createdAt time.Time) *domain.Book {
+		t.Helper()
+		book := createBook(t, b, owner, "Solaris", createdAt)
+		book.WorkID = &work.ID
+		book.Status = status
+		if location != nil {
+			book.CurrentLocationID = &location.ID
+		}
+		mustNoErr(t, books.Update(book))
+		return book
+	}
+	first := workCopy(domain.BookAvailable, library, base)
+	second := workCopy(domain.BookAvailable, library, base.Add(time.Hour))
+	third := workCopy(domain.BookAvailable, cafe, base.Add(2*time.Hour))
+	borrowed := workCopy(domain.BookBorrowed, nil, base.Add(3*time.Hour))
+	workCopy(domain.BookDeleted, library, base.Add(4*time.Hour))
+	single := createBook(t, b, owner, "Solaris Station", base)
+
+	// Удаленные экземпляры не считаются
+	copies, err := works.CountCopies(work.ID)
+	mustNoErr(t, err)
+	if copies.Total != 4 || copies.Available != 3 {
+		t.Fatalf("expected 3 of 4 copies available, got %+v", copies)
+	}
+	locations, err := works.AvailableLocations(work.ID)
+	mustNoErr(t, err)
+	if len(locations) != 2 || locations[0].LocationID != library.ID || locations[0].Available != 2 ||
+		locations[0].Name != "library" || locations[1].LocationID != cafe.ID || locations[1].Available != 1 {
+		t.Fatalf("unexpected available locations %+v", locations)
+	}
+	workBooks, err := books.GetByWorkID(work.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByWorkID", bookIDs(workBooks), borrowed.ID, third.ID, second.ID, first.ID)
+
+	// Отзывы на разные экземпляры относятся к одному произведению
+	older := &domain.Review{BookID: first.ID, UserID: reader.ID, Rating: 5, CreatedAt: base}
+	newer := &domain.Review{BookID: borrowed.ID, UserID: owner.ID, Rating: 2, CreatedAt: base.Add(time.Hour)}
+	for _, review := range []*domain.Review{older, newer, {BookID: single.ID, UserID: reader.ID, Rating: 1}} {
+		mustNoErr(t, b.Repos.Reviews.Create(review))
+	}
+	byWork, err := b.Repos.Reviews.GetByWorkID(work.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByWorkID reviews", reviewIDs(byWork), newer.ID, older.ID)
+	if byWork[0].User.ID != owner.ID {
+		t.Fatal("GetByWorkID must preload User")
+	}
+
+	// В каталоге у каждого экземпляра оценка произведения
+	items, err := books.Filter(domain.BookFilter{Sort: domain.BookSortCreatedAt, Desc: true, Limit: -1})
+	mustNoErr(t, err)
+	for _, item := range items {
+		want, count := 3.5, int64(2)
+		if item.ID == single.ID {
+			want, count = 1, 1
+		} else if item.WorkID == nil || *item.WorkID != work.ID {
+			t.Fatalf("catalog item %s must carry its work", item.ID)
+		}
+		if item.AverageRating == nil || *item.AverageRating != want || item.ReviewsCount != count {
+			t.Fatalf("item %s: expected rating %v of %d reviews, got %v of %d", item.ID, want, count, item.AverageRating, item.ReviewsCount)
+		}
+	}
+
+	// Поиск схлопывает экземпляры: один результат на произведение, представитель - свободный экземпляр
+	found, total, err := books.Search("solaris", -1, 0)
+	mustNoErr(t, err)
+	if total != 2 || len(found) != 2 {
+		t.Fatalf("expected 2 works, got %d of %d", len(found), total)
+	}
+	var hit *domain.BookSearchResult
+	for _, res := range found {
+		if res.WorkID != nil && *res.WorkID == work.ID {
+			hit = res
+		} else if res.ID != single.ID || res.Copies != 1 || res.AvailableCopies != 1 {
+			t.Fatalf("book without work must be its own result, got %+v", res)
+		}
+	}
+	lo, _ := idOrder(first.ID, second.ID)
+	representative, _ := idOrder(lo, third.ID)
+	if hit == nil || hit.ID != representative || hit.Status != domain.BookAvailable || hit.Copies != 4 || hit.AvailableCopies != 3 {
+		t.Fatalf("unexpected work result %+v (want copy %s)", hit, representative)
+	}
+}
+
+func testExchanges(t *testing.T, b Backend) {
+	exchanges := b.Repos.Exchanges
+	owner := createUser(t, b, "owner@example.com", base)
+	reader := createUser(t, b, "reader@example.com", base)
+	book := createBook(t, b, owner, "Dune", base)
+
+	exchange := &domain.Exchange{UserID: reader.ID, BookID: book.ID}
+	mustNoErr(t, exchanges.Create(exchange))
+
+	got, err := exchanges.GetByID(exchange.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.ExchangeRequested {
+		t.Fatalf("expected default status requested, got %s", got.Status)
+	}
+	if got.Book.ID != book.ID || got.User.ID != reader.ID {
+		t.Fatalf("GetByID must preload Book and User, got book %s user %s", got.Book.ID, got.User.ID)
+	}
+
+	due := base.Add(14 * 24 * time.Hour)
+	got.Status = domain.ExchangeBorrowed
+	got.DueAt = &due
+	got.Renewals = 1
+	mustNoErr(t, exchanges.Update(got))
+	got, err = exchanges.GetByID(exchange.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.ExchangeBorrowed || got.DueAt == nil || !got.DueAt.Equal(due) || got.Renewals != 1 {
+		t.Fatalf("unexpected exchange after update %+v", got)
+	}
+
+	// Условное обновление: второй запрос с тем же ожидаемым статусом проигрывает
+	got.Status = domain.ExchangeReturned
+	mustNoErr(t, exchanges.UpdateIfStatus(got, domain.ExchangeBorrowed))
+	stale := *got
+	stale.Status = domain.ExchangeOverdue
+	mustErrIs(t, exchanges.UpdateIfStatus(&stale, domain.ExchangeBorrowed), domain.ErrExchangeConflict)
+	mustErrIs(t, exchanges.UpdateIfStatus(&domain.Exchange{ID: uuid.New()}, domain.ExchangeBorrowed), domain.ErrExchangeConflict)
+	got, err = exchanges.GetByID(exchange.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.ExchangeReturned || got.DueAt == nil {
+		t.Fatalf("stale update must not apply, got %+v", got)
+	}
+
+	mustNoErr(t, exchanges.Delete(exchange.ID))
+	_, err = exchanges.GetByID(exchange.ID)
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testExchangesQueries(t *testing.T, b Backend) {
+	exchanges := b.Repos.Exchanges
+	owner := createUser(t, b, "owner@example.com", base)
+	reader := createUser(t, b, "reader@example.com", base)
+	other := createUser(t, b, "other@example.com", base)
+	dune := createBook(t, b, owner, "Dune", base)
+	solaris := createBook(t, b, owner, "Solaris", base)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	farPast := time.Now().Add(-48 * time.Hour)
+
+	expired := createExchange(t, b, &domain.Exchange{UserID: reader.ID, BookID: dune.ID, ExpiresAt: &past, BookedAt: base})
+	active := createExchange(t, b, &domain.Exchange{UserID: reader.ID, BookID: solaris.ID, ExpiresAt: &future, BookedAt: base.Add(time.Hour)})
+	overdue := createExchange(t, b, &domain.Exchange{UserID: other.ID, BookID: dune.ID, Status: domain.ExchangeBorrowed, DueAt: &past, BookedAt: base.Add(2 * time.Hour)})
+	veryOverdue := createExchange(t, b, &domain.Exchange{UserID: other.ID, BookID: solaris.ID, Status: domain.ExchangeBorrowed, DueAt: &farPast, BookedAt: base.Add(3 * time.Hour)})
+	noDue := createExchange(t, b, &domain.Exchange{UserID: other.ID, BookID: solaris.ID, Status: domain.ExchangeBorrowed, BookedAt: base.Add(4 * time.Hour)})
+
+	byUser, err := exchanges.ListByUserID(reader.ID, domain.PageQuery{Limit: -1})
+	mustNoErr(t, err)
+	expectIDs(t, "ListByUserID", exchangeIDs(byUser), active.ID, expired.ID)
+	if byUser[0].Book.ID != solaris.ID {
+		t.Fatalf("ListByUserID must preload Book")
+	}
+	byUser, err = exchanges.ListByUserID(reader.ID, domain.PageQuery{After: timeCursor(active.BookedAt, active.ID), Limit: 1})
+	mustNoErr(t, err)
+	expectIDs(t, "ListByUserID after active", exchangeIDs(byUser), expired.ID)
+
+	byBook, err := exchanges.GetByBookID(dune.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByBookID", exchangeIDs(byBook), overdue.ID, expired.ID)
+	byBook, err = exchanges.ListByBookID(dune.ID, domain.PageQuery{Limit: 1})
+	mustNoErr(t, err)
+	expectIDs(t, "ListByBookID", exchangeIDs(byBook), overdue.ID)
+	if byBook[0].User.ID != other.ID {
+		t.Fatalf("ListByBookID must preload User")
+	}
+
+	for _, tt := range []struct {
+		what  string
+		count func() (int64, error)
+		want  int64
+	}{
+		{"CountByUserID", func() (int64, error) { return exchanges.CountByUserID(other.ID) }, 3},
+		{"CountByBookID", func() (int64, error) { return exchanges.CountByBookID(solaris.ID) }, 3},
+	} {
+		got, err := tt.count()
+		mustNoErr(t, err)
+		if got != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.what, tt.want, got)
+		}
+	}
+
+	list, err := exchanges.List(domain.PageQuery{After: timeCursor(noDue.BookedAt, noDue.ID), Limit: 2})
+	mustNoErr(t, err)
+	expectIDs(t, "List after newest", exchangeIDs(list), veryOverdue.ID, overdue.ID)
+
+	expiredList, err := exchanges.GetExpired()
+	mustNoErr(t, err)
+	expectIDs(t, "GetExpired", exchangeIDs(expiredList), expired.ID)
+
+	overdueList, err := exchanges.GetOverdue()
+	mustNoErr(t, err)
+	expectIDs(t, "GetOverdue", exchangeIDs(overdueList), veryOverdue.ID, overdue.ID)
+
+	// Без срока возврата - в конце, как NULL в ORDER BY ... ASC
+	borrowed, err := exchanges.GetByStatus(domain.ExchangeBorrowed)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByStatus", exchangeIDs(borrowed), veryOverdue.ID, overdue.ID, noDue.ID)
+}
+
+func testLocations(t *testing.T, b Backend) {
+	locations := b.Repos.Locations
+	owner := createUser(t, b, "owner@example.com", base)
+	second := createLocation(t, b, "b-library", "Мира, 2")
+	first := createLocation(t, b, "a-library", "Ленина, 1")
+
+	book := createBook(t, b, owner, "Dune", base)
+	book.CurrentLocationID = &first.ID
+	mustNoErr(t, b.Repos.Books.Update(book))
+
+	got, err := locations.GetByID(first.ID)
+	mustNoErr(t, err)
+	if len(got.Books) != 1 || got.Books[0].ID != book.ID {
+		t.Fatalf("GetByID must preload Books, got %d books", len(got.Books))
+	}
+	got, err = locations.GetByAddress("Мира, 2")
+	mustNoErr(t, err)
+	if got.ID != second.ID {
+		t.Fatalf("GetByAddress returned %s, want %s", got.ID, second.ID)
+	}
+	_, err = locations.GetByAddress("Нет такого")
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+
+	all, err := locations.GetAll()
+	mustNoErr(t, err)
+	var ids []uuid.UUID
+	for _, l := range all {
+		ids = append(ids, l.ID)
+	}
+	expectIDs(t, "GetAll", ids, first.ID, second.ID)
+
+	second.Name = "c-library"
+	mustNoErr(t, locations.Update(second))
+	got, err = locations.GetByID(second.ID)
+	mustNoErr(t, err)
+	if got.Name != "c-library" {
+		t.Fatalf("unexpected name after update %q", got.Name)
+	}
+
+	mustNoErr(t, locations.Delete(second.ID))
+	mustErrIs(t, locations.Delete(second.ID), gorm.ErrRecordNotFound)
+}
+
+func testReviews(t *testing.T, b Backend) {
+	reviews := b.Repos.Reviews
+	owner := createUser(t, b, "owner@example.com", base)
+	reader := createUser(t, b, "reader@example.com", base)
+	other := createUser(t, b, "other@example.com", base)
+	dune := createBook(t, b, owner, "Dune", base)
+	solaris := createBook(t, b, owner, "Solaris", base)
+
+	older := &domain.Review{BookID: dune.ID, UserID: reader.ID, Rating: 4, Text: "Хорошо", CreatedAt: base}
+	mustNoErr(t, reviews.Create(older))
+	newer := &domain.Review{BookID: dune.ID, UserID: other.ID, Rating: 5, CreatedAt: base.Add(time.Hour)}
+	mustNoErr(t, reviews.Create(newer))
+	another := &domain.Review{BookID: solaris.ID, UserID: reader.ID, Rating: 3, CreatedAt: base.Add(2 * time.Hour)}
+	mustNoErr(t, reviews.Create(another))
+
+	duplicate := &domain.Review{BookID: dune.ID, UserID: reader.ID, Rating: 1}
+	mustErrIs(t, reviews.Create(duplicate), gorm.ErrDuplicatedKey)
+
+	byBook, err := reviews.GetByBookID(dune.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByBookID", reviewIDs(byBook), newer.ID, older.ID)
+	if byBook[0].User.ID != other.ID {
+		t.Fatalf("GetByBookID must preload User")
+	}
+
+	byUser, err := reviews.GetByUserID(reader.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByUserID", reviewIDs(byUser), another.ID, older.ID)
+
+	got, err := reviews.GetByID(older.ID)
+	mustNoErr(t, err)
+	got.Rating = 2
+	mustNoErr(t, reviews.Update(got))
+	got, err = reviews.GetByID(older.ID)
+	mustNoErr(t, err)
+	if got.Rating != 2 || got.Book.ID != dune.ID {
+		t.Fatalf("unexpected review after update %+v", got)
+	}
+
+	mustNoErr(t, reviews.Delete(older.ID))
+	_, err = reviews.GetByID(older.ID)
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testMovements(t *testing.T, b Backend) {
+	movements := b.Repos.Movements
+	owner := createUser(t, b, "owner@example.com", base)
+	reader := createUser(t, b, "reader@example.com", base)
+	book := createBook(t, b, owner, "Dune", base)
+	exchange := createExchange(t, b, &domain.Exchange{UserID: reader.ID, BookID: book.ID})
+
+	created := createMovement(t, b, &domain.BookMovementHistory{BookID: book.ID, UserID: &owner.ID, Action: domain.ActionCreated, CreatedAt: base})
+	requested := createMovement(t, b, &domain.BookMovementHistory{BookID: book.ID, UserID: &reader.ID, ExchangeID: &exchange.ID, Action: domain.ActionRequested, CreatedAt: base.Add(time.Hour)})
+	borrowed := createMovement(t, b, &domain.BookMovementHistory{BookID: book.ID, UserID: &reader.ID, ExchangeID: &exchange.ID, Action: domain.ActionBorrowed, CreatedAt: base.Add(2 * time.Hour)})
+
+	byBook, err := movements.GetByBookID(book.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByBookID", movementIDs(byBook), borrowed.ID, requested.ID, created.ID)
+
+	byExchange, err := movements.GetByExchangeID(exchange.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByExchangeID", movementIDs(byExchange), borrowed.ID, requested.ID)
+
+	byUser, err := movements.GetByUserID(owner.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByUserID", movementIDs(byUser), created.ID)
+
+	page, err := movements.List(domain.PageQuery{After: timeCursor(borrowed.CreatedAt, borrowed.ID), Limit: 1})
+	mustNoErr(t, err)
+	expectIDs(t, "List after newest", movementIDs(page), requested.ID)
+
+	page, err = movements.ListByBookID(book.ID, domain.PageQuery{After: timeCursor(requested.CreatedAt, requested.ID), Limit: 5})
+	mustNoErr(t, err)
+	expectIDs(t, "ListByBookID after requested", movementIDs(page), created.ID)
+	page, err = movements.ListByUserID(reader.ID, domain.PageQuery{Limit: 1})
+	mustNoErr(t, err)
+	expectIDs(t, "ListByUserID", movementIDs(page), borrowed.ID)
+	if page[0].Book == nil || page[0].Book.ID != book.ID {
+		t.Fatalf("ListByUserID must preload Book")
+	}
+
+	byBookCount, err := movements.CountByBookID(book.ID)
+	mustNoErr(t, err)
+	byUserCount, err := movements.CountByUserID(reader.ID)
+	mustNoErr(t, err)
+	if byBookCount != 3 || byUserCount != 2 {
+		t.Fatalf("expected counts 3 by book and 2 by user, got %d and %d", byBookCount, byUserCount)
+	}
+
+	got, err := movements.GetByID(requested.ID)
+	mustNoErr(t, err)
+	if got.Book == nil || got.Book.ID != book.ID || got.Exchange == nil || got.Exchange.ID != exchange.ID {
+		t.Fatalf("GetByID must preload Book and Exchange")
+	}
+	got.Notes = "Заметка"
+	mustNoErr(t, movements.Update(got))
+	got, err = movements.GetByID(requested.ID)
+	mustNoErr(t, err)
+	if got.Notes != "Заметка" {
+		t.Fatalf("unexpected notes after update %q", got.Notes)
+	}
+
+	_, err = movements.GetByID(uuid.New())
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testWaitlist(t *testing.T, b Backend) {
+	waitlist := b.Repos.Waitlist
+	owner := createUser(t, b, "owner@example.com", base)
+	first := createUser(t, b, "first@example.com", base)
+	second := createUser(t, b, "second@example.com", base)
+	book := createBook(t, b, owner, "Dune", base)
+
+	secondEntry := &domain.WaitlistEntry{BookID: book.ID, UserID: second.ID, CreatedAt: base.Add(time.Minute)}
+	mustNoErr(t, waitlist.Create(secondEntry))
+	firstEntry := &domain.WaitlistEntry{BookID: book.ID, UserID: first.ID, CreatedAt: base}
+	mustNoErr(t, waitlist.Create(firstEntry))
+	mustErrIs(t, waitlist.Create(&domain.WaitlistEntry{BookID: book.ID, UserID: first.ID}), gorm.ErrDuplicatedKey)
+
+	queue, err := waitlist.GetByBookID(book.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByBookID", waitlistIDs(queue), firstEntry.ID, secondEntry.ID)
+	if queue[0].User == nil || queue[0].User.ID != first.ID {
+		t.Fatalf("GetByBookID must preload User")
+	}
+
+	byUser, err := waitlist.GetByUserID(second.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "GetByUserID", waitlistIDs(byUser), secondEntry.ID)
+
+	got, err := waitlist.GetByBookAndUser(book.ID, first.ID)
+	mustNoErr(t, err)
+	if got.ID != firstEntry.ID {
+		t.Fatalf("GetByBookAndUser returned %s, want %s", got.ID, firstEntry.ID)
+	}
+
+	mustNoErr(t, waitlist.Delete(firstEntry.ID))
+	_, err = waitlist.GetByBookAndUser(book.ID, first.ID)
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testUnitOfWork(t *testing.T, b Backend) {
+	owner := createUser(t, b, "owner@example.com", base)
+	book := createBook(t, b, owner, "Dune", base)
+
+	// Ошибка внутри Do откатывает все изменения
+	errRollback := errors.New("rollback")
+	err := b.UnitOfWork.Do(func(repos *domain.Repositories) error {
+		book.Status = domain.BookRequested
+		if err := repos.Books.UpdateIfStatus(book, domain.BookAvailable); err != nil {
+			return err
+		}
+		if err := repos.Movements.Create(&domain.BookMovementHistory{BookID: book.ID, Action: domain.ActionRequested}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	mustErrIs(t, err, errRollback)
+
+	got, err := b.Repos.Books.GetByID(book.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.BookAvailable {
+		t.Fatalf("rolled back transaction changed status to %s", got.Status)
+	}
+	history, err := b.Repos.Movements.GetByBookID(book.ID)
+	mustNoErr(t, err)
+	expectIDs(t, "history after rollback", movementIDs(history))
+
+	// Успешный Do сохраняет изменения
+	err = b.UnitOfWork.Do(func(repos *domain.Repositories) error {
+		book.Status = domain.BookRequested
+		if err := repos.Books.UpdateIfStatus(book, domain.BookAvailable); err != nil {
+			return err
+		}
+		return repos.Movements.Create(&domain.BookMovementHistory{BookID: book.ID, Action: domain.ActionRequested})
+	})
+	mustNoErr(t, err)
+
+	got, err = b.Repos.Books.GetByID(book.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.BookRequested {
+		t.Fatalf("committed transaction did not change status, got %s", got.Status)
+	}
+	history, err = b.Repos.Movements.GetByBookID(book.ID)
+	mustNoErr(t, err)
+	if len(history) != 1 {
+		t.Fatalf("expected 1 movement after commit, got %d", len(history))
+	}
+}
+
+func testUploads(t *testing.T, b Backend) {
+	owner := createUser(t, b, "owner@example.com", base)
+	upload := &domain.Upload{OwnerID: owner.ID, ContentType: "image/png", Size: 2048, Width: 640, Height: 480, URL: "/ignored"}
+	mustNoErr(t, b.Repos.Uploads.Create(upload))
+	if upload.ID == uuid.Nil || upload.CreatedAt.IsZero() {
+		t.Fatalf("Create must assign an ID and created_at: %+v", upload)
+	}
+
+	got, err := b.Repos.Uploads.GetByID(upload.ID)
+	mustNoErr(t, err)
+	if got.OwnerID != owner.ID || got.ContentType != "image/png" || got.Size != 2048 || got.Width != 640 || got.Height != 480 || got.URL != "" {
+		t.Fatalf("unexpected upload %+v", got)
+	}
+	_, err = b.Repos.Uploads.GetByID(uuid.New())
+	mustErrIs(t, err, gorm.ErrRecordNotFound)
+
+	// Книга ссылается на загрузку как на обложку
+	book := &domain.Book{OwnerID: owner.ID, Title: "Обложка", Author: "Автор", ImageID: &upload.ID, ImageURL: domain.ImageURL(upload.ID, domain.ImageFull)}
+	mustNoErr(t, b.Repos.Books.Create(book))
+	stored, err := b.Repos.Books.GetByID(book.ID)
+	mustNoErr(t, err)
+	if stored.ImageID == nil || *stored.ImageID != upload.ID || stored.ImageURL != book.ImageURL {
+		t.Fatalf("book must keep its image: %+v", stored)
+	}
+}
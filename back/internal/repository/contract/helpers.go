@@ -0,0 +1,190 @@
+package contract
+
+import (
+	"bookvito/internal/domain"
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func createUser(t *testing.T, b Backend, email string, createdAt time.Time) *domain.User {
+	t.Helper()
+	user := &domain.User{Email: email, Password: "hash", Name: email, CreatedAt: createdAt}
+	mustNoErr(t, b.Repos.Users.Create(user))
+	return user
+}
+
+func createBook(t *testing.T, b Backend, owner *domain.User, title string, createdAt time.Time) *domain.Book {
+	t.Helper()
+	book := &domain.Book{OwnerID: owner.ID, Title: title, Author: "Author", CreatedAt: createdAt}
+	mustNoErr(t, b.Repos.Books.Create(book))
+	return book
+}
+
+func createLocation(t *testing.T, b Backend, name, address string) *domain.Location {
+	t.Helper()
+	location := &domain.Location{Name: name, Address: address}
+	mustNoErr(t, b.Repos.Locations.Create(location))
+	return location
+}
+
+func createExchange(t *testing.T, b Backend, exchange *domain.Exchange) *domain.Exchange {
+	t.Helper()
+	mustNoErr(t, b.Repos.Exchanges.Create(exchange))
+	return exchange
+}
+
+func createMovement(t *testing.T, b Backend, movement *domain.BookMovementHistory) *domain.BookMovementHistory {
+	t.Helper()
+	mustNoErr(t, b.Repos.Movements.Create(movement))
+	return movement
+}
+
+func mustNoErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustErrIs(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected error %v, got %v", target, err)
+	}
+}
+
+// expectIDs сравнивает порядок записей по ID
+func expectIDs(t *testing.T, what string, got []uuid.UUID, want ...uuid.UUID) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d records %v, got %d %v", what, len(want), want, len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: record %d is %s, want %s (got %v)", what, i, got[i], want[i], got)
+		}
+	}
+}
+
+func userIDs(users []*domain.User) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func sessionIDs(sessions []*domain.Session) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(sessions))
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func bookIDs(books []*domain.Book) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(books))
+	for _, b := range books {
+		ids = append(ids, b.ID)
+	}
+	return ids
+}
+
+func listIDs(items []*domain.BookListItem) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+// expectFacets сравнивает счетчики фасета вместе с порядком
+func expectFacets(t *testing.T, what string, got []domain.FacetCount, want ...domain.FacetCount) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("facet %s: expected %v, got %v", what, want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("facet %s: value %d is %+v, want %+v (got %v)", what, i, got[i], want[i], got)
+		}
+	}
+}
+
+// sortedFacets упорядочивает значения с равными счетчиками так же, как репозиторий - по значению
+func sortedFacets(facets ...domain.FacetCount) []domain.FacetCount {
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
+
+func searchIDs(results []*domain.BookSearchResult) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// pageOrder - ожидаемый порядок двух записей, когда первой может оказаться любая из них
+func pageOrder(first, a, b uuid.UUID) []uuid.UUID {
+	if first == a {
+		return []uuid.UUID{a, b}
+	}
+	return []uuid.UUID{b, a}
+}
+
+// idOrder упорядочивает два ID так же, как их сортирует хранилище
+func idOrder(a, b uuid.UUID) (uuid.UUID, uuid.UUID) {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		return b, a
+	}
+	return a, b
+}
+
+// timeCursor - курсор репозитория на запись списка, упорядоченного по времени
+func timeCursor(t time.Time, id uuid.UUID) *domain.Cursor {
+	c := domain.TimeCursor("", t, id)
+	return &c
+}
+
+func exchangeIDs(exchanges []*domain.Exchange) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(exchanges))
+	for _, e := range exchanges {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func reviewIDs(reviews []domain.Review) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(reviews))
+	for _, r := range reviews {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func movementIDs(movements []*domain.BookMovementHistory) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(movements))
+	for _, m := range movements {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func waitlistIDs(entries []*domain.WaitlistEntry) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"bookvito/internal/domain"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer сохраняет каждое письмо в отдельный .eml-файл каталога вместо отправки
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates the directory if needed and stores letters there
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, mail domain.Mail) error {
+	date := time.Now()
+	message, err := buildMessage(m.from, mail, date)
+	if err != nil {
+		return err
+	}
+	// Имя начинается со времени, поэтому сортировка по имени - порядок отправки
+	name := fmt.Sprintf("%s-%s.eml", date.UTC().Format("20060102T150405.000000000"), uuid.NewString()[:8])
+	return os.WriteFile(filepath.Join(m.dir, name), message, 0o644)
+}
+
+// LogMailer пишет письма в лог приложения; используется, если почта не настроена
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, mail domain.Mail) error {
+	log.Printf("Mail to %s: %s\n%s", mail.To, mail.Subject, mail.Body)
+	return nil
+}
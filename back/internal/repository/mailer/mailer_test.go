@@ -0,0 +1,141 @@
+package mailer
+
+import (
+	"bookvito/internal/domain"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var letter = domain.Mail{
+	To:      "reader@example.com",
+	Subject: "Подтверждение почты",
+	Body:    "Здравствуйте!\n\nСсылка: https://bookvito.example.com/verify-email?token=" + strings.Repeat("x", 60) + "\n",
+}
+
+// readLetter разбирает письмо и проверяет заголовки и текст
+func readLetter(t *testing.T, r io.Reader) {
+	t.Helper()
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if to := msg.Header.Get("To"); to != letter.To {
+		t.Fatalf("expected To %q, got %q", letter.To, to)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != letter.Subject {
+		t.Fatalf("expected Subject %q, got %q (%v)", letter.Subject, subject, err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	// В письме строки разделяются CRLF
+	if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != letter.Body {
+		t.Fatalf("expected body %q, got %q", letter.Body, got)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "noreply@bookvito.local")
+	if err != nil {
+		t.Fatalf("create mailer: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := m.Send(context.Background(), letter); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("expected 2 letters, got %v (%v)", files, err)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("open letter: %v", err)
+	}
+	defer f.Close()
+	readLetter(t, f)
+}
+
+func TestSMTPMailer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	type received struct {
+		from, to, data string
+	}
+	done := make(chan received, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		done <- serveSMTP(textproto.NewConn(conn))
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	m := NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "noreply@bookvito.local"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Send(ctx, letter); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	got := <-done
+	if got.from != "<noreply@bookvito.local>" || got.to != "<reader@example.com>" {
+		t.Fatalf("unexpected envelope %q -> %q", got.from, got.to)
+	}
+	readLetter(t, strings.NewReader(got.data))
+}
+
+// serveSMTP - минимальный SMTP-сервер на одно письмо, без STARTTLS и авторизации
+func serveSMTP(c *textproto.Conn) (r struct{ from, to, data string }) {
+	c.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return r
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "MAIL":
+			r.from = strings.TrimPrefix(arg, "FROM:")
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			r.to = strings.TrimPrefix(arg, "TO:")
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return r
+			}
+			r.data = string(data)
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return r
+		default:
+			c.PrintfLine("502 Command not implemented")
+		}
+	}
+}
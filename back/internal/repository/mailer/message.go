@@ -0,0 +1,37 @@
+// Package mailer implements domain.Mailer: SMTPMailer sends through a mail server,
+// FileMailer and LogMailer keep letters locally for development and tests.
+package mailer
+
+import (
+	"bookvito/internal/domain"
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// buildMessage собирает письмо в формате RFC 5322: текст в UTF-8, quoted-printable
+func buildMessage(from string, mail domain.Mail, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", mail.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@bookvito>\r\n", uuid.NewString())
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(mail.Body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type accountTokenRepository struct {
+	conn
+}
+
+// NewAccountTokenRepository creates a new in-memory account token repository
+func NewAccountTokenRepository(store *Store) domain.AccountTokenRepository {
+	return &accountTokenRepository{conn{store: store}}
+}
+
+func (r *accountTokenRepository) Create(token *domain.AccountToken) error {
+	return r.do(func(t *tables) error {
+		token.ID = newID(token.ID)
+		if _, ok := t.tokens[token.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		// Уникальный индекс по token_hash
+		for _, existing := range t.tokens {
+			if existing.TokenHash == token.TokenHash {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+		if token.CreatedAt.IsZero() {
+			token.CreatedAt = now()
+		}
+		t.tokens[token.ID] = storedAccountToken(token)
+		return nil
+	})
+}
+
+func (r *accountTokenRepository) GetByHash(tokenHash string) (*domain.AccountToken, error) {
+	var token *domain.AccountToken
+	err := r.do(func(t *tables) error {
+		for _, existing := range t.tokens {
+			if existing.TokenHash == tokenHash {
+				token = &existing
+				return nil
+			}
+		}
+		return gorm.ErrRecordNotFound
+	})
+	return token, err
+}
+
+func (r *accountTokenRepository) Use(id uuid.UUID, at time.Time) error {
+	return r.do(func(t *tables) error {
+		token, ok := t.tokens[id]
+		if !ok || token.UsedAt != nil {
+			return domain.ErrInvalidAccountToken
+		}
+		token.UsedAt = copyTime(&at)
+		t.tokens[id] = token
+		return nil
+	})
+}
+
+func (r *accountTokenRepository) InvalidateByUserID(userID uuid.UUID, purpose domain.TokenPurpose, at time.Time) error {
+	return r.do(func(t *tables) error {
+		for id, token := range t.tokens {
+			if token.UserID != userID || token.Purpose != purpose || token.UsedAt != nil {
+				continue
+			}
+			token.UsedAt = copyTime(&at)
+			t.tokens[id] = token
+		}
+		return nil
+	})
+}
+
+func storedAccountToken(token *domain.AccountToken) domain.AccountToken {
+	t := *token
+	t.UsedAt = copyTime(token.UsedAt)
+	return t
+}
@@ -0,0 +1,599 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type bookRepository struct {
+	conn
+}
+
+// NewBookRepository creates a new in-memory book repository
+func NewBookRepository(store *Store) domain.BookRepository {
+	return &bookRepository{conn{store: store}}
+}
+
+func (r *bookRepository) Create(book *domain.Book) error {
+	return r.do(func(t *tables) error {
+		book.ID = newID(book.ID)
+		if _, ok := t.books[book.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		if book.Condition == "" {
+			book.Condition = domain.ConditionGood
+		}
+		if book.Status == "" {
+			book.Status = domain.BookAvailable
+		}
+		ts := now()
+		if book.CreatedAt.IsZero() {
+			book.CreatedAt = ts
+		}
+		if book.UpdatedAt.IsZero() {
+			book.UpdatedAt = ts
+		}
+		t.books[book.ID] = storedBook(book)
+		return nil
+	})
+}
+
+func (r *bookRepository) GetByID(id uuid.UUID) (*domain.Book, error) {
+	var book *domain.Book
+	err := r.do(func(t *tables) error {
+		stored, ok := t.books[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		b := storedBook(&stored)
+		b.CurrentLocation = t.location(b.CurrentLocationID)
+		b.Reviews = t.bookReviews(b.ID)
+		book = &b
+		return nil
+	})
+	return book, err
+}
+
+func (r *bookRepository) Update(book *domain.Book) error {
+	return r.do(func(t *tables) error {
+		book.UpdatedAt = now()
+		t.books[book.ID] = storedBook(book)
+		return nil
+	})
+}
+
+func (r *bookRepository) UpdateIfStatus(book *domain.Book, expected domain.BookStatus) error {
+	return r.do(func(t *tables) error {
+		current, ok := t.books[book.ID]
+		if !ok || current.Status != expected {
+			return domain.ErrBookConflict
+		}
+		book.UpdatedAt = now()
+		t.books[book.ID] = storedBook(book)
+		return nil
+	})
+}
+
+func (r *bookRepository) Delete(bookID uuid.UUID) error {
+	return r.do(func(t *tables) error {
+		if _, ok := t.books[bookID]; !ok {
+			return gorm.ErrRecordNotFound
+		}
+		delete(t.books, bookID)
+		return nil
+	})
+}
+
+func (r *bookRepository) List(page domain.PageQuery) ([]*domain.Book, error) {
+	books, err := r.filter(func(b *domain.Book) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	books = keyset(books, page, func(b *domain.Book, c *domain.Cursor) bool {
+		return afterTime(b.CreatedAt, b.ID, c, true)
+	})
+	// Как и в postgres, список содержит только поля карточки
+	for i, b := range books {
+		books[i] = &domain.Book{ID: b.ID, ImageURL: b.ImageURL, Title: b.Title, Author: b.Author, CreatedAt: b.CreatedAt}
+	}
+	return books, nil
+}
+
+func (r *bookRepository) GetSummaryList(page domain.PageQuery) ([]*domain.BookSummary, error) {
+	books, err := r.List(page)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]*domain.BookSummary, 0, len(books))
+	for _, b := range books {
+		summaries = append(summaries, &domain.BookSummary{ID: b.ID, ImageURL: b.ImageURL, Title: b.Title, Author: b.Author, CreatedAt: b.CreatedAt})
+	}
+	return summaries, nil
+}
+
+func (r *bookRepository) Count() (int64, error) {
+	var count int64
+	err := r.do(func(t *tables) error {
+		count = int64(len(t.books))
+		return nil
+	})
+	return count, err
+}
+
+// Search - упрощенный аналог полнотекстового поиска: все слова запроса должны встретиться
+// в названии, авторе или описании (без учета регистра). Стемминга и опечаток здесь нет.
+// Как и в postgres, экземпляры одного произведения схлопываются в один результат.
+func (r *bookRepository) Search(query string, limit, offset int) ([]*domain.BookSearchResult, int64, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	var results []*domain.BookSearchResult
+	err := r.do(func(t *tables) error {
+		// best - представитель произведения; bestRank - его собственная релевантность,
+		// а Rank результата - лучшая среди всех экземпляров
+		best := make(map[uuid.UUID]*domain.BookSearchResult)
+		bestRank := make(map[uuid.UUID]float64)
+		for _, b := range t.books {
+			if b.Status == domain.BookDeleted {
+				continue
+			}
+			rank, ok := searchRank(&b, terms)
+			if !ok {
+				continue
+			}
+			key := workKey(&b)
+			current, seen := best[key]
+			if seen && !betterSearchHit(&b, rank, current, bestRank[key]) {
+				current.Rank = max(current.Rank, rank)
+				continue
+			}
+			result := &domain.BookSearchResult{
+				ID:                b.ID,
+				WorkID:            copyUUID(b.WorkID),
+				Title:             b.Title,
+				Author:            b.Author,
+				ImageURL:          b.ImageURL,
+				Status:            b.Status,
+				Condition:         b.Condition,
+				CurrentLocationID: copyUUID(b.CurrentLocationID),
+				Rank:              rank,
+				Headline:          highlight(b.Title, terms),
+				Snippet:           highlight(b.Description, terms),
+			}
+			if seen {
+				result.Rank = max(current.Rank, rank)
+			}
+			best[key], bestRank[key] = result, rank
+		}
+		copies := t.workCopies()
+		for key, result := range best {
+			result.Copies = copies[key].Total
+			result.AvailableCopies = copies[key].Available
+			results = append(results, result)
+		}
+		sortBy(results, func(res *domain.BookSearchResult) uuid.UUID { return res.ID }, false, func(a, b *domain.BookSearchResult) int {
+			// Релевантные - первыми
+			return compareFloats(b.Rank, a.Rank)
+		})
+		return nil
+	})
+	total := int64(len(results))
+	return paginate(results, limit, offset), total, err
+}
+
+// betterSearchHit повторяет DISTINCT ON в postgres: свободный экземпляр лучше,
+// затем более релевантный, затем с меньшим ID
+func betterSearchHit(b *domain.Book, rank float64, current *domain.BookSearchResult, currentRank float64) bool {
+	if available := b.Status == domain.BookAvailable; available != (current.Status == domain.BookAvailable) {
+		return available
+	}
+	if rank != currentRank {
+		return rank > currentRank
+	}
+	return compareIDs(b.ID, current.ID) < 0
+}
+
+// workKey - ключ группировки экземпляров, как coalesce(work_id, id) в postgres
+func workKey(b *domain.Book) uuid.UUID {
+	if b.WorkID != nil {
+		return *b.WorkID
+	}
+	return b.ID
+}
+
+// workCopies считает экземпляры (без удаленных) по ключу workKey
+func (t *tables) workCopies() map[uuid.UUID]domain.WorkCopies {
+	copies := make(map[uuid.UUID]domain.WorkCopies)
+	for _, b := range t.books {
+		if b.Status == domain.BookDeleted {
+			continue
+		}
+		key := workKey(&b)
+		c := copies[key]
+		c.Total++
+		if b.Status == domain.BookAvailable {
+			c.Available++
+		}
+		copies[key] = c
+	}
+	return copies
+}
+
+// searchRank взвешивает совпадения как setweight в Postgres: название > автор > описание
+func searchRank(b *domain.Book, terms []string) (float64, bool) {
+	var rank float64
+	for _, term := range terms {
+		found := false
+		for _, field := range []struct {
+			text   string
+			weight float64
+		}{{b.Title, 1}, {b.Author, 0.4}, {b.Description, 0.2}} {
+			if containsFold(field.text, term) {
+				rank += field.weight
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return rank, true
+}
+
+// highlight оборачивает совпадения в <mark>, как ts_headline
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Редкие символы меняют длину при смене регистра - тогда без подсветки
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		matched := ""
+		for _, term := range terms {
+			if strings.HasPrefix(lower[i:], term) && len(term) > len(matched) {
+				matched = term
+			}
+		}
+		if matched == "" {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		b.WriteString("<mark>" + text[i:i+len(matched)] + "</mark>")
+		i += len(matched)
+	}
+	return b.String()
+}
+
+func (r *bookRepository) Filter(filter domain.BookFilter) ([]*domain.BookListItem, error) {
+	var items []*domain.BookListItem
+	err := r.do(func(t *tables) error {
+		ratings := t.bookRatings()
+		for _, b := range t.books {
+			if !matchBookFilter(&b, filter, "") {
+				continue
+			}
+			item := &domain.BookListItem{
+				ID:                b.ID,
+				WorkID:            copyUUID(b.WorkID),
+				Title:             b.Title,
+				Author:            b.Author,
+				ImageURL:          b.ImageURL,
+				Status:            b.Status,
+				Condition:         b.Condition,
+				OwnerID:           b.OwnerID,
+				CurrentLocationID: copyUUID(b.CurrentLocationID),
+				CreatedAt:         b.CreatedAt,
+			}
+			if rt, ok := ratings[workKey(&b)]; ok {
+				avg := float64(rt.sum) / float64(rt.count)
+				item.AverageRating = &avg
+				item.ReviewsCount = rt.count
+			}
+			items = append(items, item)
+		}
+		sortBookList(items, filter)
+		return nil
+	})
+	return keyset(items, domain.PageQuery{After: filter.After, Limit: filter.Limit}, func(item *domain.BookListItem, c *domain.Cursor) bool {
+		return afterBookListCursor(item, c, filter)
+	}), err
+}
+
+func (r *bookRepository) CountFiltered(filter domain.BookFilter) (int64, error) {
+	var count int64
+	err := r.do(func(t *tables) error {
+		for _, b := range t.books {
+			if matchBookFilter(&b, filter, "") {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// afterBookListCursor повторяет условие keyset каталога из postgres, включая NULL-рейтинг в конце
+func afterBookListCursor(item *domain.BookListItem, c *domain.Cursor, filter domain.BookFilter) bool {
+	var cmp int
+	switch filter.Sort {
+	case domain.BookSortTitle:
+		if c.Text == nil {
+			return true
+		}
+		cmp = strings.Compare(item.Title, *c.Text)
+	case domain.BookSortRating:
+		switch {
+		case item.AverageRating == nil && c.Number != nil:
+			return true
+		case item.AverageRating != nil && c.Number == nil:
+			return false
+		case item.AverageRating != nil:
+			cmp = compareFloats(*item.AverageRating, *c.Number)
+		}
+	default:
+		return afterTime(item.CreatedAt, item.ID, c, filter.Desc)
+	}
+	if cmp == 0 {
+		cmp = compareIDs(item.ID, c.ID)
+	}
+	if filter.Desc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+type bookRating struct {
+	sum   int64
+	count int64
+}
+
+// bookRatings считает оценки по произведениям (ключ workKey), как bookRatingsJoin в postgres
+func (t *tables) bookRatings() map[uuid.UUID]bookRating {
+	ratings := make(map[uuid.UUID]bookRating)
+	for _, rv := range t.reviews {
+		b, ok := t.books[rv.BookID]
+		if !ok {
+			continue
+		}
+		key := workKey(&b)
+		rt := ratings[key]
+		rt.sum += int64(rv.Rating)
+		rt.count++
+		ratings[key] = rt
+	}
+	return ratings
+}
+
+// sortBookList повторяет ORDER BY каталога в postgres; книги без отзывов при сортировке по рейтингу всегда в конце
+func sortBookList(items []*domain.BookListItem, filter domain.BookFilter) {
+	id := func(item *domain.BookListItem) uuid.UUID { return item.ID }
+	switch filter.Sort {
+	case domain.BookSortTitle:
+		sortBy(items, id, filter.Desc, func(a, b *domain.BookListItem) int { return strings.Compare(a.Title, b.Title) })
+	case domain.BookSortRating:
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i].AverageRating, items[j].AverageRating
+			if (a == nil) != (b == nil) {
+				return a != nil
+			}
+			c := 0
+			if a != nil {
+				c = compareFloats(*a, *b)
+			}
+			if c == 0 {
+				c = compareIDs(items[i].ID, items[j].ID)
+			}
+			if filter.Desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	default:
+		sortBy(items, id, filter.Desc, func(a, b *domain.BookListItem) int { return compareTimes(a.CreatedAt, b.CreatedAt) })
+	}
+}
+
+// bookFacet - поле, по которому считается фасет; его собственный фильтр при подсчете не применяется
+type bookFacet string
+
+const (
+	facetStatus    bookFacet = "status"
+	facetCondition bookFacet = "condition"
+	facetAuthor    bookFacet = "author"
+	facetLocation  bookFacet = "location"
+	facetOwner     bookFacet = "owner"
+)
+
+// matchBookFilter проверяет книгу по всем фильтрам, кроме фильтра по полю skip
+func matchBookFilter(b *domain.Book, filter domain.BookFilter, skip bookFacet) bool {
+	if b.Status == domain.BookDeleted {
+		return false
+	}
+	if skip != facetStatus && len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, b.Status) {
+		return false
+	}
+	if skip != facetCondition && len(filter.Conditions) > 0 && !slices.Contains(filter.Conditions, b.Condition) {
+		return false
+	}
+	if skip != facetAuthor && len(filter.Authors) > 0 && !slices.ContainsFunc(filter.Authors, func(a string) bool {
+		return strings.ToLower(a) == strings.ToLower(b.Author)
+	}) {
+		return false
+	}
+	if skip != facetLocation && len(filter.LocationIDs) > 0 &&
+		(b.CurrentLocationID == nil || !slices.Contains(filter.LocationIDs, *b.CurrentLocationID)) {
+		return false
+	}
+	if skip != facetOwner && len(filter.OwnerIDs) > 0 && !slices.Contains(filter.OwnerIDs, b.OwnerID) {
+		return false
+	}
+	if filter.CreatedFrom != nil && b.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && !b.CreatedAt.Before(*filter.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+func (r *bookRepository) Facets(filter domain.BookFilter) (*domain.BookFacets, error) {
+	var facets domain.BookFacets
+	err := r.do(func(t *tables) error {
+		facets.Status = t.bookFacet(filter, facetStatus, -1, func(b *domain.Book) (string, string, bool) {
+			return string(b.Status), "", true
+		})
+		facets.Condition = t.bookFacet(filter, facetCondition, -1, func(b *domain.Book) (string, string, bool) {
+			return string(b.Condition), "", true
+		})
+		facets.Author = t.bookFacet(filter, facetAuthor, domain.MaxFacetValues, func(b *domain.Book) (string, string, bool) {
+			return b.Author, "", true
+		})
+		// Как JOIN в postgres: книги без пункта или с несуществующим пунктом не считаются
+		facets.Location = t.bookFacet(filter, facetLocation, domain.MaxFacetValues, func(b *domain.Book) (string, string, bool) {
+			l := t.location(b.CurrentLocationID)
+			if l == nil {
+				return "", "", false
+			}
+			return l.ID.String(), l.Name, true
+		})
+		facets.Owner = t.bookFacet(filter, facetOwner, domain.MaxFacetValues, func(b *domain.Book) (string, string, bool) {
+			u := t.user(&b.OwnerID)
+			if u == nil {
+				return "", "", false
+			}
+			return u.ID.String(), u.Name, true
+		})
+		return nil
+	})
+	return &facets, err
+}
+
+// bookFacet группирует подходящие книги по значению из key: частые значения первыми, при равенстве - по значению
+func (t *tables) bookFacet(filter domain.BookFilter, skip bookFacet, limit int, key func(b *domain.Book) (value, label string, ok bool)) []domain.FacetCount {
+	index := make(map[string]int)
+	counts := []domain.FacetCount{}
+	for _, b := range t.books {
+		if !matchBookFilter(&b, filter, skip) {
+			continue
+		}
+		value, label, ok := key(&b)
+		if !ok {
+			continue
+		}
+		i, seen := index[value]
+		if !seen {
+			i = len(counts)
+			index[value] = i
+			counts = append(counts, domain.FacetCount{Value: value, Label: label})
+		}
+		counts[i].Count++
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	return paginate(counts, limit, 0)
+}
+
+func (r *bookRepository) GetByStatus(status domain.BookStatus, limit, offset int) ([]*domain.Book, error) {
+	books, err := r.filter(func(b *domain.Book) bool { return b.Status == status })
+	return paginate(books, limit, offset), err
+}
+
+func (r *bookRepository) GetByLocationID(locationID uuid.UUID) ([]*domain.Book, error) {
+	return r.filter(func(b *domain.Book) bool {
+		return b.CurrentLocationID != nil && *b.CurrentLocationID == locationID
+	})
+}
+
+func (r *bookRepository) GetByWorkID(workID uuid.UUID) ([]*domain.Book, error) {
+	return r.filter(func(b *domain.Book) bool {
+		return b.WorkID != nil && *b.WorkID == workID && b.Status != domain.BookDeleted
+	})
+}
+
+func (r *bookRepository) GetByOwnerID(ownerID uuid.UUID) ([]*domain.Book, error) {
+	return r.filter(func(b *domain.Book) bool {
+		return b.OwnerID == ownerID && b.Status != domain.BookDeleted
+	})
+}
+
+// filter возвращает подходящие книги от новых к старым с подгруженным CurrentLocation
+func (r *bookRepository) filter(match func(b *domain.Book) bool) ([]*domain.Book, error) {
+	var books []*domain.Book
+	err := r.do(func(t *tables) error {
+		for _, stored := range t.books {
+			if !match(&stored) {
+				continue
+			}
+			b := storedBook(&stored)
+			b.CurrentLocation = t.location(b.CurrentLocationID)
+			books = append(books, &b)
+		}
+		sortBy(books, func(b *domain.Book) uuid.UUID { return b.ID }, true, func(a, b *domain.Book) int {
+			return compareTimes(a.CreatedAt, b.CreatedAt)
+		})
+		return nil
+	})
+	return books, err
+}
+
+// storedBook копирует книгу без связей; используется и при записи, и при чтении,
+// чтобы вызывающий код не разделял указатели с хранилищем
+func storedBook(book *domain.Book) domain.Book {
+	b := *book
+	b.CurrentLocationID = copyUUID(book.CurrentLocationID)
+	b.WorkID = copyUUID(book.WorkID)
+	b.ImageID = copyUUID(book.ImageID)
+	b.Owner = nil
+	b.CurrentLocation = nil
+	b.Reviews = nil
+	b.Exchanges = nil
+	return b
+}
+
+// location подгружает связь по необязательному внешнему ключу
+func (t *tables) location(id *uuid.UUID) *domain.Location {
+	if id == nil {
+		return nil
+	}
+	l, ok := t.locations[*id]
+	if !ok {
+		return nil
+	}
+	return &l
+}
+
+func (t *tables) book(id *uuid.UUID) *domain.Book {
+	if id == nil {
+		return nil
+	}
+	stored, ok := t.books[*id]
+	if !ok {
+		return nil
+	}
+	b := storedBook(&stored)
+	return &b
+}
+
+func (t *tables) user(id *uuid.UUID) *domain.User {
+	if id == nil {
+		return nil
+	}
+	u, ok := t.users[*id]
+	if !ok {
+		return nil
+	}
+	return &u
+}
@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"bookvito/internal/repository/contract"
+	"testing"
+)
+
+func TestContract(t *testing.T) {
+	contract.Run(t, func(t *testing.T) contract.Backend {
+		store := NewStore()
+		return contract.Backend{Repos: NewRepositories(store), UnitOfWork: NewUnitOfWork(store)}
+	})
+}
@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type exchangeRepository struct {
+	conn
+}
+
+// NewExchangeRepository creates a new in-memory exchange repository
+func NewExchangeRepository(store *Store) domain.ExchangeRepository {
+	return &exchangeRepository{conn{store: store}}
+}
+
+func (r *exchangeRepository) Create(exchange *domain.Exchange) error {
+	return r.do(func(t *tables) error {
+		exchange.ID = newID(exchange.ID)
+		if _, ok := t.exchanges[exchange.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		if exchange.Status == "" {
+			exchange.Status = domain.ExchangeRequested
+		}
+		if exchange.BookedAt.IsZero() {
+			exchange.BookedAt = now()
+		}
+		t.exchanges[exchange.ID] = storedExchange(exchange)
+		return nil
+	})
+}
+
+func (r *exchangeRepository) GetByID(id uuid.UUID) (*domain.Exchange, error) {
+	var exchange *domain.Exchange
+	err := r.do(func(t *tables) error {
+		stored, ok := t.exchanges[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		e := storedExchange(&stored)
+		t.preloadExchange(&e, true, true)
+		exchange = &e
+		return nil
+	})
+	return exchange, err
+}
+
+func (r *exchangeRepository) GetByBookID(bookID uuid.UUID) ([]*domain.Exchange, error) {
+	return r.filter(true, false, newestExchangesFirst, func(e *domain.Exchange) bool { return e.BookID == bookID })
+}
+
+func (r *exchangeRepository) ListByUserID(userID uuid.UUID, page domain.PageQuery) ([]*domain.Exchange, error) {
+	exchanges, err := r.filter(false, true, newestExchangesFirst, func(e *domain.Exchange) bool { return e.UserID == userID })
+	return keyset(exchanges, page, afterExchange), err
+}
+
+func (r *exchangeRepository) ListByBookID(bookID uuid.UUID, page domain.PageQuery) ([]*domain.Exchange, error) {
+	exchanges, err := r.GetByBookID(bookID)
+	return keyset(exchanges, page, afterExchange), err
+}
+
+func (r *exchangeRepository) CountByUserID(userID uuid.UUID) (int64, error) {
+	return r.count(func(e *domain.Exchange) bool { return e.UserID == userID })
+}
+
+func (r *exchangeRepository) CountByBookID(bookID uuid.UUID) (int64, error) {
+	return r.count(func(e *domain.Exchange) bool { return e.BookID == bookID })
+}
+
+func (r *exchangeRepository) Update(exchange *domain.Exchange) error {
+	return r.do(func(t *tables) error {
+		t.exchanges[exchange.ID] = storedExchange(exchange)
+		return nil
+	})
+}
+
+func (r *exchangeRepository) UpdateIfStatus(exchange *domain.Exchange, expected domain.ExchangeStatus) error {
+	return r.do(func(t *tables) error {
+		current, ok := t.exchanges[exchange.ID]
+		if !ok || current.Status != expected {
+			return domain.ErrExchangeConflict
+		}
+		t.exchanges[exchange.ID] = storedExchange(exchange)
+		return nil
+	})
+}
+
+func (r *exchangeRepository) Delete(id uuid.UUID) error {
+	return r.do(func(t *tables) error {
+		delete(t.exchanges, id)
+		return nil
+	})
+}
+
+func (r *exchangeRepository) List(page domain.PageQuery) ([]*domain.Exchange, error) {
+	exchanges, err := r.filter(true, true, newestExchangesFirst, func(e *domain.Exchange) bool { return true })
+	return keyset(exchanges, page, afterExchange), err
+}
+
+// afterExchange - позиция в списках от новых бронирований к старым
+func afterExchange(e *domain.Exchange, c *domain.Cursor) bool {
+	return afterTime(e.BookedAt, e.ID, c, true)
+}
+
+func (r *exchangeRepository) GetExpired() ([]*domain.Exchange, error) {
+	current := time.Now()
+	return r.filter(false, false, byExpiresAt, func(e *domain.Exchange) bool {
+		return e.Status == domain.ExchangeRequested && e.ExpiresAt != nil && e.ExpiresAt.Before(current)
+	})
+}
+
+func (r *exchangeRepository) GetOverdue() ([]*domain.Exchange, error) {
+	current := time.Now()
+	return r.filter(false, false, byDueAt, func(e *domain.Exchange) bool {
+		return e.Status == domain.ExchangeBorrowed && e.DueAt != nil && e.DueAt.Before(current)
+	})
+}
+
+func (r *exchangeRepository) GetByStatus(status domain.ExchangeStatus) ([]*domain.Exchange, error) {
+	return r.filter(true, true, byDueAt, func(e *domain.Exchange) bool { return e.Status == status })
+}
+
+type exchangeOrder struct {
+	desc bool
+	cmp  func(a, b *domain.Exchange) int
+}
+
+var (
+	newestExchangesFirst = exchangeOrder{desc: true, cmp: func(a, b *domain.Exchange) int {
+		return compareTimes(a.BookedAt, b.BookedAt)
+	}}
+	byExpiresAt = exchangeOrder{cmp: func(a, b *domain.Exchange) int {
+		return compareNullableTimes(a.ExpiresAt, b.ExpiresAt)
+	}}
+	byDueAt = exchangeOrder{cmp: func(a, b *domain.Exchange) int {
+		return compareNullableTimes(a.DueAt, b.DueAt)
+	}}
+)
+
+// filter возвращает подходящие бронирования в порядке order; withUser/withBook повторяют Preload
+func (r *exchangeRepository) filter(withUser, withBook bool, order exchangeOrder, match func(e *domain.Exchange) bool) ([]*domain.Exchange, error) {
+	var exchanges []*domain.Exchange
+	err := r.do(func(t *tables) error {
+		for _, stored := range t.exchanges {
+			if !match(&stored) {
+				continue
+			}
+			e := storedExchange(&stored)
+			t.preloadExchange(&e, withUser, withBook)
+			exchanges = append(exchanges, &e)
+		}
+		sortBy(exchanges, func(e *domain.Exchange) uuid.UUID { return e.ID }, order.desc, order.cmp)
+		return nil
+	})
+	return exchanges, err
+}
+
+func (r *exchangeRepository) count(match func(e *domain.Exchange) bool) (int64, error) {
+	var count int64
+	err := r.do(func(t *tables) error {
+		for _, e := range t.exchanges {
+			if match(&e) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// preloadExchange подгружает User, Book и Location
+func (t *tables) preloadExchange(e *domain.Exchange, withUser, withBook bool) {
+	if withUser {
+		if u := t.user(&e.UserID); u != nil {
+			e.User = *u
+		}
+	}
+	if withBook {
+		if b := t.book(&e.BookID); b != nil {
+			e.Book = *b
+		}
+	}
+	e.Location = t.location(e.LocationID)
+}
+
+// storedExchange копирует бронирование без связей и с собственными копиями указателей
+func storedExchange(exchange *domain.Exchange) domain.Exchange {
+	e := *exchange
+	e.ExpiresAt = copyTime(exchange.ExpiresAt)
+	e.BorrowedAt = copyTime(exchange.BorrowedAt)
+	e.DueAt = copyTime(exchange.DueAt)
+	e.LocationID = copyUUID(exchange.LocationID)
+	e.User = domain.User{}
+	e.Book = domain.Book{}
+	e.Location = nil
+	return e
+}
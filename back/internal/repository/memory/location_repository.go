@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type locationRepository struct {
+	conn
+}
+
+// NewLocationRepository creates a new in-memory location repository
+func NewLocationRepository(store *Store) domain.LocationRepository {
+	return &locationRepository{conn{store: store}}
+}
+
+func (r *locationRepository) Create(location *domain.Location) error {
+	// Как и в postgres: пункт с тем же адресом получает его ID, и вставка падает на первичном ключе
+	existing, err := r.GetByAddress(location.Address)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if existing != nil {
+		location.ID = existing.ID
+	}
+
+	return r.do(func(t *tables) error {
+		location.ID = newID(location.ID)
+		if _, ok := t.locations[location.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		t.locations[location.ID] = storedLocation(location)
+		return nil
+	})
+}
+
+func (r *locationRepository) GetByID(id uuid.UUID) (*domain.Location, error) {
+	return r.find(func(l *domain.Location) bool { return l.ID == id })
+}
+
+func (r *locationRepository) GetByAddress(address string) (*domain.Location, error) {
+	return r.find(func(l *domain.Location) bool { return l.Address == address })
+}
+
+func (r *locationRepository) GetAll() ([]domain.Location, error) {
+	var locations []domain.Location
+	err := r.do(func(t *tables) error {
+		for _, l := range t.locations {
+			l.Books = t.locationBooks(l.ID)
+			locations = append(locations, l)
+		}
+		sortBy(locations, func(l domain.Location) uuid.UUID { return l.ID }, false, func(a, b domain.Location) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		return nil
+	})
+	return locations, err
+}
+
+func (r *locationRepository) Update(location *domain.Location) error {
+	return r.do(func(t *tables) error {
+		t.locations[location.ID] = storedLocation(location)
+		return nil
+	})
+}
+
+func (r *locationRepository) Delete(id uuid.UUID) error {
+	return r.do(func(t *tables) error {
+		if _, ok := t.locations[id]; !ok {
+			return gorm.ErrRecordNotFound
+		}
+		delete(t.locations, id)
+		return nil
+	})
+}
+
+func (r *locationRepository) find(match func(l *domain.Location) bool) (*domain.Location, error) {
+	var location *domain.Location
+	err := r.do(func(t *tables) error {
+		// Как First в GORM: из нескольких подходящих берем строку с наименьшим id
+		for _, l := range t.locations {
+			if match(&l) && (location == nil || compareIDs(l.ID, location.ID) < 0) {
+				location = &l
+			}
+		}
+		if location == nil {
+			return gorm.ErrRecordNotFound
+		}
+		location.Books = t.locationBooks(location.ID)
+		return nil
+	})
+	return location, err
+}
+
+// locationBooks подгружает книги, находящиеся в пункте выдачи
+func (t *tables) locationBooks(locationID uuid.UUID) []domain.Book {
+	var books []domain.Book
+	for _, stored := range t.books {
+		if stored.CurrentLocationID != nil && *stored.CurrentLocationID == locationID {
+			books = append(books, storedBook(&stored))
+		}
+	}
+	sortBy(books, func(b domain.Book) uuid.UUID { return b.ID }, true, func(a, b domain.Book) int {
+		return compareTimes(a.CreatedAt, b.CreatedAt)
+	})
+	return books
+}
+
+func storedLocation(location *domain.Location) domain.Location {
+	l := *location
+	l.Books = nil
+	return l
+}
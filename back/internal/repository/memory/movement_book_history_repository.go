@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type bookMovementHistoryRepository struct {
+	conn
+}
+
+// NewBookMovementHistoryRepository creates a new in-memory book movement history repository
+func NewBookMovementHistoryRepository(store *Store) domain.BookMovementHistoryRepository {
+	return &bookMovementHistoryRepository{conn{store: store}}
+}
+
+// Create creates a new book movement history record
+func (r *bookMovementHistoryRepository) Create(movement *domain.BookMovementHistory) error {
+	return r.do(func(t *tables) error {
+		movement.ID = newID(movement.ID)
+		if _, ok := t.movements[movement.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		if movement.CreatedAt.IsZero() {
+			movement.CreatedAt = now()
+		}
+		t.movements[movement.ID] = storedMovement(movement)
+		return nil
+	})
+}
+
+// Update updates an existing book movement history record
+func (r *bookMovementHistoryRepository) Update(movement *domain.BookMovementHistory) error {
+	return r.do(func(t *tables) error {
+		t.movements[movement.ID] = storedMovement(movement)
+		return nil
+	})
+}
+
+// GetByID retrieves a book movement history record by ID
+func (r *bookMovementHistoryRepository) GetByID(id uuid.UUID) (*domain.BookMovementHistory, error) {
+	movements, err := r.filter(func(m *domain.BookMovementHistory) bool { return m.ID == id })
+	if err != nil {
+		return nil, err
+	}
+	if len(movements) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return movements[0], nil
+}
+
+// GetByBookID retrieves all movement history for a specific book
+func (r *bookMovementHistoryRepository) GetByBookID(bookID uuid.UUID) ([]*domain.BookMovementHistory, error) {
+	return r.filter(func(m *domain.BookMovementHistory) bool { return m.BookID == bookID })
+}
+
+// GetByExchangeID retrieves all movement history for a specific exchange
+func (r *bookMovementHistoryRepository) GetByExchangeID(exchangeID uuid.UUID) ([]*domain.BookMovementHistory, error) {
+	return r.filter(func(m *domain.BookMovementHistory) bool {
+		return m.ExchangeID != nil && *m.ExchangeID == exchangeID
+	})
+}
+
+// GetByUserID retrieves all movement history initiated by a specific user
+func (r *bookMovementHistoryRepository) GetByUserID(userID uuid.UUID) ([]*domain.BookMovementHistory, error) {
+	return r.filter(func(m *domain.BookMovementHistory) bool {
+		return m.UserID != nil && *m.UserID == userID
+	})
+}
+
+// ListByBookID retrieves a page of movement history for a specific book
+func (r *bookMovementHistoryRepository) ListByBookID(bookID uuid.UUID, page domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+	movements, err := r.GetByBookID(bookID)
+	return keyset(movements, page, afterMovement), err
+}
+
+// ListByUserID retrieves a page of movement history initiated by a specific user
+func (r *bookMovementHistoryRepository) ListByUserID(userID uuid.UUID, page domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+	movements, err := r.GetByUserID(userID)
+	return keyset(movements, page, afterMovement), err
+}
+
+// List retrieves a page of movement history
+func (r *bookMovementHistoryRepository) List(page domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+	movements, err := r.filter(func(m *domain.BookMovementHistory) bool { return true })
+	return keyset(movements, page, afterMovement), err
+}
+
+// CountByBookID counts movement history records of a specific book
+func (r *bookMovementHistoryRepository) CountByBookID(bookID uuid.UUID) (int64, error) {
+	movements, err := r.GetByBookID(bookID)
+	return int64(len(movements)), err
+}
+
+// CountByUserID counts movement history records initiated by a specific user
+func (r *bookMovementHistoryRepository) CountByUserID(userID uuid.UUID) (int64, error) {
+	movements, err := r.GetByUserID(userID)
+	return int64(len(movements)), err
+}
+
+// afterMovement - позиция в истории от новых записей к старым
+func afterMovement(m *domain.BookMovementHistory, c *domain.Cursor) bool {
+	return afterTime(m.CreatedAt, m.ID, c, true)
+}
+
+// filter возвращает подходящие записи от новых к старым со всеми связями
+func (r *bookMovementHistoryRepository) filter(match func(m *domain.BookMovementHistory) bool) ([]*domain.BookMovementHistory, error) {
+	var movements []*domain.BookMovementHistory
+	err := r.do(func(t *tables) error {
+		for _, stored := range t.movements {
+			if !match(&stored) {
+				continue
+			}
+			m := storedMovement(&stored)
+			m.Book = t.book(&m.BookID)
+			m.FromLocation = t.location(m.FromLocationID)
+			m.ToLocation = t.location(m.ToLocationID)
+			m.User = t.user(m.UserID)
+			if m.ExchangeID != nil {
+				if e, ok := t.exchanges[*m.ExchangeID]; ok {
+					e = storedExchange(&e)
+					m.Exchange = &e
+				}
+			}
+			movements = append(movements, &m)
+		}
+		sortBy(movements, func(m *domain.BookMovementHistory) uuid.UUID { return m.ID }, true, func(a, b *domain.BookMovementHistory) int {
+			return compareTimes(a.CreatedAt, b.CreatedAt)
+		})
+		return nil
+	})
+	return movements, err
+}
+
+func storedMovement(movement *domain.BookMovementHistory) domain.BookMovementHistory {
+	m := *movement
+	m.FromLocationID = copyUUID(movement.FromLocationID)
+	m.ToLocationID = copyUUID(movement.ToLocationID)
+	m.ExchangeID = copyUUID(movement.ExchangeID)
+	m.UserID = copyUUID(movement.UserID)
+	m.Book = nil
+	m.FromLocation = nil
+	m.ToLocation = nil
+	m.Exchange = nil
+	m.User = nil
+	return m
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type reviewRepository struct {
+	conn
+}
+
+// NewReviewRepository creates a new in-memory review repository
+func NewReviewRepository(store *Store) domain.ReviewRepository {
+	return &reviewRepository{conn{store: store}}
+}
+
+func (r *reviewRepository) Create(review *domain.Review) error {
+	return r.do(func(t *tables) error {
+		review.ID = newID(review.ID)
+		if _, ok := t.reviews[review.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		if err := checkUniqueReview(t, review); err != nil {
+			return err
+		}
+		if review.CreatedAt.IsZero() {
+			review.CreatedAt = now()
+		}
+		t.reviews[review.ID] = storedReview(review)
+		return nil
+	})
+}
+
+func (r *reviewRepository) GetByID(id uuid.UUID) (*domain.Review, error) {
+	var review *domain.Review
+	err := r.do(func(t *tables) error {
+		rv, ok := t.reviews[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		if b := t.book(&rv.BookID); b != nil {
+			rv.Book = *b
+		}
+		if u := t.user(&rv.UserID); u != nil {
+			rv.User = *u
+		}
+		review = &rv
+		return nil
+	})
+	return review, err
+}
+
+func (r *reviewRepository) GetByBookID(bookID uuid.UUID) ([]domain.Review, error) {
+	var reviews []domain.Review
+	err := r.do(func(t *tables) error {
+		for _, rv := range t.bookReviews(bookID) {
+			if u := t.user(&rv.UserID); u != nil {
+				rv.User = *u
+			}
+			reviews = append(reviews, rv)
+		}
+		return nil
+	})
+	return reviews, err
+}
+
+func (r *reviewRepository) GetByWorkID(workID uuid.UUID) ([]domain.Review, error) {
+	var reviews []domain.Review
+	err := r.do(func(t *tables) error {
+		for _, rv := range t.reviews {
+			b, ok := t.books[rv.BookID]
+			if !ok || b.WorkID == nil || *b.WorkID != workID {
+				continue
+			}
+			if u := t.user(&rv.UserID); u != nil {
+				rv.User = *u
+			}
+			reviews = append(reviews, rv)
+		}
+		sortReviews(reviews)
+		return nil
+	})
+	return reviews, err
+}
+
+func (r *reviewRepository) GetByUserID(userID uuid.UUID) ([]domain.Review, error) {
+	var reviews []domain.Review
+	err := r.do(func(t *tables) error {
+		for _, rv := range t.reviews {
+			if rv.UserID != userID {
+				continue
+			}
+			if b := t.book(&rv.BookID); b != nil {
+				rv.Book = *b
+			}
+			reviews = append(reviews, rv)
+		}
+		sortReviews(reviews)
+		return nil
+	})
+	return reviews, err
+}
+
+func (r *reviewRepository) Update(review *domain.Review) error {
+	return r.do(func(t *tables) error {
+		if err := checkUniqueReview(t, review); err != nil {
+			return err
+		}
+		t.reviews[review.ID] = storedReview(review)
+		return nil
+	})
+}
+
+func (r *reviewRepository) Delete(id uuid.UUID) error {
+	return r.do(func(t *tables) error {
+		delete(t.reviews, id)
+		return nil
+	})
+}
+
+// bookReviews возвращает отзывы на книгу от новых к старым
+func (t *tables) bookReviews(bookID uuid.UUID) []domain.Review {
+	var reviews []domain.Review
+	for _, rv := range t.reviews {
+		if rv.BookID == bookID {
+			reviews = append(reviews, rv)
+		}
+	}
+	sortReviews(reviews)
+	return reviews
+}
+
+func sortReviews(reviews []domain.Review) {
+	sortBy(reviews, func(rv domain.Review) uuid.UUID { return rv.ID }, true, func(a, b domain.Review) int {
+		return compareTimes(a.CreatedAt, b.CreatedAt)
+	})
+}
+
+// checkUniqueReview повторяет уникальный индекс idx_reviews_book_user
+func checkUniqueReview(t *tables, review *domain.Review) error {
+	for id, rv := range t.reviews {
+		if id != review.ID && rv.BookID == review.BookID && rv.UserID == review.UserID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+func storedReview(review *domain.Review) domain.Review {
+	rv := *review
+	rv.Book = domain.Book{}
+	rv.User = domain.User{}
+	return rv
+}
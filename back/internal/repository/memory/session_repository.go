@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	conn
+}
+
+// NewSessionRepository creates a new in-memory session repository
+func NewSessionRepository(store *Store) domain.SessionRepository {
+	return &sessionRepository{conn{store: store}}
+}
+
+func (r *sessionRepository) Create(session *domain.Session) error {
+	return r.do(func(t *tables) error {
+		session.ID = newID(session.ID)
+		if _, ok := t.sessions[session.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		if session.CreatedAt.IsZero() {
+			session.CreatedAt = now()
+		}
+		t.sessions[session.ID] = storedSession(session)
+		return nil
+	})
+}
+
+func (r *sessionRepository) GetByID(id uuid.UUID) (*domain.Session, error) {
+	var session *domain.Session
+	err := r.do(func(t *tables) error {
+		s, ok := t.sessions[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		session = &s
+		return nil
+	})
+	return session, err
+}
+
+func (r *sessionRepository) Rotate(session *domain.Session, previousHash string) error {
+	return r.do(func(t *tables) error {
+		current, ok := t.sessions[session.ID]
+		if !ok || current.TokenHash != previousHash || current.RevokedAt != nil {
+			return domain.ErrRefreshTokenReused
+		}
+		current.TokenHash = session.TokenHash
+		current.IP = session.IP
+		current.LastUsedAt = session.LastUsedAt
+		current.ExpiresAt = session.ExpiresAt
+		t.sessions[session.ID] = current
+		return nil
+	})
+}
+
+func (r *sessionRepository) Revoke(id uuid.UUID, at time.Time) error {
+	return r.revoke(func(s *domain.Session) bool { return s.ID == id }, at)
+}
+
+func (r *sessionRepository) RevokeByUserID(userID uuid.UUID, at time.Time) error {
+	return r.revoke(func(s *domain.Session) bool { return s.UserID == userID }, at)
+}
+
+func (r *sessionRepository) revoke(match func(s *domain.Session) bool, at time.Time) error {
+	return r.do(func(t *tables) error {
+		for id, s := range t.sessions {
+			if s.RevokedAt != nil || !match(&s) {
+				continue
+			}
+			s.RevokedAt = copyTime(&at)
+			t.sessions[id] = s
+		}
+		return nil
+	})
+}
+
+func (r *sessionRepository) ListActiveByUserID(userID uuid.UUID, at time.Time) ([]*domain.Session, error) {
+	var sessions []*domain.Session
+	err := r.do(func(t *tables) error {
+		for _, s := range t.sessions {
+			if s.UserID == userID && s.Active(at) {
+				sessions = append(sessions, &s)
+			}
+		}
+		sortBy(sessions, func(s *domain.Session) uuid.UUID { return s.ID }, false, func(a, b *domain.Session) int {
+			return compareTimes(b.LastUsedAt, a.LastUsedAt)
+		})
+		return nil
+	})
+	return sessions, err
+}
+
+func storedSession(session *domain.Session) domain.Session {
+	s := *session
+	s.RevokedAt = copyTime(session.RevokedAt)
+	s.Current = false
+	return s
+}
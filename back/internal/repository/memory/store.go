@@ -0,0 +1,217 @@
+// Package memory implements the domain repositories in process memory.
+// It mirrors the behaviour of the postgres package (ordering, pagination,
+// not found errors, unique constraints) and is meant for tests and local runs.
+package memory
+
+import (
+	"bookvito/internal/domain"
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store хранит все таблицы. Репозитории, созданные от одного Store, видят одни и те же данные.
+type Store struct {
+	mu     sync.Mutex
+	tables *tables
+}
+
+type tables struct {
+	users     map[uuid.UUID]domain.User
+	sessions  map[uuid.UUID]domain.Session
+	tokens    map[uuid.UUID]domain.AccountToken
+	books     map[uuid.UUID]domain.Book
+	works     map[uuid.UUID]domain.Work
+	exchanges map[uuid.UUID]domain.Exchange
+	locations map[uuid.UUID]domain.Location
+	reviews   map[uuid.UUID]domain.Review
+	movements map[uuid.UUID]domain.BookMovementHistory
+	waitlist  map[uuid.UUID]domain.WaitlistEntry
+	uploads   map[uuid.UUID]domain.Upload
+}
+
+// NewStore creates an empty in-memory store
+func NewStore() *Store {
+	return &Store{tables: newTables()}
+}
+
+func newTables() *tables {
+	return &tables{
+		users:     make(map[uuid.UUID]domain.User),
+		sessions:  make(map[uuid.UUID]domain.Session),
+		tokens:    make(map[uuid.UUID]domain.AccountToken),
+		books:     make(map[uuid.UUID]domain.Book),
+		works:     make(map[uuid.UUID]domain.Work),
+		exchanges: make(map[uuid.UUID]domain.Exchange),
+		locations: make(map[uuid.UUID]domain.Location),
+		reviews:   make(map[uuid.UUID]domain.Review),
+		movements: make(map[uuid.UUID]domain.BookMovementHistory),
+		waitlist:  make(map[uuid.UUID]domain.WaitlistEntry),
+		uploads:   make(map[uuid.UUID]domain.Upload),
+	}
+}
+
+// snapshot копирует таблицы для отката транзакции. Строки хранятся без связей
+// и с собственными копиями указателей, поэтому достаточно скопировать карты.
+func (t *tables) snapshot() *tables {
+	return &tables{
+		users:     cloneMap(t.users),
+		sessions:  cloneMap(t.sessions),
+		tokens:    cloneMap(t.tokens),
+		books:     cloneMap(t.books),
+		works:     cloneMap(t.works),
+		exchanges: cloneMap(t.exchanges),
+		locations: cloneMap(t.locations),
+		reviews:   cloneMap(t.reviews),
+		movements: cloneMap(t.movements),
+		waitlist:  cloneMap(t.waitlist),
+		uploads:   cloneMap(t.uploads),
+	}
+}
+
+func cloneMap[T any](m map[uuid.UUID]T) map[uuid.UUID]T {
+	clone := make(map[uuid.UUID]T, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// conn - доступ репозитория к Store. Вне транзакции каждый вызов берет блокировку сам,
+// внутри UnitOfWork.Do блокировку уже держит транзакция.
+type conn struct {
+	store *Store
+	inTx  bool
+}
+
+func (c conn) do(fn func(t *tables) error) error {
+	if !c.inTx {
+		c.store.mu.Lock()
+		defer c.store.mu.Unlock()
+	}
+	return fn(c.store.tables)
+}
+
+// now возвращает время с точностью Postgres (микросекунды)
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func newID(id uuid.UUID) uuid.UUID {
+	if id == uuid.Nil {
+		return uuid.New()
+	}
+	return id
+}
+
+func copyUUID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	v := *id
+	return &v
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
+
+// paginate повторяет LIMIT/OFFSET из GORM: отрицательный limit - без ограничения
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(items) {
+			return items[:0]
+		}
+		items = items[offset:]
+	}
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// keyset повторяет keyset-пагинацию postgres: items уже упорядочены, записи до курсора
+// включительно пропускаются. after сообщает, стоит ли запись строго после курсора.
+func keyset[T any](items []T, page domain.PageQuery, after func(item T, c *domain.Cursor) bool) []T {
+	if page.After != nil {
+		i := 0
+		for i < len(items) && !after(items[i], page.After) {
+			i++
+		}
+		items = items[i:]
+	}
+	return paginate(items, page.Limit, 0)
+}
+
+// afterTime - after для списков, упорядоченных по (время, id)
+func afterTime(t time.Time, id uuid.UUID, c *domain.Cursor, desc bool) bool {
+	if c.Time == nil {
+		return true
+	}
+	cmp := compareTimes(t, *c.Time)
+	if cmp == 0 {
+		cmp = compareIDs(id, c.ID)
+	}
+	if desc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+// Порядок сортировки совпадает с Postgres: uuid сравнивается побайтно, NULL идет после значений
+
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+func compareTimes(a, b time.Time) int {
+	return a.Compare(b)
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareNullableTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// sortBy сортирует по ключу cmp, при равенстве - по id в том же направлении
+func sortBy[T any](items []T, id func(T) uuid.UUID, desc bool, cmp func(a, b T) int) {
+	sort.SliceStable(items, func(i, j int) bool {
+		c := cmp(items[i], items[j])
+		if c == 0 {
+			c = compareIDs(id(items[i]), id(items[j]))
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,54 @@
+package memory
+
+import "bookvito/internal/domain"
+
+type unitOfWork struct {
+	store *Store
+}
+
+// NewUnitOfWork creates a unit of work over the store. Транзакции выполняются по одной
+// (как SERIALIZABLE), при ошибке fn все изменения откатываются.
+func NewUnitOfWork(store *Store) domain.UnitOfWork {
+	return &unitOfWork{store: store}
+}
+
+// Do runs fn with exclusive access to the store; repositories passed to fn share the transaction
+func (u *unitOfWork) Do(fn func(repos *domain.Repositories) error) (err error) {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	snapshot := u.store.tables.snapshot()
+	defer func() {
+		// Откатываем и при панике, чтобы Store не остался в промежуточном состоянии
+		if r := recover(); r != nil {
+			u.store.tables = snapshot
+			panic(r)
+		}
+		if err != nil {
+			u.store.tables = snapshot
+		}
+	}()
+
+	return fn(newRepositories(conn{store: u.store, inTx: true}))
+}
+
+// NewRepositories creates repositories that work with the store outside of a transaction
+func NewRepositories(store *Store) *domain.Repositories {
+	return newRepositories(conn{store: store})
+}
+
+func newRepositories(c conn) *domain.Repositories {
+	return &domain.Repositories{
+		Users:     &userRepository{c},
+		Sessions:  &sessionRepository{c},
+		Tokens:    &accountTokenRepository{c},
+		Books:     &bookRepository{c},
+		Works:     &workRepository{c},
+		Exchanges: &exchangeRepository{c},
+		Locations: &locationRepository{c},
+		Reviews:   &reviewRepository{c},
+		Movements: &bookMovementHistoryRepository{c},
+		Waitlist:  &waitlistRepository{c},
+		Uploads:   &uploadRepository{c},
+	}
+}
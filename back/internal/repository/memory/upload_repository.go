@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type uploadRepository struct {
+	conn
+}
+
+// NewUploadRepository creates a new in-memory upload repository
+func NewUploadRepository(store *Store) domain.UploadRepository {
+	return &uploadRepository{conn{store: store}}
+}
+
+func (r *uploadRepository) Create(upload *domain.Upload) error {
+	return r.do(func(t *tables) error {
+		upload.ID = newID(upload.ID)
+		if _, ok := t.uploads[upload.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		if upload.CreatedAt.IsZero() {
+			upload.CreatedAt = now()
+		}
+		t.uploads[upload.ID] = storedUpload(upload)
+		return nil
+	})
+}
+
+func (r *uploadRepository) GetByID(id uuid.UUID) (*domain.Upload, error) {
+	var upload *domain.Upload
+	err := r.do(func(t *tables) error {
+		u, ok := t.uploads[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		upload = &u
+		return nil
+	})
+	return upload, err
+}
+
+// storedUpload отбрасывает адреса: в таблице их нет, их заполняет use case
+func storedUpload(upload *domain.Upload) domain.Upload {
+	u := *upload
+	u.URL = ""
+	u.ThumbURL = ""
+	return u
+}
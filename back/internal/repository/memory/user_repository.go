@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type userRepository struct {
+	conn
+}
+
+// NewUserRepository creates a new in-memory user repository
+func NewUserRepository(store *Store) domain.UserRepository {
+	return &userRepository{conn{store: store}}
+}
+
+func (r *userRepository) Create(user *domain.User) error {
+	return r.do(func(t *tables) error {
+		user.ID = newID(user.ID)
+		if _, ok := t.users[user.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		if err := checkUniqueEmail(t, user); err != nil {
+			return err
+		}
+		if user.Role == "" {
+			user.Role = domain.RoleUser
+		}
+		if user.CreatedAt.IsZero() {
+			user.CreatedAt = now()
+		}
+		t.users[user.ID] = storedUser(user)
+		return nil
+	})
+}
+
+func (r *userRepository) GetByID(id uuid.UUID) (*domain.User, error) {
+	var user *domain.User
+	err := r.do(func(t *tables) error {
+		u, ok := t.users[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		user = &u
+		return nil
+	})
+	return user, err
+}
+
+func (r *userRepository) GetByEmail(email string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Email == email })
+}
+
+func (r *userRepository) Update(user *domain.User) error {
+	return r.do(func(t *tables) error {
+		if err := checkUniqueEmail(t, user); err != nil {
+			return err
+		}
+		u := storedUser(user)
+		// Роль и блокировку меняют только UpdateRole и UpdateBan
+		if stored, ok := t.users[user.ID]; ok {
+			u.Role = stored.Role
+			u.UserBan = stored.UserBan
+		}
+		t.users[user.ID] = u
+		return nil
+	})
+}
+
+func (r *userRepository) UpdateRole(id uuid.UUID, role domain.UserRole) error {
+	return r.do(func(t *tables) error {
+		u, ok := t.users[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		u.Role = role
+		t.users[id] = u
+		return nil
+	})
+}
+
+func (r *userRepository) UpdateBan(id uuid.UUID, ban domain.UserBan) error {
+	return r.do(func(t *tables) error {
+		u, ok := t.users[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		u.UserBan = storedBan(ban)
+		t.users[id] = u
+		return nil
+	})
+}
+
+func (r *userRepository) Delete(id uuid.UUID) error {
+	return r.do(func(t *tables) error {
+		delete(t.users, id)
+		return nil
+	})
+}
+
+func (r *userRepository) List(filter domain.UserFilter, page domain.PageQuery) ([]*domain.User, error) {
+	var users []*domain.User
+	err := r.do(func(t *tables) error {
+		for _, u := range t.users {
+			if matchUser(&u, filter) {
+				users = append(users, &u)
+			}
+		}
+		sortBy(users, func(u *domain.User) uuid.UUID { return u.ID }, false, func(a, b *domain.User) int {
+			return compareTimes(a.CreatedAt, b.CreatedAt)
+		})
+		users = keyset(users, page, func(u *domain.User, c *domain.Cursor) bool {
+			return afterTime(u.CreatedAt, u.ID, c, false)
+		})
+		return nil
+	})
+	return users, err
+}
+
+func (r *userRepository) Count(filter domain.UserFilter) (int64, error) {
+	var count int64
+	err := r.do(func(t *tables) error {
+		for _, u := range t.users {
+			if matchUser(&u, filter) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func matchUser(u *domain.User, filter domain.UserFilter) bool {
+	if filter.Query != "" && !containsFold(u.Email, filter.Query) && !containsFold(u.Name, filter.Query) {
+		return false
+	}
+	return filter.Role == "" || u.Role == filter.Role
+}
+
+func (r *userRepository) find(match func(u *domain.User) bool) (*domain.User, error) {
+	var user *domain.User
+	err := r.do(func(t *tables) error {
+		// Как First в GORM: из нескольких подходящих берем строку с наименьшим id
+		for _, u := range t.users {
+			if match(&u) && (user == nil || compareIDs(u.ID, user.ID) < 0) {
+				user = &u
+			}
+		}
+		if user == nil {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	return user, err
+}
+
+// checkUniqueEmail повторяет уникальный индекс users.email
+func checkUniqueEmail(t *tables, user *domain.User) error {
+	for id, u := range t.users {
+		if id != user.ID && u.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+func storedUser(user *domain.User) domain.User {
+	u := *user
+	u.DeletedAt = copyTime(user.DeletedAt)
+	u.EmailVerifiedAt = copyTime(user.EmailVerifiedAt)
+	u.UserBan = storedBan(user.UserBan)
+	u.Exchanges = nil
+	u.Reviews = nil
+	return u
+}
+
+func storedBan(ban domain.UserBan) domain.UserBan {
+	ban.BannedAt = copyTime(ban.BannedAt)
+	ban.BannedUntil = copyTime(ban.BannedUntil)
+	ban.BannedBy = copyUUID(ban.BannedBy)
+	return ban
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type waitlistRepository struct {
+	conn
+}
+
+// NewWaitlistRepository creates a new in-memory waitlist repository
+func NewWaitlistRepository(store *Store) domain.WaitlistRepository {
+	return &waitlistRepository{conn{store: store}}
+}
+
+func (r *waitlistRepository) Create(entry *domain.WaitlistEntry) error {
+	return r.do(func(t *tables) error {
+		entry.ID = newID(entry.ID)
+		if _, ok := t.waitlist[entry.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		// Уникальный индекс idx_waitlist_book_user
+		for _, e := range t.waitlist {
+			if e.BookID == entry.BookID && e.UserID == entry.UserID {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = now()
+		}
+		t.waitlist[entry.ID] = storedWaitlistEntry(entry)
+		return nil
+	})
+}
+
+func (r *waitlistRepository) Delete(id uuid.UUID) error {
+	return r.do(func(t *tables) error {
+		delete(t.waitlist, id)
+		return nil
+	})
+}
+
+func (r *waitlistRepository) GetByBookID(bookID uuid.UUID) ([]*domain.WaitlistEntry, error) {
+	return r.filter(func(e *domain.WaitlistEntry) bool { return e.BookID == bookID }, func(t *tables, e *domain.WaitlistEntry) {
+		e.User = t.user(&e.UserID)
+	})
+}
+
+func (r *waitlistRepository) GetByUserID(userID uuid.UUID) ([]*domain.WaitlistEntry, error) {
+	return r.filter(func(e *domain.WaitlistEntry) bool { return e.UserID == userID }, func(t *tables, e *domain.WaitlistEntry) {
+		e.Book = t.book(&e.BookID)
+	})
+}
+
+func (r *waitlistRepository) GetByBookAndUser(bookID, userID uuid.UUID) (*domain.WaitlistEntry, error) {
+	entries, err := r.filter(func(e *domain.WaitlistEntry) bool {
+		return e.BookID == bookID && e.UserID == userID
+	}, func(t *tables, e *domain.WaitlistEntry) {})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return entries[0], nil
+}
+
+// filter возвращает подходящие записи в порядке очереди (FIFO)
+func (r *waitlistRepository) filter(match func(e *domain.WaitlistEntry) bool, preload func(t *tables, e *domain.WaitlistEntry)) ([]*domain.WaitlistEntry, error) {
+	var entries []*domain.WaitlistEntry
+	err := r.do(func(t *tables) error {
+		for _, e := range t.waitlist {
+			if !match(&e) {
+				continue
+			}
+			preload(t, &e)
+			entries = append(entries, &e)
+		}
+		sortBy(entries, func(e *domain.WaitlistEntry) uuid.UUID { return e.ID }, false, func(a, b *domain.WaitlistEntry) int {
+			return compareTimes(a.CreatedAt, b.CreatedAt)
+		})
+		return nil
+	})
+	return entries, err
+}
+
+func storedWaitlistEntry(entry *domain.WaitlistEntry) domain.WaitlistEntry {
+	e := *entry
+	e.Book = nil
+	e.User = nil
+	e.Position = 0
+	return e
+}
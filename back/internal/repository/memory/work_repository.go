@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"bookvito/internal/domain"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type workRepository struct {
+	conn
+}
+
+// NewWorkRepository creates a new in-memory work repository
+func NewWorkRepository(store *Store) domain.WorkRepository {
+	return &workRepository{conn{store: store}}
+}
+
+func (r *workRepository) Create(work *domain.Work) error {
+	return r.do(func(t *tables) error {
+		work.ID = newID(work.ID)
+		if _, ok := t.works[work.ID]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		// Уникальный индекс по works.key
+		for _, w := range t.works {
+			if w.Key == work.Key {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+		ts := now()
+		if work.CreatedAt.IsZero() {
+			work.CreatedAt = ts
+		}
+		if work.UpdatedAt.IsZero() {
+			work.UpdatedAt = ts
+		}
+		t.works[work.ID] = *work
+		return nil
+	})
+}
+
+func (r *workRepository) GetByID(id uuid.UUID) (*domain.Work, error) {
+	var work *domain.Work
+	err := r.do(func(t *tables) error {
+		w, ok := t.works[id]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		work = &w
+		return nil
+	})
+	return work, err
+}
+
+func (r *workRepository) GetByKey(key string) (*domain.Work, error) {
+	var work *domain.Work
+	err := r.do(func(t *tables) error {
+		for _, w := range t.works {
+			if w.Key == key {
+				work = &w
+				return nil
+			}
+		}
+		return gorm.ErrRecordNotFound
+	})
+	return work, err
+}
+
+func (r *workRepository) CountCopies(workID uuid.UUID) (*domain.WorkCopies, error) {
+	var copies domain.WorkCopies
+	err := r.do(func(t *tables) error {
+		copies = t.workCopies()[workID]
+		return nil
+	})
+	return &copies, err
+}
+
+func (r *workRepository) AvailableLocations(workID uuid.UUID) ([]*domain.WorkLocation, error) {
+	var locations []*domain.WorkLocation
+	err := r.do(func(t *tables) error {
+		index := make(map[uuid.UUID]*domain.WorkLocation)
+		for _, b := range t.books {
+			if b.WorkID == nil || *b.WorkID != workID || b.Status != domain.BookAvailable {
+				continue
+			}
+			// Как JOIN в postgres: экземпляры без пункта не учитываются
+			l := t.location(b.CurrentLocationID)
+			if l == nil {
+				continue
+			}
+			wl, ok := index[l.ID]
+			if !ok {
+				wl = &domain.WorkLocation{LocationID: l.ID, Name: l.Name, Address: l.Address}
+				index[l.ID] = wl
+				locations = append(locations, wl)
+			}
+			wl.Available++
+		}
+		sort.Slice(locations, func(i, j int) bool {
+			a, b := locations[i], locations[j]
+			if a.Available != b.Available {
+				return a.Available > b.Available
+			}
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return compareIDs(a.LocationID, b.LocationID) < 0
+		})
+		return nil
+	})
+	return locations, err
+}
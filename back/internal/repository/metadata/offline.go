@@ -0,0 +1,73 @@
+// Package metadata implements domain.MetadataProvider. OfflineProvider answers from
+// a local dataset file; an online provider (e.g. OpenLibrary) can implement the same interface.
+package metadata
+
+import (
+	"bookvito/internal/domain"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OfflineProvider держит весь набор данных в памяти, ключ - ISBN-13
+type OfflineProvider struct {
+	books map[string]domain.BookMetadata
+}
+
+// NewOfflineProvider reads a JSON Lines dataset: one object per line with
+// "isbn", "title", "author" and optional "description" and "cover_url".
+// ISBN-10 and ISBN-13 with or without hyphens are accepted.
+func NewOfflineProvider(path string) (*OfflineProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ISBN dataset: %w", err)
+	}
+	defer f.Close()
+
+	p := &OfflineProvider{books: make(map[string]domain.BookMetadata)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // Длинные описания
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var book domain.BookMetadata
+		if err := json.Unmarshal([]byte(text), &book); err != nil {
+			return nil, fmt.Errorf("ISBN dataset line %d: %w", line, err)
+		}
+		isbn, err := domain.NormalizeISBN(book.ISBN)
+		if err != nil {
+			return nil, fmt.Errorf("ISBN dataset line %d: %w %q", line, err, book.ISBN)
+		}
+		book.ISBN = isbn
+		p.books[isbn] = book
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ISBN dataset: %w", err)
+	}
+	return p, nil
+}
+
+func (p *OfflineProvider) LookupISBN(ctx context.Context, isbn string) (*domain.BookMetadata, error) {
+	book, ok := p.books[isbn]
+	if !ok {
+		return nil, domain.ErrMetadataNotFound
+	}
+	return &book, nil
+}
+
+// Len возвращает число изданий в наборе данных
+func (p *OfflineProvider) Len() int {
+	return len(p.books)
+}
+
+// None - провайдер без данных: поиск по ISBN всегда возвращает ErrMetadataNotFound
+type None struct{}
+
+func (None) LookupISBN(ctx context.Context, isbn string) (*domain.BookMetadata, error) {
+	return nil, domain.ErrMetadataNotFound
+}
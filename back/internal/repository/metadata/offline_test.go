@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"bookvito/internal/domain"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOfflineProvider(t *testing.T) {
+	p, err := NewOfflineProvider(filepath.Join("testdata", "books.jsonl"))
+	if err != nil {
+		t.Fatalf("load dataset: %v", err)
+	}
+	if p.Len() != 2 {
+		t.Fatalf("expected 2 books, got %d", p.Len())
+	}
+
+	book, err := p.LookupISBN(context.Background(), "9785170905874")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if book.Title != "Мастер и Маргарита" || book.Author != "Михаил Булгаков" || book.CoverURL == "" || book.ISBN != "9785170905874" {
+		t.Fatalf("unexpected metadata %+v", book)
+	}
+
+	// ISBN-10 из набора доступен по ISBN-13
+	isbn, err := domain.NormalizeISBN("0-306-40615-2")
+	if err != nil || isbn != "9780306406157" {
+		t.Fatalf("unexpected ISBN-13 %q: %v", isbn, err)
+	}
+	if _, err := p.LookupISBN(context.Background(), isbn); err != nil {
+		t.Fatalf("lookup converted ISBN-10: %v", err)
+	}
+
+	if _, err := p.LookupISBN(context.Background(), "9780000000002"); !errors.Is(err, domain.ErrMetadataNotFound) {
+		t.Fatalf("expected ErrMetadataNotFound, got %v", err)
+	}
+}
+
+func TestOfflineProviderRejectsInvalidISBN(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.jsonl")
+	if err := os.WriteFile(path, []byte(`{"isbn": "978-5-17-090587-0", "title": "x", "author": "y"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewOfflineProvider(path); !errors.Is(err, domain.ErrInvalidISBN) {
+		t.Fatalf("expected ErrInvalidISBN, got %v", err)
+	}
+}
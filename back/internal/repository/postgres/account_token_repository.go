@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"bookvito/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type accountTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountTokenRepository creates a new account token repository
+func NewAccountTokenRepository(db *gorm.DB) domain.AccountTokenRepository {
+	return &accountTokenRepository{db: db}
+}
+
+func (r *accountTokenRepository) Create(token *domain.AccountToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *accountTokenRepository) GetByHash(tokenHash string) (*domain.AccountToken, error) {
+	var token domain.AccountToken
+	err := r.db.First(&token, "token_hash = ?", tokenHash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *accountTokenRepository) Use(id uuid.UUID, at time.Time) error {
+	// Условный UPDATE ... WHERE used_at IS NULL: токен погасит только один запрос
+	result := r.db.Model(&domain.AccountToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrInvalidAccountToken
+	}
+	return nil
+}
+
+func (r *accountTokenRepository) InvalidateByUserID(userID uuid.UUID, purpose domain.TokenPurpose, at time.Time) error {
+	return r.db.Model(&domain.AccountToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
@@ -2,9 +2,11 @@ package postgres
 
 import (
 	"bookvito/internal/domain"
+	"strings"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type bookRepository struct {
@@ -22,7 +24,7 @@ func (r *bookRepository) Create(book *domain.Book) error {
 
 func (r *bookRepository) GetByID(id uuid.UUID) (*domain.Book, error) {
 	var book domain.Book
-	err := r.db.Preload("CurrentLocation").Preload("Reviews").First(&book, "id = ?", id).Error
+	err := r.db.Preload("CurrentLocation").Preload("Reviews", orderNewestFirst).First(&book, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -30,7 +32,24 @@ func (r *bookRepository) GetByID(id uuid.UUID) (*domain.Book, error) {
 }
 
 func (r *bookRepository) Update(book *domain.Book) error {
-	return r.db.Save(book).Error
+	// Не сохраняем подгруженные CurrentLocation/Reviews: иначе GORM перезапишет CurrentLocationID из старой связи
+	return r.db.Omit(clause.Associations).Save(book).Error
+}
+
+func (r *bookRepository) UpdateIfStatus(book *domain.Book, expected domain.BookStatus) error {
+	// Условный UPDATE ... WHERE status = expected: из двух параллельных запросов строку обновит только один
+	result := r.db.Model(book).
+		Omit(clause.Associations).
+		Where("status = ?", expected).
+		Select("*").
+		Updates(book)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrBookConflict
+	}
+	return nil
 }
 
 func (r *bookRepository) Delete(bookID uuid.UUID) error {
@@ -42,42 +61,240 @@ func (r *bookRepository) Delete(bookID uuid.UUID) error {
 	return r.db.Delete(&domain.Book{}, "id = ?", bookID).Error
 }
 
-func (r *bookRepository) List(limit, offset int) ([]*domain.Book, error) {
+func (r *bookRepository) List(page domain.PageQuery) ([]*domain.Book, error) {
 	var books []*domain.Book
-
-	err := r.db.Model(&domain.Book{}). // Указываем модель, но выбираем только нужные поля
-						Select("id, image_url, title, author"). // Выбираем только нужные поля
-						Limit(limit).
-						Offset(offset).
-						Find(&books).Error
+	// Указываем модель, но выбираем только нужные поля (created_at - для курсора)
+	db := r.db.Model(&domain.Book{}).Select("id, image_url, title, author, created_at")
+	err := keysetByTime(db, page, "created_at", "id", true).Find(&books).Error
 	return books, err
 }
 
-func (r *bookRepository) GetSummaryList(limit, offset int) ([]*domain.BookSummary, error) {
+func (r *bookRepository) GetSummaryList(page domain.PageQuery) ([]*domain.BookSummary, error) {
 	var summaries []*domain.BookSummary
-	err := r.db.Model(&domain.Book{}). // Указываем модель, но выбираем только нужные поля
-						Select("id, image_url, title, author"). // Выбираем только нужные поля
-						Limit(limit).
-						Offset(offset).
-						Find(&summaries).Error
+	// Указываем модель, но выбираем только нужные поля (created_at - для курсора)
+	db := r.db.Model(&domain.Book{}).Select("id, image_url, title, author, created_at")
+	err := keysetByTime(db, page, "created_at", "id", true).Find(&summaries).Error
 	return summaries, err
 }
 
-func (r *bookRepository) Search(query string, limit, offset int) ([]*domain.Book, error) {
-	var books []*domain.Book
-	searchPattern := "%" + query + "%"
-	err := r.db.Preload("CurrentLocation").
-		Where("title ILIKE ? OR author ILIKE ? OR description ILIKE ?", searchPattern, searchPattern, searchPattern).
+func (r *bookRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.Book{}).Count(&count).Error
+	return count, err
+}
+
+// Поисковый запрос собирается из частей: WITH + SELECT (count или сами книги) + FROM/WHERE.
+// Совпадение - по tsvector (словоформы) или по триграммам названия/автора (опечатки: "Булгакав").
+// Экземпляры одного произведения схлопываются по ключу coalesce(work_id, id).
+const bookSearchWith = `WITH q AS (SELECT websearch_to_tsquery('russian', @query) AS tsq)
+`
+
+const bookSearchFrom = `
+FROM books b, q
+WHERE b.status <> 'deleted'
+  AND (b.search_vector @@ q.tsq OR @query <% b.title OR @query <% b.author)`
+
+const bookSearchRank = `ts_rank_cd(b.search_vector, q.tsq, 32)
+         + 0.5 * greatest(word_similarity(@query, b.title), word_similarity(@query, b.author))`
+
+// hits - лучший экземпляр каждого произведения (свободный, затем самый релевантный)
+// с релевантностью лучшего совпадения среди всех экземпляров
+const bookSearchHits = `, hits AS (
+SELECT DISTINCT ON (coalesce(b.work_id, b.id))
+       b.id, b.work_id, coalesce(b.work_id, b.id) AS work_key, b.title, b.author, b.description,
+       b.image_url, b.status, b.condition, b.current_location_id,
+       max(` + bookSearchRank + `) OVER (PARTITION BY coalesce(b.work_id, b.id)) AS rank` + bookSearchFrom + `
+ORDER BY coalesce(b.work_id, b.id), b.status = 'available' DESC, ` + bookSearchRank + ` DESC, b.id ASC
+)
+`
+
+const bookSearchSelect = `SELECT h.id, h.work_id, h.title, h.author, h.image_url, h.status, h.condition, h.current_location_id, h.rank,
+       ts_headline('russian', h.title, q.tsq, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS headline,
+       ts_headline('russian', coalesce(h.description, ''), q.tsq,
+         'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=<mark>, StopSel=</mark>') AS snippet,
+       coalesce(c.copies, 0) AS copies, coalesce(c.available_copies, 0) AS available_copies
+FROM hits h CROSS JOIN q
+LEFT JOIN (
+	SELECT coalesce(work_id, id) AS work_key, count(*) AS copies,
+	       count(*) FILTER (WHERE status = 'available') AS available_copies
+	FROM books WHERE status <> 'deleted' GROUP BY 1
+) c ON c.work_key = h.work_key`
+
+func (r *bookRepository) Search(query string, limit, offset int) ([]*domain.BookSearchResult, int64, error) {
+	args := map[string]interface{}{"query": query, "limit": limit, "offset": offset}
+
+	var total int64
+	err := r.db.Raw(bookSearchWith+"SELECT count(DISTINCT coalesce(b.work_id, b.id))"+bookSearchFrom, args).Scan(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stmt := bookSearchWith + bookSearchHits + bookSearchSelect + "\nORDER BY h.rank DESC, h.id ASC"
+	if limit >= 0 {
+		stmt += "\nLIMIT @limit"
+	}
+	if offset > 0 {
+		stmt += "\nOFFSET @offset"
+	}
+
+	var results []*domain.BookSearchResult
+	err = r.db.Raw(stmt, args).Scan(&results).Error
+	return results, total, err
+}
+
+// Средняя оценка и число отзывов по каждому произведению (по отзывам на все его экземпляры) -
+// для карточек каталога и сортировки по рейтингу
+const bookRatingsJoin = `LEFT JOIN (
+	SELECT coalesce(rb.work_id, rb.id) AS work_key, avg(rv.rating)::float8 AS average_rating, count(*) AS reviews_count
+	FROM reviews rv JOIN books rb ON rb.id = rv.book_id
+	GROUP BY 1
+) r ON r.work_key = coalesce(b.work_id, b.id)`
+
+const bookListSelect = `b.id, b.work_id, b.title, b.author, b.image_url, b.status, b.condition, b.owner_id,
+	b.current_location_id, b.created_at, r.average_rating, coalesce(r.reviews_count, 0) AS reviews_count`
+
+func (r *bookRepository) Filter(filter domain.BookFilter) ([]*domain.BookListItem, error) {
+	db := applyBookFilter(r.db.Table("books AS b"), filter, "").
+		Select(bookListSelect).
+		Joins(bookRatingsJoin)
+
+	var items []*domain.BookListItem
+	err := bookListKeyset(db, filter).Limit(filter.Limit).Scan(&items).Error
+	return items, err
+}
+
+func (r *bookRepository) CountFiltered(filter domain.BookFilter) (int64, error) {
+	var total int64
+	err := applyBookFilter(r.db.Table("books AS b"), filter, "").Count(&total).Error
+	return total, err
+}
+
+// bookListKeyset добавляет ORDER BY каталога и условие "после курсора".
+// Книги без отзывов при сортировке по рейтингу всегда в конце, поэтому для рейтинга условие
+// учитывает NULL: после оцененной книги идут оцененные ниже (выше) и все неоцененные.
+func bookListKeyset(db *gorm.DB, filter domain.BookFilter) *gorm.DB {
+	dir, op := orderDir(filter.Desc), keysetOp(filter.Desc)
+	after := filter.After
+
+	switch filter.Sort {
+	case domain.BookSortTitle:
+		if after != nil && after.Text != nil {
+			db = db.Where("(b.title, b.id) "+op+" (?, ?)", *after.Text, after.ID)
+		}
+		return db.Order("b.title " + dir + ", b.id " + dir)
+	case domain.BookSortRating:
+		switch {
+		case after != nil && after.Number != nil:
+			db = db.Where("(r.average_rating "+op+" ? OR r.average_rating IS NULL OR (r.average_rating = ? AND b.id "+op+" ?))",
+				*after.Number, *after.Number, after.ID)
+		case after != nil:
+			db = db.Where("r.average_rating IS NULL AND b.id "+op+" ?", after.ID)
+		}
+		return db.Order("r.average_rating " + dir + " NULLS LAST, b.id " + dir)
+	default:
+		if after != nil && after.Time != nil {
+			db = db.Where("(b.created_at, b.id) "+op+" (?, ?)", *after.Time, after.ID)
+		}
+		return db.Order("b.created_at " + dir + ", b.id " + dir)
+	}
+}
+
+// bookFacet - поле, по которому считается фасет; его собственный фильтр при подсчете не применяется
+type bookFacet string
+
+const (
+	facetStatus    bookFacet = "status"
+	facetCondition bookFacet = "condition"
+	facetAuthor    bookFacet = "author"
+	facetLocation  bookFacet = "location"
+	facetOwner     bookFacet = "owner"
+)
+
+// applyBookFilter добавляет к запросу по books AS b условия фильтра, кроме фильтра по полю skip
+func applyBookFilter(db *gorm.DB, filter domain.BookFilter, skip bookFacet) *gorm.DB {
+	db = db.Where("b.status <> ?", domain.BookDeleted)
+	if skip != facetStatus && len(filter.Statuses) > 0 {
+		db = db.Where("b.status IN ?", filter.Statuses)
+	}
+	if skip != facetCondition && len(filter.Conditions) > 0 {
+		db = db.Where("b.condition IN ?", filter.Conditions)
+	}
+	if skip != facetAuthor && len(filter.Authors) > 0 {
+		authors := make([]string, 0, len(filter.Authors))
+		for _, a := range filter.Authors {
+			authors = append(authors, strings.ToLower(a))
+		}
+		db = db.Where("lower(b.author) IN ?", authors)
+	}
+	if skip != facetLocation && len(filter.LocationIDs) > 0 {
+		db = db.Where("b.current_location_id IN ?", filter.LocationIDs)
+	}
+	if skip != facetOwner && len(filter.OwnerIDs) > 0 {
+		db = db.Where("b.owner_id IN ?", filter.OwnerIDs)
+	}
+	if filter.CreatedFrom != nil {
+		db = db.Where("b.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		db = db.Where("b.created_at < ?", *filter.CreatedTo)
+	}
+	return db
+}
+
+func (r *bookRepository) Facets(filter domain.BookFilter) (*domain.BookFacets, error) {
+	var (
+		facets domain.BookFacets
+		err    error
+	)
+	if facets.Status, err = r.facet(filter, facetStatus, "b.status", "", -1); err != nil {
+		return nil, err
+	}
+	if facets.Condition, err = r.facet(filter, facetCondition, "b.condition", "", -1); err != nil {
+		return nil, err
+	}
+	if facets.Author, err = r.facet(filter, facetAuthor, "b.author", "", domain.MaxFacetValues); err != nil {
+		return nil, err
+	}
+	facets.Location, err = r.facet(filter, facetLocation, "b.current_location_id::text", "JOIN locations l ON l.id = b.current_location_id", domain.MaxFacetValues)
+	if err != nil {
+		return nil, err
+	}
+	facets.Owner, err = r.facet(filter, facetOwner, "b.owner_id::text", "JOIN users u ON u.id = b.owner_id", domain.MaxFacetValues)
+	if err != nil {
+		return nil, err
+	}
+	return &facets, nil
+}
+
+// facet группирует подходящие книги по value; join подключает таблицу с подписью значения (l.name, u.name)
+func (r *bookRepository) facet(filter domain.BookFilter, skip bookFacet, value, join string, limit int) ([]domain.FacetCount, error) {
+	label := "''"
+	switch skip {
+	case facetLocation:
+		label = "l.name"
+	case facetOwner:
+		label = "u.name"
+	}
+
+	db := r.db.Table("books AS b")
+	if join != "" {
+		db = db.Joins(join)
+	}
+	counts := []domain.FacetCount{}
+	err := applyBookFilter(db, filter, skip).
+		Select(value + " AS value, " + label + " AS label, count(*) AS count").
+		Group("1, 2").
+		Order("count DESC, value ASC").
 		Limit(limit).
-		Offset(offset).
-		Find(&books).Error
-	return books, err
+		Scan(&counts).Error
+	return counts, err
 }
 
 func (r *bookRepository) GetByStatus(status domain.BookStatus, limit, offset int) ([]*domain.Book, error) {
 	var books []*domain.Book
 	err := r.db.Preload("CurrentLocation").
 		Where("status = ?", status).
+		Order("created_at DESC, id DESC").
 		Limit(limit).
 		Offset(offset).
 		Find(&books).Error
@@ -88,6 +305,30 @@ func (r *bookRepository) GetByLocationID(locationID uuid.UUID) ([]*domain.Book,
 	var books []*domain.Book
 	err := r.db.Preload("CurrentLocation").
 		Where("current_location_id = ?", locationID).
+		Order("created_at DESC, id DESC").
+		Find(&books).Error
+	return books, err
+}
+
+func (r *bookRepository) GetByWorkID(workID uuid.UUID) ([]*domain.Book, error) {
+	var books []*domain.Book
+	err := r.db.Preload("CurrentLocation").
+		Where("work_id = ? AND status <> ?", workID, domain.BookDeleted).
+		Order("created_at DESC, id DESC").
+		Find(&books).Error
+	return books, err
+}
+
+func (r *bookRepository) GetByOwnerID(ownerID uuid.UUID) ([]*domain.Book, error) {
+	var books []*domain.Book
+	err := r.db.Preload("CurrentLocation").
+		Where("owner_id = ? AND status <> ?", ownerID, domain.BookDeleted).
+		Order("created_at DESC, id DESC").
 		Find(&books).Error
 	return books, err
 }
+
+// orderNewestFirst - порядок подгружаемых связей (книг, отзывов): от новых к старым
+func orderNewestFirst(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at DESC, id DESC")
+}
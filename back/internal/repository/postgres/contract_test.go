@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"bookvito/internal/repository/contract"
+	"bookvito/pkg/migrations"
+	"context"
+	"os"
+	"testing"
+
+	pg "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Контракт против настоящей базы запускается, только если задан BOOKVITO_TEST_DATABASE_DSN.
+// База будет очищена: перед каждым подтестом схема откатывается и создается заново.
+func TestContract(t *testing.T) {
+	dsn := os.Getenv("BOOKVITO_TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("BOOKVITO_TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(pg.Open(dsn), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("connection pool: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+
+	contract.Run(t, func(t *testing.T) contract.Backend {
+		ctx := context.Background()
+		if err := migrator.To(ctx, 0); err != nil {
+			t.Fatalf("migrate down: %v", err)
+		}
+		if err := migrator.Up(ctx); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		return contract.Backend{Repos: newRepositories(db), UnitOfWork: NewUnitOfWork(db)}
+	})
+}
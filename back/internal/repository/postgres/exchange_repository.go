@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type exchangeRepository struct {
@@ -30,42 +31,92 @@ func (r *exchangeRepository) GetByID(id uuid.UUID) (*domain.Exchange, error) {
 	return &exchange, nil
 }
 
-func (r *exchangeRepository) GetByUserID(userID uuid.UUID) ([]*domain.Exchange, error) {
+func (r *exchangeRepository) GetByBookID(bookID uuid.UUID) ([]*domain.Exchange, error) {
 	var exchanges []*domain.Exchange
-	// Preload Book and Location, User is redundant as we are querying by user_id
-	err := r.db.Preload("Book").Preload("Location").Where("user_id = ?", userID).Find(&exchanges).Error
+	// Preload User and Location, Book is redundant as we are querying by book_id
+	err := r.db.Preload("User").Preload("Location").Where("book_id = ?", bookID).Order("booked_at DESC, id DESC").Find(&exchanges).Error
 	if err != nil {
 		return nil, err
 	}
 	return exchanges, nil
 }
 
-func (r *exchangeRepository) GetByBookID(bookID uuid.UUID) ([]*domain.Exchange, error) {
+func (r *exchangeRepository) ListByUserID(userID uuid.UUID, page domain.PageQuery) ([]*domain.Exchange, error) {
+	var exchanges []*domain.Exchange
+	// Preload Book and Location, User is redundant as we are querying by user_id
+	err := keysetByTime(r.db.Preload("Book").Preload("Location").Where("user_id = ?", userID), page, "booked_at", "id", true).
+		Find(&exchanges).Error
+	return exchanges, err
+}
+
+func (r *exchangeRepository) ListByBookID(bookID uuid.UUID, page domain.PageQuery) ([]*domain.Exchange, error) {
 	var exchanges []*domain.Exchange
 	// Preload User and Location, Book is redundant as we are querying by book_id
-	err := r.db.Preload("User").Preload("Location").Where("book_id = ?", bookID).Find(&exchanges).Error
-	if err != nil {
-		return nil, err
-	}
-	return exchanges, nil
+	err := keysetByTime(r.db.Preload("User").Preload("Location").Where("book_id = ?", bookID), page, "booked_at", "id", true).
+		Find(&exchanges).Error
+	return exchanges, err
+}
+
+func (r *exchangeRepository) CountByUserID(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.Exchange{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *exchangeRepository) CountByBookID(bookID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.Exchange{}).Where("book_id = ?", bookID).Count(&count).Error
+	return count, err
 }
 
 func (r *exchangeRepository) Update(exchange *domain.Exchange) error {
-	return r.db.Save(exchange).Error
+	// Связанные User/Book/Location могли быть подгружены через Preload - их не сохраняем
+	return r.db.Omit(clause.Associations).Save(exchange).Error
+}
+
+func (r *exchangeRepository) UpdateIfStatus(exchange *domain.Exchange, expected domain.ExchangeStatus) error {
+	// Условный UPDATE ... WHERE status = expected, как у книг
+	result := r.db.Model(exchange).
+		Omit(clause.Associations).
+		Where("status = ?", expected).
+		Select("*").
+		Updates(exchange)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrExchangeConflict
+	}
+	return nil
 }
 
 func (r *exchangeRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&domain.Exchange{}, "id = ?", id).Error
 }
 
-func (r *exchangeRepository) List(limit, offset int) ([]*domain.Exchange, error) {
+func (r *exchangeRepository) List(page domain.PageQuery) ([]*domain.Exchange, error) {
 	var exchanges []*domain.Exchange
-	err := r.db.Preload("User").Preload("Book").Preload("Location").Limit(limit).Offset(offset).Find(&exchanges).Error
+	err := keysetByTime(r.db.Preload("User").Preload("Book").Preload("Location"), page, "booked_at", "id", true).Find(&exchanges).Error
 	return exchanges, err
 }
 
 func (r *exchangeRepository) GetExpired() ([]*domain.Exchange, error) {
 	var exchanges []*domain.Exchange
-	err := r.db.Where("status = ? AND expires_at < ?", domain.ExchangeRequested, time.Now()).Find(&exchanges).Error
+	err := r.db.Where("status = ? AND expires_at < ?", domain.ExchangeRequested, time.Now()).Order("expires_at ASC, id ASC").Find(&exchanges).Error
+	return exchanges, err
+}
+
+func (r *exchangeRepository) GetOverdue() ([]*domain.Exchange, error) {
+	var exchanges []*domain.Exchange
+	err := r.db.Where("status = ? AND due_at < ?", domain.ExchangeBorrowed, time.Now()).Order("due_at ASC, id ASC").Find(&exchanges).Error
+	return exchanges, err
+}
+
+func (r *exchangeRepository) GetByStatus(status domain.ExchangeStatus) ([]*domain.Exchange, error) {
+	var exchanges []*domain.Exchange
+	err := r.db.Preload("User").Preload("Book").Preload("Location").
+		Where("status = ?", status).
+		Order("due_at ASC, id ASC").
+		Find(&exchanges).Error
 	return exchanges, err
 }
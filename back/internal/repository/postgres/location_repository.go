@@ -29,7 +29,7 @@ func (r *locationRepository) Create(location *domain.Location) error {
 
 func (r *locationRepository) GetByID(id uuid.UUID) (*domain.Location, error) {
 	var location domain.Location
-	err := r.db.Preload("Books").First(&location, "id = ?", id).Error
+	err := r.db.Preload("Books", orderNewestFirst).First(&location, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +38,7 @@ func (r *locationRepository) GetByID(id uuid.UUID) (*domain.Location, error) {
 
 func (r *locationRepository) GetByAddress(address string) (*domain.Location, error) {
 	var location domain.Location
-	err := r.db.Preload("Books").First(&location, "address = ?", address).Error
+	err := r.db.Preload("Books", orderNewestFirst).First(&location, "address = ?", address).Error
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +47,7 @@ func (r *locationRepository) GetByAddress(address string) (*domain.Location, err
 
 func (r *locationRepository) GetAll() ([]domain.Location, error) {
 	var locations []domain.Location
-	err := r.db.Preload("Books").Find(&locations).Error
+	err := r.db.Preload("Books", orderNewestFirst).Order("name ASC, id ASC").Find(&locations).Error
 	return locations, err
 }
 
@@ -51,7 +51,7 @@ func (r *bookMovementHistoryRepository) GetByBookID(bookID uuid.UUID) ([]*domain
 		Preload("ToLocation").
 		Preload("Exchange").
 		Preload("User").
-		Order("created_at DESC").
+		Order("created_at DESC, id DESC").
 		Find(&movements).Error
 	if err != nil {
 		return nil, err
@@ -68,7 +68,7 @@ func (r *bookMovementHistoryRepository) GetByExchangeID(exchangeID uuid.UUID) ([
 		Preload("FromLocation").
 		Preload("ToLocation").
 		Preload("User").
-		Order("created_at DESC").
+		Order("created_at DESC, id DESC").
 		Find(&movements).Error
 	if err != nil {
 		return nil, err
@@ -85,7 +85,7 @@ func (r *bookMovementHistoryRepository) GetByUserID(userID uuid.UUID) ([]*domain
 		Preload("FromLocation").
 		Preload("ToLocation").
 		Preload("Exchange").
-		Order("created_at DESC").
+		Order("created_at DESC, id DESC").
 		Find(&movements).Error
 	if err != nil {
 		return nil, err
@@ -93,21 +93,55 @@ func (r *bookMovementHistoryRepository) GetByUserID(userID uuid.UUID) ([]*domain
 	return movements, nil
 }
 
-// List retrieves a paginated list of movement history
-func (r *bookMovementHistoryRepository) List(limit, offset int) ([]*domain.BookMovementHistory, error) {
+// ListByBookID retrieves a page of movement history for a specific book
+func (r *bookMovementHistoryRepository) ListByBookID(bookID uuid.UUID, page domain.PageQuery) ([]*domain.BookMovementHistory, error) {
 	var movements []*domain.BookMovementHistory
-	err := r.db.
+	db := r.db.
+		Where("book_id = ?", bookID).
+		Preload("FromLocation").
+		Preload("ToLocation").
+		Preload("Exchange").
+		Preload("User")
+	err := keysetByTime(db, page, "created_at", "id", true).Find(&movements).Error
+	return movements, err
+}
+
+// ListByUserID retrieves a page of movement history initiated by a specific user
+func (r *bookMovementHistoryRepository) ListByUserID(userID uuid.UUID, page domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+	var movements []*domain.BookMovementHistory
+	db := r.db.
+		Where("user_id = ?", userID).
+		Preload("Book").
+		Preload("FromLocation").
+		Preload("ToLocation").
+		Preload("Exchange")
+	err := keysetByTime(db, page, "created_at", "id", true).Find(&movements).Error
+	return movements, err
+}
+
+// List retrieves a page of movement history
+func (r *bookMovementHistoryRepository) List(page domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+	var movements []*domain.BookMovementHistory
+	db := r.db.
 		Preload("Book").
 		Preload("FromLocation").
 		Preload("ToLocation").
 		Preload("Exchange").
-		Preload("User").
-		Order("created_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&movements).Error
-	if err != nil {
-		return nil, err
-	}
-	return movements, nil
+		Preload("User")
+	err := keysetByTime(db, page, "created_at", "id", true).Find(&movements).Error
+	return movements, err
+}
+
+// CountByBookID counts movement history records of a specific book
+func (r *bookMovementHistoryRepository) CountByBookID(bookID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.BookMovementHistory{}).Where("book_id = ?", bookID).Count(&count).Error
+	return count, err
+}
+
+// CountByUserID counts movement history records initiated by a specific user
+func (r *bookMovementHistoryRepository) CountByUserID(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.BookMovementHistory{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
 }
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"bookvito/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// keysetByTime ограничивает выборку записями строго после курсора в порядке (column, id).
+// Сравнение кортежей использует составной индекс по (column, id), если он есть.
+func keysetByTime(db *gorm.DB, page domain.PageQuery, column, idColumn string, desc bool) *gorm.DB {
+	if page.After != nil && page.After.Time != nil {
+		db = db.Where("("+column+", "+idColumn+") "+keysetOp(desc)+" (?, ?)", *page.After.Time, page.After.ID)
+	}
+	return db.Order(column + " " + orderDir(desc) + ", " + idColumn + " " + orderDir(desc)).Limit(page.Limit)
+}
+
+func keysetOp(desc bool) string {
+	if desc {
+		return "<"
+	}
+	return ">"
+}
+
+func orderDir(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+	return "ASC"
+}
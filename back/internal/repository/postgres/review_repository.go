@@ -30,13 +30,22 @@ func (r *reviewRepository) GetByID(id uuid.UUID) (*domain.Review, error) {
 
 func (r *reviewRepository) GetByBookID(bookID uuid.UUID) ([]domain.Review, error) {
 	var reviews []domain.Review
-	err := r.db.Preload("User").Where("book_id = ?", bookID).Find(&reviews).Error
+	err := r.db.Preload("User").Where("book_id = ?", bookID).Order("created_at DESC, id DESC").Find(&reviews).Error
+	return reviews, err
+}
+
+func (r *reviewRepository) GetByWorkID(workID uuid.UUID) ([]domain.Review, error) {
+	var reviews []domain.Review
+	err := r.db.Preload("User").
+		Where("book_id IN (?)", r.db.Model(&domain.Book{}).Select("id").Where("work_id = ?", workID)).
+		Order("created_at DESC, id DESC").
+		Find(&reviews).Error
 	return reviews, err
 }
 
 func (r *reviewRepository) GetByUserID(userID uuid.UUID) ([]domain.Review, error) {
 	var reviews []domain.Review
-	err := r.db.Preload("Book").Where("user_id = ?", userID).Find(&reviews).Error
+	err := r.db.Preload("Book").Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&reviews).Error
 	return reviews, err
 }
 
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"bookvito/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *gorm.DB) domain.SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *domain.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) GetByID(id uuid.UUID) (*domain.Session, error) {
+	var session domain.Session
+	err := r.db.First(&session, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) Rotate(session *domain.Session, previousHash string) error {
+	// Условный UPDATE ... WHERE token_hash = previousHash: один токен обменяет только один запрос
+	result := r.db.Model(&domain.Session{}).
+		Where("id = ? AND token_hash = ? AND revoked_at IS NULL", session.ID, previousHash).
+		Updates(map[string]any{
+			"token_hash":   session.TokenHash,
+			"ip":           session.IP,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (r *sessionRepository) Revoke(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *sessionRepository) RevokeByUserID(userID uuid.UUID, at time.Time) error {
+	return r.db.Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (r *sessionRepository) ListActiveByUserID(userID uuid.UUID, at time.Time) ([]*domain.Session, error) {
+	var sessions []*domain.Session
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, at).
+		Order("last_used_at DESC, id ASC").
+		Find(&sessions).Error
+	return sessions, err
+}
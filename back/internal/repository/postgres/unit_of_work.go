@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"bookvito/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork creates a unit of work backed by database transactions
+func NewUnitOfWork(db *gorm.DB) domain.UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+// Do runs fn inside a transaction; repositories passed to fn share the same tx
+func (u *unitOfWork) Do(fn func(repos *domain.Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositories(tx))
+	})
+}
+
+func newRepositories(db *gorm.DB) *domain.Repositories {
+	return &domain.Repositories{
+		Users:     NewUserRepository(db),
+		Sessions:  NewSessionRepository(db),
+		Tokens:    NewAccountTokenRepository(db),
+		Books:     NewBookRepository(db),
+		Works:     NewWorkRepository(db),
+		Exchanges: NewExchangeRepository(db),
+		Locations: NewLocationRepository(db),
+		Reviews:   NewReviewRepository(db),
+		Movements: NewBookMovementHistoryRepository(db),
+		Waitlist:  NewWaitlistRepository(db),
+		Uploads:   NewUploadRepository(db),
+	}
+}
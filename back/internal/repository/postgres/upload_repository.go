@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type uploadRepository struct {
+	db *gorm.DB
+}
+
+func NewUploadRepository(db *gorm.DB) domain.UploadRepository {
+	return &uploadRepository{db: db}
+}
+
+func (r *uploadRepository) Create(upload *domain.Upload) error {
+	return r.db.Create(upload).Error
+}
+
+func (r *uploadRepository) GetByID(id uuid.UUID) (*domain.Upload, error) {
+	var upload domain.Upload
+	err := r.db.First(&upload, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
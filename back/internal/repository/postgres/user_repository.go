@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"bookvito/internal/domain"
+	"strings"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -37,24 +38,63 @@ func (r *userRepository) GetByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
+// moderatedColumns меняются только через UpdateRole и UpdateBan
+var moderatedColumns = []string{"role", "banned_at", "banned_until", "ban_reason", "banned_by"}
+
 func (r *userRepository) Update(user *domain.User) error {
-	return r.db.Save(user).Error
+	return r.db.Omit(moderatedColumns...).Save(user).Error
+}
+
+func (r *userRepository) UpdateRole(id uuid.UUID, role domain.UserRole) error {
+	return r.updateColumns(id, map[string]any{"role": role})
+}
+
+func (r *userRepository) UpdateBan(id uuid.UUID, ban domain.UserBan) error {
+	return r.updateColumns(id, map[string]any{
+		"banned_at":    ban.BannedAt,
+		"banned_until": ban.BannedUntil,
+		"ban_reason":   ban.BanReason,
+		"banned_by":    ban.BannedBy,
+	})
+}
+
+func (r *userRepository) updateColumns(id uuid.UUID, columns map[string]any) error {
+	result := r.db.Model(&domain.User{}).Where("id = ?", id).Updates(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (r *userRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&domain.User{}, "id = ?", id).Error
 }
 
-func (r *userRepository) List(limit, offset int) ([]*domain.User, error) {
+func (r *userRepository) List(filter domain.UserFilter, page domain.PageQuery) ([]*domain.User, error) {
 	var users []*domain.User
-	err := r.db.Limit(limit).Offset(offset).Find(&users).Error
+	err := keysetByTime(filterUsers(r.db, filter), page, "created_at", "id", false).Find(&users).Error
 	return users, err
 }
-func (r *userRepository) GetByRefreshToken(refreshToken string) (*domain.User, error) {
-	var user domain.User
-	err := r.db.Where("refresh_token = ?", refreshToken).First(&user).Error
-	if err != nil {
-		return nil, err
+
+func (r *userRepository) Count(filter domain.UserFilter) (int64, error) {
+	var count int64
+	err := filterUsers(r.db.Model(&domain.User{}), filter).Count(&count).Error
+	return count, err
+}
+
+func filterUsers(db *gorm.DB, filter domain.UserFilter) *gorm.DB {
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.Query)) + "%"
+		db = db.Where("(lower(email) LIKE ? OR lower(name) LIKE ?)", pattern, pattern)
 	}
-	return &user, nil
+	if filter.Role != "" {
+		db = db.Where("role = ?", filter.Role)
+	}
+	return db
 }
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы подстрока искалась буквально
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type waitlistRepository struct {
+	db *gorm.DB
+}
+
+// NewWaitlistRepository creates a new waitlist repository
+func NewWaitlistRepository(db *gorm.DB) domain.WaitlistRepository {
+	return &waitlistRepository{db: db}
+}
+
+func (r *waitlistRepository) Create(entry *domain.WaitlistEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *waitlistRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.WaitlistEntry{}, "id = ?", id).Error
+}
+
+func (r *waitlistRepository) GetByBookID(bookID uuid.UUID) ([]*domain.WaitlistEntry, error) {
+	var entries []*domain.WaitlistEntry
+	// id добавлен в сортировку, чтобы порядок был стабильным при одинаковом времени
+	err := r.db.Preload("User").
+		Where("book_id = ?", bookID).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *waitlistRepository) GetByUserID(userID uuid.UUID) ([]*domain.WaitlistEntry, error) {
+	var entries []*domain.WaitlistEntry
+	err := r.db.Preload("Book").
+		Where("user_id = ?", userID).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *waitlistRepository) GetByBookAndUser(bookID, userID uuid.UUID) (*domain.WaitlistEntry, error) {
+	var entry domain.WaitlistEntry
+	err := r.db.First(&entry, "book_id = ? AND user_id = ?", bookID, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type workRepository struct {
+	db *gorm.DB
+}
+
+// NewWorkRepository creates a new work repository
+func NewWorkRepository(db *gorm.DB) domain.WorkRepository {
+	return &workRepository{db: db}
+}
+
+func (r *workRepository) Create(work *domain.Work) error {
+	return r.db.Create(work).Error
+}
+
+func (r *workRepository) GetByID(id uuid.UUID) (*domain.Work, error) {
+	var work domain.Work
+	err := r.db.First(&work, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+func (r *workRepository) GetByKey(key string) (*domain.Work, error) {
+	var work domain.Work
+	err := r.db.First(&work, "key = ?", key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+func (r *workRepository) CountCopies(workID uuid.UUID) (*domain.WorkCopies, error) {
+	var copies domain.WorkCopies
+	err := r.db.Model(&domain.Book{}).
+		Select("count(*) AS total, count(*) FILTER (WHERE status = ?) AS available", domain.BookAvailable).
+		Where("work_id = ? AND status <> ?", workID, domain.BookDeleted).
+		Scan(&copies).Error
+	return &copies, err
+}
+
+func (r *workRepository) AvailableLocations(workID uuid.UUID) ([]*domain.WorkLocation, error) {
+	var locations []*domain.WorkLocation
+	err := r.db.Table("books AS b").
+		Select("l.id AS location_id, l.name, l.address, count(*) AS available").
+		Joins("JOIN locations l ON l.id = b.current_location_id").
+		Where("b.work_id = ? AND b.status = ?", workID, domain.BookAvailable).
+		Group("l.id, l.name, l.address").
+		Order("available DESC, l.name ASC, l.id ASC").
+		Scan(&locations).Error
+	return locations, err
+}
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// authorize загружает пользователя и проверяет его право по роли в базе. Middleware уже берет
+// роль из базы через CheckAccess, но сценарий не полагается на delivery-слой: проверка
+// повторяется здесь, а загруженный пользователь возвращается вызывающему.
+func authorize(users domain.UserRepository, userID uuid.UUID, p domain.Permission) (*domain.User, error) {
+	user, err := users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, domain.ErrAccountDeleted
+	}
+	if err := user.Role.Authorize(p); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UpdateBook меняет описание книги: название, автора, описание, состояние и обложку
+// (загрузка image_id или адрес image_url).
+// Статус, владелец и местоположение меняются только своими действиями.
+func (uc *BookUseCase) UpdateBook(updated *domain.Book, userID uuid.UUID) (*domain.Book, error) {
+	if updated.Title == "" {
+		return nil, errors.New("book title cannot be empty")
+	}
+	if updated.Author == "" {
+		return nil, errors.New("book author cannot be empty")
+	}
+
+	book, _, actors, err := uc.loadForTransition(updated.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := domain.EditBook(book.Status, domain.ActionUpdated, actors...)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated.ISBN != "" {
+		if updated.ISBN, err = domain.NormalizeISBN(updated.ISBN); err != nil {
+			return nil, err
+		}
+	}
+
+	workKey := domain.WorkKey(book.Title, book.Author)
+	book.Title = updated.Title
+	book.ISBN = updated.ISBN
+	book.Author = updated.Author
+	book.Description = updated.Description
+	book.Condition = updated.Condition
+	if updated.ImageURL != book.ImageURL {
+		book.ImageID = nil // Другой адрес заменяет загруженную обложку
+	}
+	book.ImageURL = updated.ImageURL
+	if err := uc.attachImage(book, updated.ImageID, userID); err != nil {
+		return nil, err
+	}
+	if err := uc.relinkWork(book, workKey); err != nil {
+		return nil, err
+	}
+
+	err = uc.uow.Do(func(repos *domain.Repositories) error {
+		// Статус не меняется, но условное обновление не даст затереть параллельный переход
+		if err := repos.Books.UpdateIfStatus(book, book.Status); err != nil {
+			return err
+		}
+		movement := edit.Movement(book.ID, &userID, book.Status)
+		movement.Notes = "Book details updated"
+		return repos.Movements.Create(movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.bookRepo.GetByID(book.ID)
+}
+
+// ArchiveBook убирает свободную книгу из оборота; книгу на руках или в брони архивировать нельзя
+func (uc *BookUseCase) ArchiveBook(bookID, userID uuid.UUID) error {
+	return uc.changeShelfStatus(bookID, userID, domain.BookAvailable, domain.BookArchived, "Book archived")
+}
+
+// UnarchiveBook возвращает книгу из архива в оборот
+func (uc *BookUseCase) UnarchiveBook(bookID, userID uuid.UUID) error {
+	return uc.changeShelfStatus(bookID, userID, domain.BookArchived, domain.BookAvailable, "Book unarchived")
+}
+
+// changeShelfStatus переводит книгу между from и to, не трогая брони: из других статусов
+// (например, requested -> available - это отмена брони) такой переход недоступен
+func (uc *BookUseCase) changeShelfStatus(bookID, userID uuid.UUID, from, to domain.BookStatus, notes string) error {
+	book, _, actors, err := uc.loadForTransition(bookID, userID)
+	if err != nil {
+		return err
+	}
+	if book.Status != from {
+		return fmt.Errorf("%w: %s -> %s", domain.ErrInvalidTransition, book.Status, to)
+	}
+	transition, err := domain.TransitionBook(book.Status, to, actors...)
+	if err != nil {
+		return err
+	}
+
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := moveBook(repos, book, transition); err != nil {
+			return err
+		}
+		movement := transition.Movement(book.ID, &userID)
+		movement.FromLocationID = book.CurrentLocationID
+		movement.ToLocationID = book.CurrentLocationID
+		movement.Notes = notes
+		return repos.Movements.Create(movement)
+	})
+}
+
+// TransferBook передает книгу другому пользователю. Книга не должна быть на руках или в брони.
+func (uc *BookUseCase) TransferBook(bookID, userID, newOwnerID uuid.UUID) error {
+	book, _, actors, err := uc.loadForTransition(bookID, userID)
+	if err != nil {
+		return err
+	}
+	edit, err := domain.EditBook(book.Status, domain.ActionTransferred, actors...)
+	if err != nil {
+		return err
+	}
+	if book.OwnerID == newOwnerID {
+		return domain.ErrSameOwner
+	}
+	newOwner, err := uc.userRepo.GetByID(newOwnerID)
+	if err != nil {
+		return err
+	}
+	// Удаленный или заблокированный пользователь не сможет распоряжаться книгой
+	if err := newOwner.CheckAccess(time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidNewOwner, err)
+	}
+
+	previousOwnerID := book.OwnerID
+	book.OwnerID = newOwnerID
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := repos.Books.UpdateIfStatus(book, book.Status); err != nil {
+			return err
+		}
+		movement := edit.Movement(book.ID, &userID, book.Status)
+		movement.Notes = "Ownership transferred from " + previousOwnerID.String() + " to " + newOwnerID.String()
+		return repos.Movements.Create(movement)
+	})
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// activeExchange возвращает текущую бронь книги (запрошенную или выданную) или nil, если ее нет
+func activeExchange(repo domain.ExchangeRepository, bookID uuid.UUID) (*domain.Exchange, error) {
+	exchanges, err := repo.GetByBookID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ex := range exchanges {
+		switch ex.Status {
+		case domain.ExchangeRequested, domain.ExchangeBorrowed, domain.ExchangeOverdue:
+			return ex, nil
+		}
+	}
+	return nil, nil
+}
+
+// bookActors определяет, в каких ролях пользователь действует над книгой
+func bookActors(book *domain.Book, holder *domain.Exchange, user *domain.User) []domain.Actor {
+	var actors []domain.Actor
+	if holder != nil && holder.UserID == user.ID {
+		actors = append(actors, domain.ActorRequester)
+	}
+	if book.OwnerID == user.ID {
+		actors = append(actors, domain.ActorOwner)
+	}
+	if user.Role.Can(domain.PermBookManage) {
+		actors = append(actors, domain.ActorAdmin)
+	}
+	if user.Role.Can(domain.PermBookModerate) {
+		actors = append(actors, domain.ActorModer)
+	}
+	return actors
+}
+
+// lockBook загружает книгу и блокирует ее строку до конца транзакции пустым условным обновлением.
+// Сценарии, которые решают по очереди на книгу (вступление в очередь, продление выдачи,
+// передача следующему), так выполняются по одному, а не читают очередь одновременно.
+func lockBook(repos *domain.Repositories, bookID uuid.UUID) (*domain.Book, error) {
+	book, err := repos.Books.GetByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := repos.Books.UpdateIfStatus(book, book.Status); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// moveBook переводит книгу по переходу t. Обновление условное (WHERE status = t.From),
+// поэтому параллельный запрос, успевший изменить книгу, приведет к ErrBookConflict.
+func moveBook(repos *domain.Repositories, book *domain.Book, t *domain.BookTransition) error {
+	book.Status = t.To
+	return repos.Books.UpdateIfStatus(book, t.From)
+}
@@ -2,254 +2,479 @@ package usecase
 
 import (
 	"bookvito/internal/domain"
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// Сколько ждать внешний источник описаний изданий
+const metadataLookupTimeout = 5 * time.Second
+
 type BookUseCase struct {
 	bookRepo            domain.BookRepository
+	workRepo            domain.WorkRepository
 	movementHistoryRepo domain.BookMovementHistoryRepository
 	exchangeUseCaseRepo domain.ExchangeRepository
+	userRepo            domain.UserRepository
+	uploadRepo          domain.UploadRepository
+	metadata            domain.MetadataProvider
+	uow                 domain.UnitOfWork
+	loanPeriod          time.Duration
 }
 
-func NewBookUseCase(bookRepo domain.BookRepository, movementHistoryRepo domain.BookMovementHistoryRepository, exchangeUseCaseRepo domain.ExchangeRepository) *BookUseCase {
+func NewBookUseCase(bookRepo domain.BookRepository, workRepo domain.WorkRepository, movementHistoryRepo domain.BookMovementHistoryRepository, exchangeUseCaseRepo domain.ExchangeRepository, userRepo domain.UserRepository, uploadRepo domain.UploadRepository, metadata domain.MetadataProvider, uow domain.UnitOfWork, loanPeriod time.Duration) *BookUseCase {
 	return &BookUseCase{
 		bookRepo:            bookRepo,
+		workRepo:            workRepo,
 		movementHistoryRepo: movementHistoryRepo,
 		exchangeUseCaseRepo: exchangeUseCaseRepo,
+		userRepo:            userRepo,
+		uploadRepo:          uploadRepo,
+		metadata:            metadata,
+		uow:                 uow,
+		loanPeriod:          loanPeriod,
 	}
 }
 
 func (uc *BookUseCase) CreateBook(book *domain.Book) error {
-
-	if err := uc.bookRepo.Create(book); err != nil {
+	transition, err := domain.TransitionBook("", domain.BookAvailable, domain.ActorOwner)
+	if err != nil {
 		return err
 	}
-
-	// Создаем запись в истории перемещений
-	movement := &domain.BookMovementHistory{
-		BookID:         book.ID,
-		ToLocationID:   book.CurrentLocationID,
-		UserID:         &book.OwnerID,
-		Action:         "created",
-		Notes:          "Книга добавлена в систему",
-		PreviousStatus: "",
-		NewStatus:      domain.BookAvailable,
+	if err := uc.fillFromISBN(book); err != nil {
+		return err
 	}
-
-	if err := uc.movementHistoryRepo.Create(movement); err != nil {
+	if book.Title == "" || book.Author == "" {
+		return domain.ErrBookDetails
+	}
+	if book.Condition == "" {
+		book.Condition = domain.ConditionGood
+	}
+	if err := uc.attachImage(book, book.ImageID, book.OwnerID); err != nil {
+		return err
+	}
+	if err := uc.linkWork(book); err != nil {
 		return err
 	}
+	book.Status = transition.To
 
-	return nil
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := repos.Books.Create(book); err != nil {
+			return err
+		}
+
+		// Создаем запись в истории перемещений
+		movement := transition.Movement(book.ID, &book.OwnerID)
+		movement.ToLocationID = book.CurrentLocationID
+		movement.Notes = "Книга добавлена в систему"
+		return repos.Movements.Create(movement)
+	})
 }
 
 func (uc *BookUseCase) Request(bookID uuid.UUID, userID uuid.UUID) error {
-	book, err := uc.bookRepo.GetByID(bookID)
-	if err != nil {
+	if err := requireVerifiedEmail(uc.userRepo, userID); err != nil {
 		return err
 	}
-	if book.Status != domain.BookAvailable || book.Status == "" {
-		return errors.New("book is not available for request")
-	}
-
-	book.Status = domain.BookRequested
-	if err := uc.bookRepo.Update(book); err != nil {
+	book, err := uc.bookRepo.GetByID(bookID)
+	if err != nil {
 		return err
 	}
-
-	// Создаем запись в истории перемещений
-	movement := &domain.BookMovementHistory{
-		BookID:         book.ID,
-		UserID:         &userID,
-		Action:         "requested",
-		PreviousStatus: domain.BookAvailable,
-		NewStatus:      domain.BookRequested,
-		Notes:          "Book requested by user",
+	transition, err := domain.TransitionBook(book.Status, domain.BookRequested, domain.ActorRequester)
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		return domain.ErrBookNotAvailable
 	}
-	if err := uc.movementHistoryRepo.Create(movement); err != nil {
+	if err != nil {
 		return err
 	}
 
-	expiresAt := time.Now().Add(48 * time.Hour) // Бронь истекает через 48 часов
-
-	if err := uc.exchangeUseCaseRepo.Create(&domain.Exchange{
+	expiresAt := time.Now().Add(reservationPeriod) // Бронь истекает через 48 часов
+	exchange := &domain.Exchange{
 		UserID:    userID,
 		BookID:    bookID,
 		Status:    domain.ExchangeRequested,
 		ExpiresAt: &expiresAt,
-	}); err != nil {
-		return err
 	}
 
-	return nil
+	// Статус книги, бронь и запись в истории меняются только вместе
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := moveBook(repos, book, transition); err != nil {
+			return err
+		}
+
+		if err := repos.Exchanges.Create(exchange); err != nil {
+			return err
+		}
+
+		// Создаем запись в истории перемещений
+		movement := transition.Movement(book.ID, &userID)
+		movement.ExchangeID = &exchange.ID
+		movement.Notes = "Book requested by user"
+		return repos.Movements.Create(movement)
+	})
 }
 
 func (uc *BookUseCase) Borrow(bookID uuid.UUID, userID uuid.UUID) error {
-	book, err := uc.bookRepo.GetByID(bookID)
-	if err != nil {
+	if err := requireVerifiedEmail(uc.userRepo, userID); err != nil {
 		return err
 	}
-	if book.Status != domain.BookRequested {
-		return errors.New("book is not available for borrowing")
-	}
-	exchanges, err := uc.exchangeUseCaseRepo.GetByBookID(bookID)
+	book, holder, actors, err := uc.loadForTransition(bookID, userID)
 	if err != nil {
 		return err
 	}
-	var requesterFound bool
-	for _, ex := range exchanges {
-		if ex.UserID == userID && ex.Status == domain.ExchangeRequested {
-			requesterFound = true
-			break
-		}
-	}
-	if !requesterFound {
-		return errors.New("only the user who requested the book can borrow it")
-	}
-
-	book.Status = domain.BookBorrowed
-	if err := uc.bookRepo.Update(book); err != nil {
+	transition, err := domain.TransitionBook(book.Status, domain.BookBorrowed, actors...)
+	if err != nil {
 		return err
 	}
 
-	// Создаем запись в истории перемещений
-	movement := &domain.BookMovementHistory{
-		BookID:         book.ID,
-		UserID:         &userID,
-		Action:         "borrowed",
-		PreviousStatus: domain.BookAvailable,
-		NewStatus:      domain.BookBorrowed,
-		Notes:          "Book borrowed by user",
-	}
-	if err := uc.movementHistoryRepo.Create(movement); err != nil {
-		return err
+	// Бронь превращается в выдачу со сроком возврата. Истекшую бронь фоновая задача
+	// могла еще не отменить - выдавать по ней нельзя.
+	now := time.Now()
+	if holder.ExpiresAt != nil && holder.ExpiresAt.Before(now) {
+		return domain.ErrReservationExpired
 	}
+	dueAt := now.Add(uc.loanPeriod)
+	holder.Status = domain.ExchangeBorrowed
+	holder.BorrowedAt = &now
+	holder.DueAt = &dueAt
 
-	return nil
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := moveBook(repos, book, transition); err != nil {
+			return err
+		}
+		if err := repos.Exchanges.UpdateIfStatus(holder, domain.ExchangeRequested); err != nil {
+			return err
+		}
+
+		// Создаем запись в истории перемещений
+		movement := transition.Movement(book.ID, &userID)
+		movement.ExchangeID = &holder.ID
+		movement.Notes = "Book borrowed by user until " + dueAt.Format(time.DateOnly)
+		return repos.Movements.Create(movement)
+	})
 }
 
+// Return принимает книгу обратно. От вернувшего берутся только состояние книги и пункт выдачи:
+// описание книги меняет владелец через UpdateBook.
 func (uc *BookUseCase) Return(updatedBook *domain.Book, userID uuid.UUID) error {
-	if updatedBook.Title == "" {
-		return errors.New("book title cannot be empty")
-	}
-	if updatedBook.Author == "" {
-		return errors.New("book author cannot be empty")
+	bookFromDB, holder, actors, err := uc.loadForTransition(updatedBook.ID, userID)
+	if err != nil {
+		return err
 	}
-	println("Returning book status:", updatedBook.Status)
-
-	bookFromDB, err := uc.bookRepo.GetByID(updatedBook.ID)
+	transition, err := domain.TransitionBook(bookFromDB.Status, domain.BookAvailable, actors...)
 	if err != nil {
-		return errors.New("Oooopsss... book not found in base")
+		return err
 	}
-
-	if bookFromDB.Status != domain.BookBorrowed {
-		return errors.New("only borrowed books can be returned")
+	if transition.Action != domain.ActionReturned {
+		// Держатель брони может перевести книгу в available, но это отмена, а не возврат
+		return fmt.Errorf("%w: only borrowed books can be returned", domain.ErrInvalidTransition)
 	}
 
 	// Обновляем только нужные поля у объекта, который мы получили из БД
-	bookFromDB.Status = domain.BookAvailable
-	bookFromDB.Title = updatedBook.Title
-	bookFromDB.Author = updatedBook.Author
-	bookFromDB.Description = updatedBook.Description
-	bookFromDB.ImageURL = updatedBook.ImageURL
 	bookFromDB.CurrentLocationID = updatedBook.CurrentLocationID
 	bookFromDB.Condition = updatedBook.Condition // Обновляем состояние из запроса
 
-	if err := uc.bookRepo.Update(bookFromDB); err != nil {
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := moveBook(repos, bookFromDB, transition); err != nil {
+			return err
+		}
+
+		movement := transition.Movement(bookFromDB.ID, &userID)
+		movement.ToLocationID = bookFromDB.CurrentLocationID
+		movement.Notes = "Book returned by user"
+		if holder != nil {
+			// Выдача может быть и просроченной; фоновая задача не должна перезаписать возврат
+			expected := holder.Status
+			holder.Status = domain.ExchangeReturned
+			if err := repos.Exchanges.UpdateIfStatus(holder, expected); err != nil {
+				return err
+			}
+			movement.ExchangeID = &holder.ID
+		}
+		if err := repos.Movements.Create(movement); err != nil {
+			return err
+		}
+
+		// Книга освободилась - отдаем ее следующему в очереди
+		return promoteNextInLine(repos, bookFromDB)
+	})
+}
+
+func (uc *BookUseCase) DeleteBook(bookID, userID uuid.UUID) error {
+	// Пометить книгу удаленной (утерянной) может тот, кто ее взял, а также модератор или админ
+	book, holder, actors, err := uc.loadForTransition(bookID, userID)
+	if err != nil {
 		return err
 	}
+	transition, err := domain.TransitionBook(book.Status, domain.BookDeleted, actors...)
+	if err != nil {
+		return err
+	}
+
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := moveBook(repos, book, transition); err != nil {
+			return err
+		}
 
-	movement := &domain.BookMovementHistory{
-		BookID:         bookFromDB.ID,
-		UserID:         &userID,
-		Action:         "returned",
-		PreviousStatus: domain.BookBorrowed,
-		NewStatus:      domain.BookAvailable,
-		Notes:          "Book returned by user",
+		// Создаем запись в истории об этом событии
+		movement := transition.Movement(bookID, &userID) // Пользователь, который выполнил действие
+		if holder != nil {
+			expected := holder.Status
+			holder.Status = domain.ExchangeCancelled
+			if err := repos.Exchanges.UpdateIfStatus(holder, expected); err != nil {
+				return err
+			}
+			movement.ExchangeID = &holder.ID
+		}
+		if err := repos.Movements.Create(movement); err != nil {
+			return err
+		}
+
+		// Книга больше не вернется в оборот, ждать ее бессмысленно
+		return clearWaitlist(repos, bookID)
+	})
+}
+
+// linkWork привязывает книгу к произведению с теми же названием и автором, создавая его при необходимости.
+// Произведение создается вне транзакции книги: без экземпляров оно просто остается в каталоге.
+func (uc *BookUseCase) linkWork(book *domain.Book) error {
+	key := domain.WorkKey(book.Title, book.Author)
+	work, err := uc.workRepo.GetByKey(key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		work = domain.NewWork(book)
+		err = uc.workRepo.Create(work)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// Произведение успели создать параллельно
+			work, err = uc.workRepo.GetByKey(key)
+		}
 	}
-	if err := uc.movementHistoryRepo.Create(movement); err != nil {
+	if err != nil {
 		return err
 	}
-	if err := uc.exchangeUseCaseRepo.Delete(bookFromDB.ID); err != nil {
-		return err
+	book.WorkID = &work.ID
+	return nil
+}
+
+// relinkWork переносит книгу к другому произведению, если изменились название или автор
+// (previousKey - WorkKey до изменения)
+func (uc *BookUseCase) relinkWork(book *domain.Book, previousKey string) error {
+	if book.WorkID != nil && domain.WorkKey(book.Title, book.Author) == previousKey {
+		return nil
 	}
+	return uc.linkWork(book)
+}
 
-	return nil
+// LookupISBN ищет описание издания по ISBN-10 или ISBN-13
+func (uc *BookUseCase) LookupISBN(isbn string) (*domain.BookMetadata, error) {
+	isbn, err := domain.NormalizeISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+	if uc.metadata == nil {
+		return nil, domain.ErrMetadataNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), metadataLookupTimeout)
+	defer cancel()
+	return uc.metadata.LookupISBN(ctx, isbn)
 }
 
-func (uc *BookUseCase) DeleteBook(bookID, userID uuid.UUID) error {
-	book, err := uc.bookRepo.GetByID(bookID)
+// fillFromISBN приводит ISBN книги к ISBN-13 и заполняет пустые поля описанием издания.
+// Если издание неизвестно или источник недоступен, книга создается из того, что ввел пользователь.
+func (uc *BookUseCase) fillFromISBN(book *domain.Book) error {
+	if book.ISBN == "" {
+		return nil
+	}
+	isbn, err := domain.NormalizeISBN(book.ISBN)
+	if err != nil {
+		return err
+	}
+	book.ISBN = isbn
 
+	meta, err := uc.LookupISBN(isbn)
+	if errors.Is(err, domain.ErrMetadataNotFound) {
+		return nil
+	}
 	if err != nil {
-		return errors.New("book not found")
+		if book.Title == "" || book.Author == "" {
+			return err
+		}
+		log.Printf("ISBN %s lookup failed, using book details from the request: %v", isbn, err)
+		return nil
 	}
 
-	// Новая проверка: книгу может удалить только тот, кто ее взял, и только если она в статусе "borrowed".
-	if book.Status != domain.BookBorrowed {
-		return errors.New("only a borrowed book can be marked as deleted by the borrower")
+	if book.Title == "" {
+		book.Title = meta.Title
+	}
+	if book.Author == "" {
+		book.Author = meta.Author
+	}
+	if book.Description == "" {
+		book.Description = meta.Description
 	}
+	if book.ImageURL == "" && book.ImageID == nil {
+		book.ImageURL = meta.CoverURL
+	}
+	return nil
+}
 
-	// Получаем последнюю запись в истории, чтобы проверить, кто взял книгу.
-	history, err := uc.movementHistoryRepo.GetByBookID(bookID)
-	if err != nil || len(history) == 0 {
-		return errors.New("could not verify book history")
+// attachImage делает загрузку imageID обложкой книги: ImageURL указывает на ее полноразмерный вариант.
+// Использовать можно только свои загрузки.
+func (uc *BookUseCase) attachImage(book *domain.Book, imageID *uuid.UUID, userID uuid.UUID) error {
+	if imageID == nil {
+		return nil
 	}
+	upload, err := uc.uploadRepo.GetByID(*imageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrUnknownUpload
+	}
+	if err != nil {
+		return err
+	}
+	if upload.OwnerID != userID {
+		return fmt.Errorf("%w: image was uploaded by another user", domain.ErrForbidden)
+	}
+	book.ImageID = &upload.ID
+	book.ImageURL = domain.ImageURL(upload.ID, domain.ImageFull)
+	return nil
+}
 
-	lastAction := history[0] // История отсортирована по убыванию даты
-	if lastAction.Action != "borrowed" || lastAction.UserID == nil || *lastAction.UserID != userID {
-		return errors.New("you are not the last user who borrowed this book")
+// requireVerifiedEmail не дает брать книги и вставать в очередь, пока пользователь не подтвердил почту
+func requireVerifiedEmail(users domain.UserRepository, userID uuid.UUID) error {
+	user, err := users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return domain.ErrEmailNotVerified
 	}
+	return nil
+}
 
-	// Старая проверка на владельца или админа (оставлена для справки)
-	// if book.Owner.Role != domain.RoleAdmin && book.OwnerID != userID {
-	// 	return errors.New("no rules to delete this book")
-	// }
+// loadForTransition загружает книгу, ее текущую бронь и роли пользователя относительно книги
+func (uc *BookUseCase) loadForTransition(bookID, userID uuid.UUID) (*domain.Book, *domain.Exchange, []domain.Actor, error) {
+	book, err := uc.bookRepo.GetByID(bookID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	holder, err := activeExchange(uc.exchangeUseCaseRepo, bookID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return book, holder, bookActors(book, holder, user), nil
+}
 
-	previousStatus := book.Status
+func (uc *BookUseCase) GetSummaryBooksList(page domain.PageRequest) (*domain.Page[*domain.BookSummary], error) {
+	return listByTime(page, domain.CursorBooks, bookSummaryKey, uc.bookRepo.GetSummaryList, uc.bookRepo.Count)
+}
 
-	book.Status = domain.BookDeleted
+func (uc *BookUseCase) GetBooksList(page domain.PageRequest) (*domain.Page[*domain.Book], error) {
+	return listByTime(page, domain.CursorBooks, bookKey, uc.bookRepo.List, uc.bookRepo.Count)
+}
 
-	if err := uc.bookRepo.Update(book); err != nil {
-		return err
+// SearchBooks выполняет полнотекстовый поиск по каталогу
+func (uc *BookUseCase) SearchBooks(query string, limit, offset int) (*domain.BookSearchPage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, domain.ErrEmptySearchQuery
 	}
 
-	// 5. Создаем запись в истории об этом событии
-	movement := &domain.BookMovementHistory{
-		BookID:         bookID,
-		UserID:         &userID, // Пользователь, который выполнил действие
-		Action:         "deleted",
-		PreviousStatus: previousStatus,
-		NewStatus:      domain.BookDeleted,
+	items, total, err := uc.bookRepo.Search(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []*domain.BookSearchResult{}
 	}
+	return &domain.BookSearchPage{Items: items, Total: total, Limit: limit, Offset: offset}, nil
+}
 
-	// 6. Сохраняем запись в истории
-	if err := uc.movementHistoryRepo.Create(movement); err != nil {
-		return err
+// ListBooks возвращает страницу каталога вместе со счетчиками для фильтров
+func (uc *BookUseCase) ListBooks(filter domain.BookFilter, req domain.PageRequest) (*domain.BookListPage, error) {
+	if err := normalizeBookFilter(&filter); err != nil {
+		return nil, err
 	}
-	if err := uc.exchangeUseCaseRepo.Delete(bookID); err != nil {
-		return err
+	after, err := domain.DecodeCursor(req.Cursor, filter.CursorKind())
+	if err != nil {
+		return nil, err
 	}
+	limit := pageLimit(req.Limit)
+	filter.After, filter.Limit = after, limit+1
 
-	return nil
+	items, err := uc.bookRepo.Filter(filter)
+	if err != nil {
+		return nil, err
+	}
+	page := newPage(items, limit, filter.Cursor)
+	err = withTotal(page, req, func() (int64, error) { return uc.bookRepo.CountFiltered(filter) })
+	if err != nil {
+		return nil, err
+	}
 
+	facets, err := uc.bookRepo.Facets(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.BookListPage{Page: *page, Facets: facets}, nil
 }
 
-func (uc *BookUseCase) GetSummaryBooksList() ([]*domain.BookSummary, error) {
-	return uc.bookRepo.GetSummaryList(100, 0)
-}
+// normalizeBookFilter проверяет значения фильтров и подставляет сортировку по умолчанию
+func normalizeBookFilter(filter *domain.BookFilter) error {
+	if filter.Sort == "" {
+		filter.Sort = domain.BookSortCreatedAt
+		filter.Desc = filter.Sort.DefaultDesc()
+	}
+	if !filter.Sort.Valid() {
+		return fmt.Errorf("%w: unknown sort %q", domain.ErrInvalidBookFilter, filter.Sort)
+	}
+	for _, s := range filter.Statuses {
+		switch s {
+		case domain.BookAvailable, domain.BookRequested, domain.BookBorrowed, domain.BookArchived:
+		default:
+			return fmt.Errorf("%w: unknown status %q", domain.ErrInvalidBookFilter, s)
+		}
+	}
+	for _, c := range filter.Conditions {
+		switch c {
+		case domain.ConditionExcellent, domain.ConditionGood, domain.ConditionBad:
+		default:
+			return fmt.Errorf("%w: unknown condition %q", domain.ErrInvalidBookFilter, c)
+		}
+	}
 
-func (uc *BookUseCase) GetBooksList() ([]*domain.Book, error) {
-	return uc.bookRepo.List(100, 0)
+	authors := filter.Authors[:0]
+	for _, a := range filter.Authors {
+		if a = strings.TrimSpace(a); a != "" {
+			authors = append(authors, a)
+		}
+	}
+	filter.Authors = authors
 
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return fmt.Errorf("%w: created_from must be before created_to", domain.ErrInvalidBookFilter)
+	}
+	return nil
 }
 
 func (uc *BookUseCase) GetBookByID(bookID uuid.UUID) (*domain.Book, error) {
 	return uc.bookRepo.GetByID(bookID)
 }
 
-func (uc *BookUseCase) GetBookMovementHistory(bookID uuid.UUID) ([]*domain.BookMovementHistory, error) {
-	return uc.movementHistoryRepo.GetByBookID(bookID)
+func (uc *BookUseCase) GetBookMovementHistory(bookID uuid.UUID, page domain.PageRequest) (*domain.Page[*domain.BookMovementHistory], error) {
+	if _, err := uc.bookRepo.GetByID(bookID); err != nil {
+		return nil, err
+	}
+	return listByTime(page, domain.CursorMovements, movementKey,
+		func(q domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+			return uc.movementHistoryRepo.ListByBookID(bookID, q)
+		},
+		func() (int64, error) { return uc.movementHistoryRepo.CountByBookID(bookID) })
 }
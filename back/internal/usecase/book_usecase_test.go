@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeBookRepo хранит книги в памяти; UpdateIfStatus атомарен, как условный UPDATE в Postgres
+type fakeBookRepo struct {
+	domain.BookRepository
+	mu    sync.Mutex
+	books map[uuid.UUID]domain.Book
+}
+
+func (r *fakeBookRepo) GetByID(id uuid.UUID) (*domain.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	book, ok := r.books[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &book, nil
+}
+
+func (r *fakeBookRepo) UpdateIfStatus(book *domain.Book, expected domain.BookStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.books[book.ID].Status != expected {
+		return domain.ErrBookConflict
+	}
+	r.books[book.ID] = *book
+	return nil
+}
+
+type fakeExchangeRepo struct {
+	domain.ExchangeRepository
+	mu        sync.Mutex
+	exchanges []domain.Exchange
+}
+
+func (r *fakeExchangeRepo) Create(exchange *domain.Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exchange.ID = uuid.New()
+	r.exchanges = append(r.exchanges, *exchange)
+	return nil
+}
+
+type fakeMovementRepo struct {
+	domain.BookMovementHistoryRepository
+}
+
+func (r *fakeMovementRepo) Create(movement *domain.BookMovementHistory) error {
+	return nil
+}
+
+// fakeUserRepo возвращает любого пользователя с подтвержденной почтой
+type fakeUserRepo struct {
+	domain.UserRepository
+}
+
+func (r *fakeUserRepo) GetByID(id uuid.UUID) (*domain.User, error) {
+	verifiedAt := time.Now()
+	return &domain.User{ID: id, EmailVerifiedAt: &verifiedAt}, nil
+}
+
+type fakeUnitOfWork struct {
+	repos *domain.Repositories
+}
+
+func (u *fakeUnitOfWork) Do(fn func(repos *domain.Repositories) error) error {
+	return fn(u.repos)
+}
+
+func TestRequestConcurrentOnlyOneWins(t *testing.T) {
+	bookID := uuid.New()
+	books := &fakeBookRepo{books: map[uuid.UUID]domain.Book{
+		bookID: {ID: bookID, Title: "Мастер и Маргарита", Author: "Булгаков", Status: domain.BookAvailable},
+	}}
+	exchanges := &fakeExchangeRepo{}
+	movements := &fakeMovementRepo{}
+	uow := &fakeUnitOfWork{repos: &domain.Repositories{Books: books, Exchanges: exchanges, Movements: movements}}
+	uc := NewBookUseCase(books, nil, movements, exchanges, &fakeUserRepo{}, nil, nil, uow, 14*24*time.Hour)
+
+	const requests = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- uc.Request(bookID, uuid.New())
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	var won int
+	for err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case errors.Is(err, domain.ErrBookConflict), errors.Is(err, domain.ErrBookNotAvailable):
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if won != 1 {
+		t.Fatalf("expected exactly one successful request, got %d", won)
+	}
+	if len(exchanges.exchanges) != 1 {
+		t.Fatalf("expected exactly one exchange, got %d", len(exchanges.exchanges))
+	}
+	if status := books.books[bookID].Status; status != domain.BookRequested {
+		t.Fatalf("expected book status %q, got %q", domain.BookRequested, status)
+	}
+}
@@ -2,7 +2,21 @@ package usecase
 
 import (
 	"bookvito/internal/domain"
+	"context"
+	"errors"
 	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// reservationPeriod - сколько действует бронь после запроса книги
+	reservationPeriod = 48 * time.Hour
+	// reservationExtension - на сколько продлевается бронь за один раз
+	reservationExtension = 24 * time.Hour
+	// maxReservationPeriod - максимальный срок брони с учетом всех продлений
+	maxReservationPeriod = 96 * time.Hour
 )
 
 type ExchangeUseCase struct {
@@ -10,20 +24,182 @@ type ExchangeUseCase struct {
 	bookRepo     domain.BookRepository
 	userRepo     domain.UserRepository
 	movementRepo domain.BookMovementHistoryRepository
+	uow          domain.UnitOfWork
+
+	renewalPeriod time.Duration
+	maxRenewals   int
 }
 
 // NewExchangeUseCase creates a new exchange use case
-func NewExchangeUseCase(exchangeRepo domain.ExchangeRepository, bookRepo domain.BookRepository, userRepo domain.UserRepository, movementRepo domain.BookMovementHistoryRepository) *ExchangeUseCase {
+func NewExchangeUseCase(exchangeRepo domain.ExchangeRepository, bookRepo domain.BookRepository, userRepo domain.UserRepository, movementRepo domain.BookMovementHistoryRepository, uow domain.UnitOfWork, renewalPeriod time.Duration, maxRenewals int) *ExchangeUseCase {
 	return &ExchangeUseCase{
-		exchangeRepo: exchangeRepo,
-		bookRepo:     bookRepo,
-		userRepo:     userRepo,
-		movementRepo: movementRepo,
+		exchangeRepo:  exchangeRepo,
+		bookRepo:      bookRepo,
+		userRepo:      userRepo,
+		movementRepo:  movementRepo,
+		uow:           uow,
+		renewalPeriod: renewalPeriod,
+		maxRenewals:   maxRenewals,
+	}
+}
+
+// GetExchangeByID возвращает бронирование, если его запрашивает автор брони или владелец книги
+func (uc *ExchangeUseCase) GetExchangeByID(exchangeID, userID uuid.UUID) (*domain.Exchange, error) {
+	exchange, err := uc.exchangeRepo.GetByID(exchangeID)
+	if err != nil {
+		return nil, err
+	}
+	// Участникам бронь видна всегда, остальным - только с правом PermExchangeReadAll
+	if exchange.UserID != userID && exchange.Book.OwnerID != userID {
+		if _, err := authorize(uc.userRepo, userID, domain.PermExchangeReadAll); err != nil {
+			return nil, err
+		}
+	}
+	return exchange, nil
+}
+
+// GetUserExchanges возвращает страницу бронирований пользователя ("мои брони")
+func (uc *ExchangeUseCase) GetUserExchanges(userID uuid.UUID, page domain.PageRequest) (*domain.Page[*domain.Exchange], error) {
+	return listByTime(page, domain.CursorExchanges, exchangeKey,
+		func(q domain.PageQuery) ([]*domain.Exchange, error) { return uc.exchangeRepo.ListByUserID(userID, q) },
+		func() (int64, error) { return uc.exchangeRepo.CountByUserID(userID) })
+}
+
+// ListUserExchanges возвращает страницу бронирований любого пользователя (для модераторов)
+func (uc *ExchangeUseCase) ListUserExchanges(actorID, userID uuid.UUID, page domain.PageRequest) (*domain.Page[*domain.Exchange], error) {
+	if _, err := authorize(uc.userRepo, actorID, domain.PermExchangeReadAll); err != nil {
+		return nil, err
+	}
+	if _, err := uc.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	return uc.GetUserExchanges(userID, page)
+}
+
+// GetBookExchanges возвращает страницу истории бронирований книги; доступно только владельцу
+func (uc *ExchangeUseCase) GetBookExchanges(bookID, userID uuid.UUID, page domain.PageRequest) (*domain.Page[*domain.Exchange], error) {
+	book, err := uc.bookRepo.GetByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.OwnerID != userID {
+		return nil, domain.ErrForbidden
+	}
+	return listByTime(page, domain.CursorExchanges, exchangeKey,
+		func(q domain.PageQuery) ([]*domain.Exchange, error) { return uc.exchangeRepo.ListByBookID(bookID, q) },
+		func() (int64, error) { return uc.exchangeRepo.CountByBookID(bookID) })
+}
+
+// CancelExchange отменяет активную бронь по просьбе того, кто ее сделал
+func (uc *ExchangeUseCase) CancelExchange(exchangeID, userID uuid.UUID) error {
+	exchange, err := uc.exchangeRepo.GetByID(exchangeID)
+	if err != nil {
+		return err
+	}
+	if exchange.UserID != userID {
+		return domain.ErrForbidden
 	}
+	if exchange.Status != domain.ExchangeRequested {
+		return domain.ErrExchangeNotActive
+	}
+
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		return cancelReservation(repos, exchange, domain.ActorRequester, &userID, "Book request cancelled by user")
+	})
+}
+
+// ExtendExchange продлевает бронь или выдачу, в зависимости от того, на каком этапе обмен
+func (uc *ExchangeUseCase) ExtendExchange(exchangeID, userID uuid.UUID) (*domain.Exchange, error) {
+	exchange, err := uc.exchangeRepo.GetByID(exchangeID)
+	if err != nil {
+		return nil, err
+	}
+	if exchange.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+
+	switch exchange.Status {
+	case domain.ExchangeRequested:
+		return uc.extendReservation(exchange)
+	case domain.ExchangeBorrowed:
+		return uc.renewLoan(exchange)
+	case domain.ExchangeOverdue:
+		return nil, domain.ErrLoanOverdue
+	default:
+		return nil, domain.ErrExchangeNotActive
+	}
+}
+
+// extendReservation продлевает бронь, но не дольше maxReservationPeriod с момента бронирования
+func (uc *ExchangeUseCase) extendReservation(exchange *domain.Exchange) (*domain.Exchange, error) {
+	if exchange.ExpiresAt == nil || exchange.ExpiresAt.Before(time.Now()) {
+		return nil, domain.ErrExchangeNotActive
+	}
+
+	expiresAt := exchange.ExpiresAt.Add(reservationExtension)
+	if expiresAt.After(exchange.BookedAt.Add(maxReservationPeriod)) {
+		return nil, domain.ErrExchangeExtendLimit
+	}
+
+	exchange.ExpiresAt = &expiresAt
+	if err := uc.exchangeRepo.UpdateIfStatus(exchange, domain.ExchangeRequested); err != nil {
+		return nil, err
+	}
+	return exchange, nil
+}
+
+// renewLoan сдвигает срок возврата на renewalPeriod, если лимит продлений не исчерпан и книгу никто не ждет
+func (uc *ExchangeUseCase) renewLoan(exchange *domain.Exchange) (*domain.Exchange, error) {
+	if exchange.DueAt == nil {
+		return nil, domain.ErrExchangeNotActive
+	}
+	if exchange.Renewals >= uc.maxRenewals {
+		return nil, domain.ErrRenewalLimit
+	}
+
+	dueAt := exchange.DueAt.Add(uc.renewalPeriod)
+	exchange.DueAt = &dueAt
+	exchange.Renewals++
+
+	// Строка книги блокируется до чтения очереди: JoinWaitlist блокирует ее же, поэтому
+	// никто не встанет в очередь, пока идет продление. Выдача обновляется, только если
+	// ее еще не вернули и не пометили просроченной.
+	err := uc.uow.Do(func(repos *domain.Repositories) error {
+		if _, err := lockBook(repos, exchange.BookID); err != nil {
+			return err
+		}
+		waiting, err := repos.Waitlist.GetByBookID(exchange.BookID)
+		if err != nil {
+			return err
+		}
+		if len(waiting) > 0 {
+			return domain.ErrRenewalBlocked
+		}
+		if err := repos.Exchanges.UpdateIfStatus(exchange, domain.ExchangeBorrowed); err != nil {
+			return err
+		}
+
+		movement := &domain.BookMovementHistory{
+			BookID:         exchange.BookID,
+			ExchangeID:     &exchange.ID,
+			UserID:         &exchange.UserID,
+			Action:         domain.ActionRenewed,
+			Notes:          "Loan renewed until " + dueAt.Format(time.DateOnly),
+			PreviousStatus: domain.BookBorrowed,
+			NewStatus:      domain.BookBorrowed,
+		}
+		return repos.Movements.Create(movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exchange, nil
 }
 
 // CancelExpiredExchanges находит и отменяет все просроченные бронирования.
-func (uc *ExchangeUseCase) CancelExpiredExchanges() error {
+// При отмене ctx (остановка сервиса) обработка прерывается между бронями: каждая бронь
+// отменяется в своей транзакции, поэтому незавершенных изменений не остается.
+func (uc *ExchangeUseCase) CancelExpiredExchanges(ctx context.Context) error {
 	expiredExchanges, err := uc.exchangeRepo.GetExpired()
 	if err != nil {
 		return err
@@ -35,39 +211,112 @@ func (uc *ExchangeUseCase) CancelExpiredExchanges() error {
 	log.Printf("Found %d expired exchanges to cancel.", len(expiredExchanges))
 
 	for _, exchange := range expiredExchanges {
-		// 1. Обновляем статус бронирования на "отменено"
-		exchange.Status = domain.ExchangeCancelled
-		if err := uc.exchangeRepo.Update(exchange); err != nil {
-			// Логируем ошибку, но продолжаем, чтобы не остановить весь процесс
-			log.Printf("failed to update expired exchange %s: %v", exchange.ID, err)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Каждая бронь отменяется в своей транзакции, чтобы ошибка по одной не откатывала остальные
+		err := uc.uow.Do(func(repos *domain.Repositories) error {
+			return cancelReservation(repos, exchange, domain.ActorSystem, nil, "Book request expired and was automatically cancelled")
+		})
+		if errors.Is(err, domain.ErrExchangeConflict) {
+			// Бронь успели выдать или отменить после выборки - отменять уже нечего
 			continue
 		}
-
-		// 2. Возвращаем книге статус "доступна"
-		book, err := uc.bookRepo.GetByID(exchange.BookID)
 		if err != nil {
-			log.Printf("failed to get book %s for expired exchange %s: %v", exchange.BookID, exchange.ID, err)
-			continue
+			// Логируем ошибку, но продолжаем, чтобы не остановить весь процесс
+			log.Printf("failed to cancel expired exchange %s: %v", exchange.ID, err)
 		}
+	}
+	return nil
+}
+
+// MarkOverdueExchanges помечает просроченными выдачи, срок возврата которых истек, и пишет это в историю книги
+func (uc *ExchangeUseCase) MarkOverdueExchanges(ctx context.Context) error {
+	overdue, err := uc.exchangeRepo.GetOverdue()
+	if err != nil {
+		return err
+	}
+	if len(overdue) == 0 {
+		return nil
+	}
+	log.Printf("Found %d overdue loans.", len(overdue))
 
-		// Убедимся, что мы не меняем статус книги, которая уже была взята или возвращена
-		if book.Status == domain.BookRequested {
-			book.Status = domain.BookAvailable
-			if err := uc.bookRepo.Update(book); err != nil {
-				log.Printf("failed to update book status for book %s: %v", book.ID, err)
-				continue
+	for _, exchange := range overdue {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := uc.uow.Do(func(repos *domain.Repositories) error {
+			// Выборка сделана вне транзакции: книгу могли успеть вернуть
+			exchange.Status = domain.ExchangeOverdue
+			if err := repos.Exchanges.UpdateIfStatus(exchange, domain.ExchangeBorrowed); err != nil {
+				return err
 			}
 
-			// 3. Создаем запись в истории перемещений
+			// Статус книги не меняется: она все еще у читателя
 			movement := &domain.BookMovementHistory{
-				BookID:         book.ID,
-				Action:         "request_cancelled",
-				Notes:          "Book request expired and was automatically cancelled",
-				PreviousStatus: domain.BookRequested,
-				NewStatus:      domain.BookAvailable,
+				BookID:         exchange.BookID,
+				ExchangeID:     &exchange.ID,
+				UserID:         &exchange.UserID,
+				Action:         domain.ActionOverdue,
+				Notes:          "Loan is overdue since " + exchange.DueAt.Format(time.DateOnly),
+				PreviousStatus: domain.BookBorrowed,
+				NewStatus:      domain.BookBorrowed,
 			}
-			uc.movementRepo.Create(movement) // Ошибку можно логировать, но она не критична для основного потока
+			return repos.Movements.Create(movement)
+		})
+		if errors.Is(err, domain.ErrExchangeConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to mark exchange %s as overdue: %v", exchange.ID, err)
 		}
 	}
 	return nil
 }
+
+// GetOverdueExchanges возвращает просроченные выдачи (для модераторов)
+func (uc *ExchangeUseCase) GetOverdueExchanges(userID uuid.UUID) ([]*domain.Exchange, error) {
+	if _, err := authorize(uc.userRepo, userID, domain.PermExchangeReadAll); err != nil {
+		return nil, err
+	}
+	return uc.exchangeRepo.GetByStatus(domain.ExchangeOverdue)
+}
+
+// cancelReservation отменяет бронь и, если книга все еще ждет выдачи, возвращает ей статус "доступна".
+// userID равен nil, когда бронь отменяется системой.
+func cancelReservation(repos *domain.Repositories, exchange *domain.Exchange, actor domain.Actor, userID *uuid.UUID, notes string) error {
+	// 1. Обновляем статус бронирования на "отменено", только если бронь еще не выдана и не отменена
+	exchange.Status = domain.ExchangeCancelled
+	if err := repos.Exchanges.UpdateIfStatus(exchange, domain.ExchangeRequested); err != nil {
+		return err
+	}
+
+	// 2. Возвращаем книге статус "доступна"
+	book, err := repos.Books.GetByID(exchange.BookID)
+	if err != nil {
+		return err
+	}
+
+	// Убедимся, что мы не меняем статус книги, которая уже была взята или возвращена
+	if book.Status != domain.BookRequested {
+		return nil
+	}
+	transition, err := domain.TransitionBook(book.Status, domain.BookAvailable, actor)
+	if err != nil {
+		return err
+	}
+	if err := moveBook(repos, book, transition); err != nil {
+		return err
+	}
+
+	// 3. Создаем запись в истории перемещений
+	movement := transition.Movement(book.ID, userID)
+	movement.ExchangeID = &exchange.ID
+	movement.Notes = notes
+	if err := repos.Movements.Create(movement); err != nil {
+		return err
+	}
+
+	// 4. Книга освободилась - отдаем ее следующему в очереди
+	return promoteNextInLine(repos, book)
+}
@@ -9,15 +9,20 @@ import (
 
 type LocationUseCase struct {
 	locationRepo domain.LocationRepository
+	userRepo     domain.UserRepository
 }
 
-func NewLocationUseCase(locationRepo domain.LocationRepository) *LocationUseCase {
+func NewLocationUseCase(locationRepo domain.LocationRepository, userRepo domain.UserRepository) *LocationUseCase {
 	return &LocationUseCase{
 		locationRepo: locationRepo,
+		userRepo:     userRepo,
 	}
 }
 
-func (uc *LocationUseCase) Create(location *domain.Location) error {
+func (uc *LocationUseCase) Create(userID uuid.UUID, location *domain.Location) error {
+	if _, err := authorize(uc.userRepo, userID, domain.PermLocationWrite); err != nil {
+		return err
+	}
 	return uc.locationRepo.Create(location)
 }
 
@@ -29,10 +34,20 @@ func (uc *LocationUseCase) GetAll() ([]domain.Location, error) {
 	return uc.locationRepo.GetAll()
 }
 
-func (uc *LocationUseCase) Update(location *domain.Location) error {
+func (uc *LocationUseCase) Update(userID uuid.UUID, location *domain.Location) error {
+	if _, err := authorize(uc.userRepo, userID, domain.PermLocationWrite); err != nil {
+		return err
+	}
+	// Save в postgres вставил бы несуществующий пункт, поэтому сначала проверяем, что он есть
+	if _, err := uc.locationRepo.GetByID(location.ID); err != nil {
+		return err
+	}
 	return uc.locationRepo.Update(location)
 }
 
-func (uc *LocationUseCase) Delete(id uuid.UUID) error {
+func (uc *LocationUseCase) Delete(userID, id uuid.UUID) error {
+	if _, err := authorize(uc.userRepo, userID, domain.PermLocationWrite); err != nil {
+		return err
+	}
 	return uc.locationRepo.Delete(id)
 }
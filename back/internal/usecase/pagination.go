@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pageLimit приводит размер страницы к допустимому диапазону
+func pageLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return domain.DefaultPageLimit
+	case limit > domain.MaxPageLimit:
+		return domain.MaxPageLimit
+	}
+	return limit
+}
+
+// newPage собирает страницу из выборки на limit+1 записей: лишняя запись означает, что есть следующая страница
+func newPage[T any](items []T, limit int, cursor func(T) domain.Cursor) *domain.Page[T] {
+	page := &domain.Page[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.HasMore = true
+		page.NextCursor = cursor(page.Items[limit-1]).Encode()
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// listByTime - общий путь списков, упорядоченных по (время, id): разбирает курсор,
+// запрашивает limit+1 записей и по просьбе клиента считает общее число записей
+func listByTime[T any](
+	req domain.PageRequest,
+	kind string,
+	key func(T) (time.Time, uuid.UUID),
+	list func(domain.PageQuery) ([]T, error),
+	count func() (int64, error),
+) (*domain.Page[T], error) {
+	after, err := domain.DecodeCursor(req.Cursor, kind)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil && after.Time == nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	limit := pageLimit(req.Limit)
+	items, err := list(domain.PageQuery{After: after, Limit: limit + 1})
+	if err != nil {
+		return nil, err
+	}
+	page := newPage(items, limit, func(item T) domain.Cursor {
+		t, id := key(item)
+		return domain.TimeCursor(kind, t, id)
+	})
+	return page, withTotal(page, req, count)
+}
+
+// withTotal заполняет Total, если клиент его запросил
+func withTotal[T any](page *domain.Page[T], req domain.PageRequest, count func() (int64, error)) error {
+	if !req.WithTotal {
+		return nil
+	}
+	total, err := count()
+	if err != nil {
+		return err
+	}
+	page.Total = &total
+	return nil
+}
+
+// Ключи курсоров для списков по времени
+
+func userKey(u *domain.User) (time.Time, uuid.UUID) { return u.CreatedAt, u.ID }
+
+func bookKey(b *domain.Book) (time.Time, uuid.UUID) { return b.CreatedAt, b.ID }
+
+func bookSummaryKey(b *domain.BookSummary) (time.Time, uuid.UUID) { return b.CreatedAt, b.ID }
+
+func exchangeKey(e *domain.Exchange) (time.Time, uuid.UUID) { return e.BookedAt, e.ID }
+
+func movementKey(m *domain.BookMovementHistory) (time.Time, uuid.UUID) { return m.CreatedAt, m.ID }
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+type ReviewUseCase struct {
+	reviewRepo   domain.ReviewRepository
+	bookRepo     domain.BookRepository
+	movementRepo domain.BookMovementHistoryRepository
+}
+
+// NewReviewUseCase creates a new review use case
+func NewReviewUseCase(reviewRepo domain.ReviewRepository, bookRepo domain.BookRepository, movementRepo domain.BookMovementHistoryRepository) *ReviewUseCase {
+	return &ReviewUseCase{
+		reviewRepo:   reviewRepo,
+		bookRepo:     bookRepo,
+		movementRepo: movementRepo,
+	}
+}
+
+func (uc *ReviewUseCase) CreateReview(bookID, userID uuid.UUID, rating int16, text string) (*domain.Review, error) {
+	if err := validateRating(rating); err != nil {
+		return nil, err
+	}
+	book, err := uc.bookRepo.GetByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	copies, err := uc.workCopies(book)
+	if err != nil {
+		return nil, err
+	}
+
+	// Один пользователь - один отзыв на произведение, каким бы экземпляром он ни читал
+	reviews, err := uc.reviewRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reviews {
+		if copies[r.BookID] || sameWork(&r.Book, book) {
+			return nil, domain.ErrReviewExists
+		}
+	}
+
+	// Оставить отзыв может только тот, кто действительно брал один из экземпляров
+	borrowed, err := uc.hasBorrowed(copies, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !borrowed {
+		return nil, domain.ErrReviewNotAllowed
+	}
+
+	review := &domain.Review{
+		BookID: bookID,
+		UserID: userID,
+		Rating: rating,
+		Text:   text,
+	}
+	if err := uc.reviewRepo.Create(review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (uc *ReviewUseCase) UpdateReview(reviewID, userID uuid.UUID, rating int16, text string) (*domain.Review, error) {
+	if err := validateRating(rating); err != nil {
+		return nil, err
+	}
+	review, err := uc.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+
+	review.Rating = rating
+	review.Text = text
+	if err := uc.reviewRepo.Update(review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (uc *ReviewUseCase) DeleteReview(reviewID, userID uuid.UUID) error {
+	review, err := uc.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return err
+	}
+	if review.UserID != userID {
+		return domain.ErrForbidden
+	}
+	return uc.reviewRepo.Delete(reviewID)
+}
+
+// GetBookReviews возвращает отзывы на произведение, экземпляром которого является книга
+func (uc *ReviewUseCase) GetBookReviews(bookID uuid.UUID) ([]domain.Review, error) {
+	book, err := uc.bookRepo.GetByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.WorkID == nil {
+		return uc.reviewRepo.GetByBookID(bookID)
+	}
+	return uc.reviewRepo.GetByWorkID(*book.WorkID)
+}
+
+// workCopies возвращает ID всех экземпляров произведения, к которому относится книга
+func (uc *ReviewUseCase) workCopies(book *domain.Book) (map[uuid.UUID]bool, error) {
+	copies := map[uuid.UUID]bool{book.ID: true}
+	if book.WorkID == nil {
+		return copies, nil
+	}
+	books, err := uc.bookRepo.GetByWorkID(*book.WorkID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range books {
+		copies[b.ID] = true
+	}
+	return copies, nil
+}
+
+func sameWork(a, b *domain.Book) bool {
+	return a.WorkID != nil && b.WorkID != nil && *a.WorkID == *b.WorkID
+}
+
+// hasBorrowed проверяет по истории перемещений, брал ли пользователь один из экземпляров
+func (uc *ReviewUseCase) hasBorrowed(copies map[uuid.UUID]bool, userID uuid.UUID) (bool, error) {
+	history, err := uc.movementRepo.GetByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, movement := range history {
+		if copies[movement.BookID] && movement.Action == domain.ActionBorrowed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func validateRating(rating int16) error {
+	if rating < 1 || rating > 5 {
+		return domain.ErrInvalidRating
+	}
+	return nil
+}
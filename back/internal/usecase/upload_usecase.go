@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"bookvito/pkg/imaging"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// Наибольшая сторона вариантов картинки, px
+const (
+	thumbSide = 320
+	fullSide  = 1600
+)
+
+type UploadUseCase struct {
+	uploadRepo domain.UploadRepository
+	blobs      domain.BlobStore
+	maxSize    int64 // Максимальный размер исходного файла, байт
+}
+
+func NewUploadUseCase(uploadRepo domain.UploadRepository, blobs domain.BlobStore, maxSize int64) *UploadUseCase {
+	return &UploadUseCase{
+		uploadRepo: uploadRepo,
+		blobs:      blobs,
+		maxSize:    maxSize,
+	}
+}
+
+// UploadImage проверяет картинку, сохраняет миниатюру и полноразмерный вариант и регистрирует загрузку
+func (uc *UploadUseCase) UploadImage(ctx context.Context, ownerID uuid.UUID, r io.Reader) (*domain.Upload, error) {
+	data, err := io.ReadAll(io.LimitReader(r, uc.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > uc.maxSize {
+		return nil, fmt.Errorf("%w: maximum size is %d bytes", domain.ErrImageTooLarge, uc.maxSize)
+	}
+
+	contentType, err := imaging.DetectType(data)
+	if err != nil {
+		return nil, domain.ErrUnsupportedImage
+	}
+	img, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, fmt.Errorf("%w: more than %d pixels", domain.ErrImageTooLarge, imaging.MaxPixels)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+
+	upload := &domain.Upload{
+		ID:          uuid.New(),
+		OwnerID:     ownerID,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	full := imaging.Fit(img, fullSide)
+	upload.Width, upload.Height = full.Bounds().Dx(), full.Bounds().Dy()
+
+	variants := map[domain.ImageVariant]image.Image{
+		domain.ImageFull:  full,
+		domain.ImageThumb: imaging.Fit(full, thumbSide),
+	}
+	for variant, img := range variants {
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, img); err != nil {
+			uc.removeBlobs(upload.ID)
+			return nil, err
+		}
+		if err := uc.blobs.Put(ctx, domain.BlobKey(upload.ID, variant), &buf, "image/jpeg"); err != nil {
+			uc.removeBlobs(upload.ID)
+			return nil, err
+		}
+	}
+
+	if err := uc.uploadRepo.Create(upload); err != nil {
+		uc.removeBlobs(upload.ID)
+		return nil, err
+	}
+	upload.FillURLs()
+	return upload, nil
+}
+
+// removeBlobs убирает файлы загрузки, которая не была сохранена
+func (uc *UploadUseCase) removeBlobs(uploadID uuid.UUID) {
+	for _, variant := range []domain.ImageVariant{domain.ImageFull, domain.ImageThumb} {
+		if err := uc.blobs.Delete(context.Background(), domain.BlobKey(uploadID, variant)); err != nil {
+			log.Printf("upload %s: remove %s variant: %v", uploadID, variant, err)
+		}
+	}
+}
+
+// OpenImage открывает вариант картинки для отдачи клиенту
+func (uc *UploadUseCase) OpenImage(ctx context.Context, uploadID uuid.UUID, variant domain.ImageVariant) (io.ReadCloser, *domain.BlobInfo, error) {
+	if !variant.Valid() {
+		return nil, nil, domain.ErrBlobNotFound
+	}
+	return uc.blobs.Get(ctx, domain.BlobKey(uploadID, variant))
+}
@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Имя удаленного пользователя в истории, отзывах и обменах
+const deletedUserName = "Удаленный пользователь"
+
+// UpdateProfile меняет имя и почту пользователя
+func (uc *UserUseCase) UpdateProfile(userID uuid.UUID, name, email string) (*domain.User, error) {
+	user, err := uc.activeUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if email != user.Email {
+		existing, err := uc.userRepo.GetByEmail(email)
+		if err == nil && existing.ID != user.ID {
+			return nil, domain.ErrEmailTaken
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	emailChanged := email != user.Email
+	user.Name = name
+	user.Email = email
+	if emailChanged {
+		// Новую почту нужно подтвердить заново
+		user.EmailVerifiedAt = nil
+	}
+	if err := uc.userRepo.Update(user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// Почту успели занять параллельно
+			return nil, domain.ErrEmailTaken
+		}
+		return nil, err
+	}
+	if emailChanged {
+		if err := uc.sendVerification(user); err != nil {
+			log.Printf("verification mail to user %s failed: %v", user.ID, err)
+		}
+	}
+	return user, nil
+}
+
+// ChangePassword меняет пароль после проверки текущего. Все сессии, открытые до смены,
+// завершаются; в ответе - пара токенов новой сессии для текущего клиента.
+func (uc *UserUseCase) ChangePassword(userID uuid.UUID, oldPassword, newPassword string, client domain.ClientInfo) (*domain.TokenResponse, error) {
+	user, err := uc.activeUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
+		return nil, domain.ErrWrongPassword
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = string(hashedPassword)
+
+	var tokens *domain.TokenResponse
+	err = uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := repos.Users.Update(user); err != nil {
+			return err
+		}
+		if err := repos.Sessions.RevokeByUserID(user.ID, time.Now()); err != nil {
+			return err
+		}
+		tokens, err = uc.startSession(repos.Sessions, user, client)
+		return err
+	})
+	return tokens, err
+}
+
+// DeleteAccount удаляет аккаунт: брони отменяются, из очередей пользователь выходит, свободные
+// книги пользователя уходят в архив, а строка пользователя обезличивается - на нее ссылаются
+// история перемещений, обмены и отзывы. Пока у пользователя есть взятые книги или его
+// собственные книги в брони или на руках, удалить аккаунт нельзя.
+func (uc *UserUseCase) DeleteAccount(userID uuid.UUID) error {
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		user, err := repos.Users.GetByID(userID)
+		if err != nil {
+			return err
+		}
+		if user.DeletedAt != nil {
+			return domain.ErrAccountDeleted
+		}
+
+		exchanges, err := repos.Exchanges.ListByUserID(userID, domain.PageQuery{Limit: -1})
+		if err != nil {
+			return err
+		}
+		for _, exchange := range exchanges {
+			if exchange.Status == domain.ExchangeBorrowed || exchange.Status == domain.ExchangeOverdue {
+				return domain.ErrActiveLoans
+			}
+		}
+
+		if err := releaseReservations(repos, userID, exchanges, domain.ActorRequester, userID, "Book request cancelled: account deleted"); err != nil {
+			return err
+		}
+		if err := archiveOwnedBooks(repos, userID); err != nil {
+			return err
+		}
+
+		anonymize(user)
+		if err := repos.Users.Update(user); err != nil {
+			return err
+		}
+		return repos.Sessions.RevokeByUserID(userID, *user.DeletedAt)
+	})
+}
+
+// releaseReservations выводит пользователя из очередей и отменяет его брони, по которым книга еще не выдана.
+// В истории отмена записывается на initiatorID в роли actor.
+func releaseReservations(repos *domain.Repositories, userID uuid.UUID, exchanges []*domain.Exchange, actor domain.Actor, initiatorID uuid.UUID, notes string) error {
+	// Сначала выходим из очередей: иначе отмена брони могла бы отдать книгу самому пользователю
+	entries, err := repos.Waitlist.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := repos.Waitlist.Delete(entry.ID); err != nil {
+			return err
+		}
+	}
+	for _, exchange := range exchanges {
+		if exchange.Status != domain.ExchangeRequested {
+			continue
+		}
+		if err := cancelReservation(repos, exchange, actor, &initiatorID, notes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveOwnedBooks убирает из оборота книги уходящего владельца, чтобы их больше
+// нельзя было забронировать. Книги в брони или на руках архивировать нельзя.
+func archiveOwnedBooks(repos *domain.Repositories, ownerID uuid.UUID) error {
+	books, err := repos.Books.GetByOwnerID(ownerID)
+	if err != nil {
+		return err
+	}
+	for _, book := range books {
+		switch book.Status {
+		case domain.BookRequested, domain.BookBorrowed:
+			return domain.ErrOwnedBooksInUse
+		case domain.BookAvailable:
+		default:
+			continue
+		}
+
+		transition, err := domain.TransitionBook(book.Status, domain.BookArchived, domain.ActorOwner)
+		if err != nil {
+			return err
+		}
+		if err := moveBook(repos, book, transition); err != nil {
+			return err
+		}
+		movement := transition.Movement(book.ID, &ownerID)
+		movement.FromLocationID = book.CurrentLocationID
+		movement.ToLocationID = book.CurrentLocationID
+		movement.Notes = "Book archived: owner account deleted"
+		if err := repos.Movements.Create(movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anonymize стирает личные данные; войти в такой аккаунт больше нельзя
+func anonymize(user *domain.User) {
+	deletedAt := time.Now()
+	user.Email = "deleted-" + user.ID.String() + "@deleted.invalid"
+	user.Name = deletedUserName
+	user.Password = ""
+	user.DeletedAt = &deletedAt
+}
+
+// activeUser загружает пользователя, который может пользоваться аккаунтом: не удален и не заблокирован
+func (uc *UserUseCase) activeUser(userID uuid.UUID) (*domain.User, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := user.CheckAccess(time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
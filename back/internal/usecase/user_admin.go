@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CheckAccess загружает владельца токена и проверяет, что он все еще может пользоваться API.
+// Вызывается на каждый запрос, поэтому блокировка и удаление аккаунта действуют сразу,
+// а не после истечения access-токена.
+func (uc *UserUseCase) CheckAccess(userID uuid.UUID) (*domain.User, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrAccountDeleted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := user.CheckAccess(time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers возвращает страницу пользователей от старых к новым; filter сужает выборку
+func (uc *UserUseCase) ListUsers(actorID uuid.UUID, filter domain.UserFilter, page domain.PageRequest) (*domain.Page[*domain.User], error) {
+	if _, err := authorize(uc.userRepo, actorID, domain.PermUserRead); err != nil {
+		return nil, err
+	}
+	if filter.Role != "" && !filter.Role.Valid() {
+		return nil, domain.ErrInvalidRole
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	return listByTime(page, domain.CursorUsers, userKey,
+		func(q domain.PageQuery) ([]*domain.User, error) { return uc.userRepo.List(filter, q) },
+		func() (int64, error) { return uc.userRepo.Count(filter) })
+}
+
+// GetUser возвращает пользователя для админки, включая блокировку
+func (uc *UserUseCase) GetUser(actorID, userID uuid.UUID) (*domain.User, error) {
+	if _, err := authorize(uc.userRepo, actorID, domain.PermUserRead); err != nil {
+		return nil, err
+	}
+	return uc.userRepo.GetByID(userID)
+}
+
+// GetUserHistory возвращает историю перемещений книг, инициированных пользователем
+func (uc *UserUseCase) GetUserHistory(actorID, userID uuid.UUID, page domain.PageRequest) (*domain.Page[*domain.BookMovementHistory], error) {
+	if _, err := authorize(uc.userRepo, actorID, domain.PermUserRead); err != nil {
+		return nil, err
+	}
+	if _, err := uc.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	return listByTime(page, domain.CursorMovements, movementKey,
+		func(q domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+			return uc.movementRepo.ListByUserID(userID, q)
+		},
+		func() (int64, error) { return uc.movementRepo.CountByUserID(userID) })
+}
+
+// ChangeRole назначает пользователю роль. Свою роль менять нельзя, чтобы не остаться без администратора.
+func (uc *UserUseCase) ChangeRole(actorID, userID uuid.UUID, role domain.UserRole) (*domain.User, error) {
+	if _, err := authorize(uc.userRepo, actorID, domain.PermUserManageRoles); err != nil {
+		return nil, err
+	}
+	if !role.Valid() {
+		return nil, domain.ErrInvalidRole
+	}
+	if actorID == userID {
+		return nil, domain.ErrSelfModeration
+	}
+	user, err := moderatedUser(uc.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.userRepo.UpdateRole(userID, role); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+// BanUser блокирует пользователя до until (nil - бессрочно). Все его сессии завершаются,
+// неполученные брони отменяются, из очередей он выходит; взятые книги остаются за ним.
+func (uc *UserUseCase) BanUser(actorID, userID uuid.UUID, reason string, until *time.Time) (*domain.User, error) {
+	actor, err := authorize(uc.userRepo, actorID, domain.PermUserBan)
+	if err != nil {
+		return nil, err
+	}
+	if actorID == userID {
+		return nil, domain.ErrSelfModeration
+	}
+	ts := time.Now()
+	reason = strings.TrimSpace(reason)
+	if reason == "" || (until != nil && !until.After(ts)) {
+		return nil, domain.ErrInvalidBan
+	}
+	user, err := moderatedUser(uc.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := canModerate(actor, user); err != nil {
+		return nil, err
+	}
+
+	ban := domain.UserBan{BannedAt: &ts, BannedUntil: until, BanReason: reason, BannedBy: &actor.ID}
+	err = uc.uow.Do(func(repos *domain.Repositories) error {
+		if err := repos.Users.UpdateBan(userID, ban); err != nil {
+			return err
+		}
+		exchanges, err := repos.Exchanges.ListByUserID(userID, domain.PageQuery{Limit: -1})
+		if err != nil {
+			return err
+		}
+		if err := releaseReservations(repos, userID, exchanges, domain.ActorModer, actor.ID, "Book request cancelled: user banned"); err != nil {
+			return err
+		}
+		return repos.Sessions.RevokeByUserID(userID, ts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.UserBan = ban
+	return user, nil
+}
+
+// UnbanUser снимает блокировку. Завершенные при блокировке сессии не восстанавливаются.
+func (uc *UserUseCase) UnbanUser(actorID, userID uuid.UUID) (*domain.User, error) {
+	actor, err := authorize(uc.userRepo, actorID, domain.PermUserBan)
+	if err != nil {
+		return nil, err
+	}
+	user, err := moderatedUser(uc.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := canModerate(actor, user); err != nil {
+		return nil, err
+	}
+	if err := uc.userRepo.UpdateBan(userID, domain.UserBan{}); err != nil {
+		return nil, err
+	}
+	user.UserBan = domain.UserBan{}
+	return user, nil
+}
+
+// moderatedUser загружает пользователя, которому меняют роль или блокировку; удаленные аккаунты не трогаем
+func moderatedUser(users domain.UserRepository, userID uuid.UUID) (*domain.User, error) {
+	user, err := users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+// canModerate не дает модератору блокировать тех, кто сам вправе блокировать:
+// модераторов и администраторов блокирует только тот, кто назначает роли
+func canModerate(actor, user *domain.User) error {
+	if !user.Role.Can(domain.PermUserBan) {
+		return nil
+	}
+	return actor.Role.Authorize(domain.PermUserManageRoles)
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	verificationTokenTTL = 48 * time.Hour
+	resetTokenTTL        = time.Hour
+	mailTimeout          = 10 * time.Second
+)
+
+// VerifyEmail подтверждает почту по токену из письма
+func (uc *UserUseCase) VerifyEmail(secret string) error {
+	token, err := uc.accountToken(secret, domain.TokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+	ts := time.Now()
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		user, err := tokenOwner(repos, token)
+		if err != nil {
+			return err
+		}
+		if err := repos.Tokens.Use(token.ID, ts); err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		user.EmailVerifiedAt = &ts
+		return repos.Users.Update(user)
+	})
+}
+
+// ResendVerification отправляет новое письмо для подтверждения почты; прежние ссылки перестают работать
+func (uc *UserUseCase) ResendVerification(userID uuid.UUID) error {
+	user, err := uc.activeUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return domain.ErrEmailAlreadyVerified
+	}
+	return uc.sendVerification(user)
+}
+
+// RequestPasswordReset отправляет письмо со ссылкой для сброса пароля. Чтобы по ответу нельзя было
+// узнать, зарегистрирована ли почта, для неизвестного адреса ошибки нет, а сбой отправки только логируется.
+func (uc *UserUseCase) RequestPasswordReset(email string) error {
+	user, err := uc.userRepo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return nil
+	}
+
+	secret, err := issueAccountToken(uc.tokenRepo, user, domain.TokenResetPassword, resetTokenTTL)
+	if err != nil {
+		return err
+	}
+	mail := domain.Mail{
+		To:      user.Email,
+		Subject: "Восстановление пароля в Bookvito",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s/reset-password?token=%s\n\n"+
+			"Ссылка действует %d мин. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			user.Name, uc.appURL, secret, int(resetTokenTTL.Minutes())),
+	}
+	if err := uc.send(mail); err != nil {
+		log.Printf("password reset mail to user %s failed: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword задает новый пароль по токену из письма и завершает все сессии пользователя
+func (uc *UserUseCase) ResetPassword(secret, newPassword string) error {
+	token, err := uc.accountToken(secret, domain.TokenResetPassword)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	ts := time.Now()
+	return uc.uow.Do(func(repos *domain.Repositories) error {
+		user, err := tokenOwner(repos, token)
+		if err != nil {
+			return err
+		}
+		if err := repos.Tokens.Use(token.ID, ts); err != nil {
+			return err
+		}
+		user.Password = string(hashedPassword)
+		// Письмо дошло до пользователя - значит, почта его
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &ts
+		}
+		if err := repos.Users.Update(user); err != nil {
+			return err
+		}
+		// Пароль сбрасывают, когда доступ мог оказаться у других
+		return repos.Sessions.RevokeByUserID(user.ID, ts)
+	})
+}
+
+// sendVerification выдает токен подтверждения текущей почты пользователя и отправляет письмо
+func (uc *UserUseCase) sendVerification(user *domain.User) error {
+	secret, err := issueAccountToken(uc.tokenRepo, user, domain.TokenVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+	return uc.send(domain.Mail{
+		To:      user.Email,
+		Subject: "Подтвердите почту в Bookvito",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы брать книги, подтвердите почту по ссылке:\n%s/verify-email?token=%s\n\n"+
+			"Ссылка действует %d ч.\n",
+			user.Name, uc.appURL, secret, int(verificationTokenTTL.Hours())),
+	})
+}
+
+func (uc *UserUseCase) send(mail domain.Mail) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+	defer cancel()
+	return uc.mailer.Send(ctx, mail)
+}
+
+// accountToken находит действующий токен с нужным назначением
+func (uc *UserUseCase) accountToken(secret string, purpose domain.TokenPurpose) (*domain.AccountToken, error) {
+	token, err := uc.tokenRepo.GetByHash(hashTokenSecret(secret))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrInvalidAccountToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token.Purpose != purpose || !token.Usable(time.Now()) {
+		return nil, domain.ErrInvalidAccountToken
+	}
+	return token, nil
+}
+
+// issueAccountToken гасит прежние токены с тем же назначением и выдает новый
+func issueAccountToken(tokens domain.AccountTokenRepository, user *domain.User, purpose domain.TokenPurpose, ttl time.Duration) (string, error) {
+	ts := time.Now()
+	if err := tokens.InvalidateByUserID(user.ID, purpose, ts); err != nil {
+		return "", err
+	}
+	secret, err := newTokenSecret()
+	if err != nil {
+		return "", err
+	}
+	token := &domain.AccountToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashTokenSecret(secret),
+		Email:     user.Email,
+		ExpiresAt: ts.Add(ttl),
+	}
+	if err := tokens.Create(token); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// tokenOwner загружает пользователя токена; после удаления аккаунта или смены почты токен недействителен
+func tokenOwner(repos *domain.Repositories, token *domain.AccountToken) (*domain.User, error) {
+	user, err := repos.Users.GetByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil || user.Email != token.Email {
+		return nil, domain.ErrInvalidAccountToken
+	}
+	return user, nil
+}
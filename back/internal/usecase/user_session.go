@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL  = 10 * time.Hour
+	refreshTokenTTL = 120 * 24 * time.Hour // Продлевается при каждом обновлении токенов
+
+	maxDeviceLength = 200
+	maxIPLength     = 45
+)
+
+// startSession открывает новую сессию пользователя и выдает ей пару токенов
+func (uc *UserUseCase) startSession(sessions domain.SessionRepository, user *domain.User, client domain.ClientInfo) (*domain.TokenResponse, error) {
+	secret, err := newTokenSecret()
+	if err != nil {
+		return nil, err
+	}
+	ts := time.Now()
+	session := &domain.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		TokenHash:  hashTokenSecret(secret),
+		Device:     truncate(client.Device, maxDeviceLength),
+		IP:         truncate(client.IP, maxIPLength),
+		LastUsedAt: ts,
+		ExpiresAt:  ts.Add(refreshTokenTTL),
+	}
+	if err := sessions.Create(session); err != nil {
+		return nil, err
+	}
+	return uc.tokenResponse(user, session, secret)
+}
+
+// RefreshToken обменивает refresh-токен на новую пару. Каждый токен можно обменять один раз:
+// повторное предъявление уже обмененного токена отзывает всю сессию.
+func (uc *UserUseCase) RefreshToken(refreshToken string, client domain.ClientInfo) (*domain.TokenResponse, error) {
+	session, secret, err := uc.sessionByToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	ts := time.Now()
+	if !tokenMatches(session, secret) {
+		// Токен этой сессии, но не текущий: его уже обменяли, значит им пользуется кто-то еще
+		return nil, uc.revokeReused(session.ID, ts)
+	}
+	if !session.Active(ts) {
+		return nil, domain.ErrRefreshTokenExpired
+	}
+	user, err := uc.activeUser(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	previousHash := session.TokenHash
+	secret, err = newTokenSecret()
+	if err != nil {
+		return nil, err
+	}
+	session.TokenHash = hashTokenSecret(secret)
+	session.IP = truncate(client.IP, maxIPLength)
+	session.LastUsedAt = ts
+	session.ExpiresAt = ts.Add(refreshTokenTTL)
+	if err := uc.sessionRepo.Rotate(session, previousHash); err != nil {
+		if errors.Is(err, domain.ErrRefreshTokenReused) {
+			// Тот же токен только что обменял параллельный запрос
+			return nil, uc.revokeReused(session.ID, ts)
+		}
+		return nil, err
+	}
+	return uc.tokenResponse(user, session, secret)
+}
+
+// Logout завершает сессию, которой выдан refresh-токен
+func (uc *UserUseCase) Logout(refreshToken string) error {
+	session, secret, err := uc.sessionByToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	if !tokenMatches(session, secret) {
+		return domain.ErrInvalidRefreshToken
+	}
+	return uc.sessionRepo.Revoke(session.ID, time.Now())
+}
+
+// LogoutAll завершает все сессии пользователя. Выданные access-токены действуют до своего истечения.
+func (uc *UserUseCase) LogoutAll(userID uuid.UUID) error {
+	return uc.sessionRepo.RevokeByUserID(userID, time.Now())
+}
+
+// ListSessions возвращает активные сессии пользователя; сессия текущего запроса помечена Current
+func (uc *UserUseCase) ListSessions(userID uuid.UUID, currentSessionID uuid.UUID) ([]*domain.Session, error) {
+	sessions, err := uc.sessionRepo.ListActiveByUserID(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// sessionByToken находит неотозванную сессию по refresh-токену. Совпадение секрета не проверяется.
+func (uc *UserUseCase) sessionByToken(refreshToken string) (*domain.Session, string, error) {
+	sessionID, secret, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, "", domain.ErrInvalidRefreshToken
+	}
+	session, err := uc.sessionRepo.GetByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if session.RevokedAt != nil {
+		return nil, "", domain.ErrInvalidRefreshToken
+	}
+	return session, secret, nil
+}
+
+func (uc *UserUseCase) revokeReused(sessionID uuid.UUID, at time.Time) error {
+	if err := uc.sessionRepo.Revoke(sessionID, at); err != nil {
+		return err
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+func (uc *UserUseCase) tokenResponse(user *domain.User, session *domain.Session, secret string) (*domain.TokenResponse, error) {
+	ts := time.Now()
+	claims := jwt.MapClaims{
+		"sub":    user.ID,
+		"userId": user.ID.String(),
+		"sid":    session.ID.String(),
+		"email":  user.Email,
+		"name":   user.Name,
+		"role":   user.Role,
+		"exp":    ts.Add(accessTokenTTL).Unix(),
+		"iat":    ts.Unix(),
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(uc.jwtSecret))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: session.ID.String() + "." + secret,
+	}, nil
+}
+
+// Refresh-токен - "<id сессии>.<секрет>"; в базе хранится только SHA-256 секрета
+func parseRefreshToken(token string) (uuid.UUID, string, bool) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return uuid.Nil, "", false
+	}
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+	return sessionID, secret, true
+}
+
+func newTokenSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashTokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func tokenMatches(session *domain.Session, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(session.TokenHash), []byte(hashTokenSecret(secret))) == 1
+}
+
+// truncate обрезает строку до limit символов
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return s
+}
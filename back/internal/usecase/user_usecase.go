@@ -3,9 +3,10 @@ package usecase
 import (
 	"bookvito/internal/domain"
 	"errors"
+	"log"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -14,23 +15,33 @@ import (
 
 type UserUseCase struct {
 	userRepo     domain.UserRepository
+	sessionRepo  domain.SessionRepository
+	tokenRepo    domain.AccountTokenRepository
 	movementRepo domain.BookMovementHistoryRepository
+	mailer       domain.Mailer
+	uow          domain.UnitOfWork
 	jwtSecret    string
+	appURL       string // Адрес приложения для ссылок в письмах
 }
 
 // NewUserUseCase creates a new user use case
-func NewUserUseCase(userRepo domain.UserRepository, movementRepo domain.BookMovementHistoryRepository, jwtSecret string) *UserUseCase {
+func NewUserUseCase(userRepo domain.UserRepository, sessionRepo domain.SessionRepository, tokenRepo domain.AccountTokenRepository, movementRepo domain.BookMovementHistoryRepository, mailer domain.Mailer, uow domain.UnitOfWork, jwtSecret, appURL string) *UserUseCase {
 	return &UserUseCase{
 		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		tokenRepo:    tokenRepo,
 		movementRepo: movementRepo,
+		mailer:       mailer,
+		uow:          uow,
 		jwtSecret:    jwtSecret,
+		appURL:       strings.TrimSuffix(appURL, "/"),
 	}
 }
 
-func (uc *UserUseCase) RegisterUser(email string, password string, name string) (*domain.TokenResponse, error) {
+func (uc *UserUseCase) RegisterUser(email string, password string, name string, client domain.ClientInfo) (*domain.TokenResponse, error) {
 	_, err := uc.userRepo.GetByEmail(email)
 	if err == nil {
-		return nil, errors.New("user with this email already exists")
+		return nil, domain.ErrEmailTaken
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		// Если ошибка - это не "запись не найдена", значит, произошла другая проблема с БД
@@ -51,44 +62,16 @@ func (uc *UserUseCase) RegisterUser(email string, password string, name string)
 		return nil, err
 	}
 
-	// Generate new pair of tokens
-	return uc.generateTokenPair(user)
-}
-
-func (uc *UserUseCase) generateTokenPair(user *domain.User) (*domain.TokenResponse, error) {
-	// Generate Access Token
-	claims := jwt.MapClaims{
-		"sub":    user.ID,
-		"userId": user.ID.String(),
-		"email":  user.Email,
-		"name":   user.Name,
-		"role":   user.Role,
-		"exp":    time.Now().Add(time.Hour * 10).Unix(),
-		"iat":    time.Now().Unix(),
-	}
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := jwtToken.SignedString([]byte(uc.jwtSecret))
-	if err != nil {
-		return nil, err
+	// Письмо не должно мешать регистрации: его можно запросить повторно
+	if err := uc.sendVerification(user); err != nil {
+		log.Printf("verification mail to user %s failed: %v", user.ID, err)
 	}
 
-	refreshToken := uuid.New().String()
-	refreshTokenExpiresAt := time.Now().Add(time.Hour * 24 * 120) // Expires in 120 days
-
-	user.RefreshToken = refreshToken
-	user.RefreshTokenExpiresAt = refreshTokenExpiresAt
-
-	if err := uc.userRepo.Update(user); err != nil {
-		return nil, err
-	}
-
-	return &domain.TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	// Первая сессия нового пользователя
+	return uc.startSession(uc.sessionRepo, user, client)
 }
 
-func (uc *UserUseCase) LoginUser(email, password string) (*domain.TokenResponse, error) {
+func (uc *UserUseCase) LoginUser(email, password string, client domain.ClientInfo) (*domain.TokenResponse, error) {
 	user, err := uc.userRepo.GetByEmail(email)
 	if err != nil {
 		return nil, errors.New("invalid email or password")
@@ -96,21 +79,11 @@ func (uc *UserUseCase) LoginUser(email, password string) (*domain.TokenResponse,
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		return nil, errors.New("invalid email or password")
 	}
-	return uc.generateTokenPair(user)
-}
-
-func (uc *UserUseCase) RefreshToken(refreshToken string) (*domain.TokenResponse, error) {
-	user, err := uc.userRepo.GetByRefreshToken(refreshToken)
-	if err != nil {
-		return nil, errors.New("invalid refresh token")
-	}
-
-	if time.Now().After(user.RefreshTokenExpiresAt) {
-		return nil, errors.New("refresh token expired")
+	// Заблокированному сообщаем причину и срок, но только после проверки пароля
+	if err := user.CheckAccess(time.Now()); err != nil {
+		return nil, err
 	}
-
-	// Generate new pair of tokens
-	return uc.generateTokenPair(user)
+	return uc.startSession(uc.sessionRepo, user, client)
 }
 
 // // LoginUser authenticates a user
@@ -146,18 +119,14 @@ func (uc *UserUseCase) GetUserByID(id string) (*domain.User, error) {
 	return uc.userRepo.GetByID(uuidID)
 }
 
-func (uc *UserUseCase) DeleteUser(id string) error {
-	uuidID, err := uuid.Parse(id)
-	if err != nil {
-		return errors.New("invalid user ID format")
-	}
-	return uc.userRepo.Delete(uuidID)
-}
-
-func (uc *UserUseCase) GetUserMovementHistory(userID string) ([]*domain.BookMovementHistory, error) {
+func (uc *UserUseCase) GetUserMovementHistory(userID string, page domain.PageRequest) (*domain.Page[*domain.BookMovementHistory], error) {
 	uuidID, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID format")
 	}
-	return uc.movementRepo.GetByUserID(uuidID)
+	return listByTime(page, domain.CursorMovements, movementKey,
+		func(q domain.PageQuery) ([]*domain.BookMovementHistory, error) {
+			return uc.movementRepo.ListByUserID(uuidID, q)
+		},
+		func() (int64, error) { return uc.movementRepo.CountByUserID(uuidID) })
 }
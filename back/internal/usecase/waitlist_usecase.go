@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WaitlistUseCase struct {
+	waitlistRepo domain.WaitlistRepository
+	userRepo     domain.UserRepository
+	uow          domain.UnitOfWork
+}
+
+// NewWaitlistUseCase creates a new waitlist use case
+func NewWaitlistUseCase(waitlistRepo domain.WaitlistRepository, userRepo domain.UserRepository, uow domain.UnitOfWork) *WaitlistUseCase {
+	return &WaitlistUseCase{
+		waitlistRepo: waitlistRepo,
+		userRepo:     userRepo,
+		uow:          uow,
+	}
+}
+
+// JoinWaitlist ставит пользователя в конец очереди на книгу, которую сейчас держит кто-то другой
+func (uc *WaitlistUseCase) JoinWaitlist(bookID, userID uuid.UUID) (*domain.WaitlistEntry, error) {
+	// Очередь заканчивается бронью, поэтому требования те же, что у Request
+	if err := requireVerifiedEmail(uc.userRepo, userID); err != nil {
+		return nil, err
+	}
+
+	entry := &domain.WaitlistEntry{
+		BookID: bookID,
+		UserID: userID,
+	}
+	// Вставка идет под блокировкой строки книги, как и продление выдачи (см. lockBook)
+	err := uc.uow.Do(func(repos *domain.Repositories) error {
+		book, err := lockBook(repos, bookID)
+		if err != nil {
+			return err
+		}
+		switch book.Status {
+		case domain.BookRequested, domain.BookBorrowed:
+		case domain.BookAvailable:
+			return domain.ErrWaitlistNotNeeded
+		default:
+			return domain.ErrWaitlistClosed
+		}
+		if book.OwnerID == userID {
+			return domain.ErrForbidden
+		}
+
+		holder, err := activeExchange(repos.Exchanges, bookID)
+		if err != nil {
+			return err
+		}
+		if holder != nil && holder.UserID == userID {
+			return domain.ErrAlreadyInWaitlist
+		}
+		_, err = repos.Waitlist.GetByBookAndUser(bookID, userID)
+		if err == nil {
+			return domain.ErrAlreadyInWaitlist
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return repos.Waitlist.Create(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := uc.queue(bookID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID == entry.ID {
+			entry.Position = e.Position
+		}
+	}
+	return entry, nil
+}
+
+// LeaveWaitlist убирает пользователя из очереди на книгу
+func (uc *WaitlistUseCase) LeaveWaitlist(bookID, userID uuid.UUID) error {
+	entry, err := uc.waitlistRepo.GetByBookAndUser(bookID, userID)
+	if err != nil {
+		return err
+	}
+	return uc.waitlistRepo.Delete(entry.ID)
+}
+
+// GetWaitlist возвращает очередь на книгу. Очередь видна всем, поэтому из пользователя
+// наружу уходит только имя.
+func (uc *WaitlistUseCase) GetWaitlist(bookID uuid.UUID) ([]*domain.WaitlistPlace, error) {
+	entries, err := uc.queue(bookID)
+	if err != nil {
+		return nil, err
+	}
+	places := make([]*domain.WaitlistPlace, 0, len(entries))
+	for _, entry := range entries {
+		place := &domain.WaitlistPlace{Position: entry.Position, JoinedAt: entry.CreatedAt}
+		if entry.User != nil {
+			place.UserName = entry.User.Name
+		}
+		places = append(places, place)
+	}
+	return places, nil
+}
+
+// queue возвращает очередь на книгу с проставленными местами
+func (uc *WaitlistUseCase) queue(bookID uuid.UUID) ([]*domain.WaitlistEntry, error) {
+	entries, err := uc.waitlistRepo.GetByBookID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		entry.Position = i + 1
+	}
+	return entries, nil
+}
+
+// promoteNextInLine выдает освободившуюся книгу первому в очереди: создает ему бронь
+// со свежим сроком и переводит книгу в "requested". Вызывается в той же транзакции,
+// в которой книга стала доступной.
+func promoteNextInLine(repos *domain.Repositories, book *domain.Book) error {
+	if book.Status != domain.BookAvailable {
+		return nil
+	}
+	next, err := nextEligibleInLine(repos, book.ID)
+	if err != nil || next == nil {
+		return err
+	}
+
+	transition, err := domain.TransitionBook(book.Status, domain.BookRequested, domain.ActorSystem)
+	if err != nil {
+		return err
+	}
+	if err := moveBook(repos, book, transition); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(reservationPeriod)
+	exchange := &domain.Exchange{
+		UserID:    next.UserID,
+		BookID:    book.ID,
+		Status:    domain.ExchangeRequested,
+		ExpiresAt: &expiresAt,
+	}
+	if err := repos.Exchanges.Create(exchange); err != nil {
+		return err
+	}
+
+	movement := transition.Movement(book.ID, &next.UserID)
+	movement.ExchangeID = &exchange.ID
+	movement.Notes = "Book assigned to the next user in the waitlist"
+	return repos.Movements.Create(movement)
+}
+
+// nextEligibleInLine снимает с начала очереди первого, кто может получить книгу прямо сейчас.
+// Тех, кто стоит перед ним, но брать книги не может (почта не подтверждена, аккаунт
+// заблокирован или удален), убирает из очереди; порядок остальных не меняется.
+func nextEligibleInLine(repos *domain.Repositories, bookID uuid.UUID) (*domain.WaitlistEntry, error) {
+	entries, err := repos.Waitlist.GetByBookID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if err := repos.Waitlist.Delete(entry.ID); err != nil {
+			return nil, err
+		}
+		user, err := repos.Users.GetByID(entry.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user.CheckAccess(now) == nil && user.EmailVerifiedAt != nil {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// clearWaitlist очищает очередь на книгу, которая больше не вернется в оборот
+func clearWaitlist(repos *domain.Repositories, bookID uuid.UUID) error {
+	entries, err := repos.Waitlist.GetByBookID(bookID)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := repos.Waitlist.Delete(entry.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"bookvito/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+type WorkUseCase struct {
+	workRepo   domain.WorkRepository
+	bookRepo   domain.BookRepository
+	reviewRepo domain.ReviewRepository
+}
+
+// NewWorkUseCase creates a new work use case
+func NewWorkUseCase(workRepo domain.WorkRepository, bookRepo domain.BookRepository, reviewRepo domain.ReviewRepository) *WorkUseCase {
+	return &WorkUseCase{
+		workRepo:   workRepo,
+		bookRepo:   bookRepo,
+		reviewRepo: reviewRepo,
+	}
+}
+
+// GetWork возвращает произведение с числом экземпляров, пунктами, где есть свободные,
+// и средней оценкой по отзывам на все экземпляры
+func (uc *WorkUseCase) GetWork(workID uuid.UUID) (*domain.WorkDetails, error) {
+	work, err := uc.workRepo.GetByID(workID)
+	if err != nil {
+		return nil, err
+	}
+	copies, err := uc.workRepo.CountCopies(workID)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := uc.workRepo.AvailableLocations(workID)
+	if err != nil {
+		return nil, err
+	}
+	if locations == nil {
+		locations = []*domain.WorkLocation{}
+	}
+	reviews, err := uc.reviewRepo.GetByWorkID(workID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &domain.WorkDetails{
+		Work:         *work,
+		WorkCopies:   *copies,
+		Locations:    locations,
+		ReviewsCount: int64(len(reviews)),
+	}
+	if len(reviews) > 0 {
+		var sum float64
+		for _, r := range reviews {
+			sum += float64(r.Rating)
+		}
+		avg := sum / float64(len(reviews))
+		details.AverageRating = &avg
+	}
+	return details, nil
+}
+
+// GetWorkBooks возвращает экземпляры произведения (без удаленных), новые сверху
+func (uc *WorkUseCase) GetWorkBooks(workID uuid.UUID) ([]*domain.Book, error) {
+	if _, err := uc.workRepo.GetByID(workID); err != nil {
+		return nil, err
+	}
+	return uc.bookRepo.GetByWorkID(workID)
+}
+
+// GetWorkReviews возвращает отзывы на все экземпляры произведения, новые сверху
+func (uc *WorkUseCase) GetWorkReviews(workID uuid.UUID) ([]domain.Review, error) {
+	if _, err := uc.workRepo.GetByID(workID); err != nil {
+		return nil, err
+	}
+	return uc.reviewRepo.GetByWorkID(workID)
+}
@@ -2,7 +2,6 @@ package database
 
 import (
 	"bookvito/config"
-	"bookvito/internal/domain"
 	"fmt"
 
 	"gorm.io/driver/postgres"
@@ -21,22 +20,11 @@ func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
 		cfg.DBSSLMode,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError: нарушение уникального индекса возвращается как gorm.ErrDuplicatedKey
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	return db, nil
 }
-
-// Автоматическая миграция схемы базы данных
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&domain.Location{},
-		&domain.User{},
-		&domain.Book{},
-		&domain.Exchange{},
-		&domain.Review{},
-		&domain.BookMovementHistory{},
-	)
-}
@@ -0,0 +1,84 @@
+// Package imaging decodes uploaded pictures and produces resized JPEG variants.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // Регистрация декодеров для image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Поддерживаемые типы исходных файлов (определяются по содержимому, а не по заголовку запроса)
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooManyPixels   = errors.New("image has too many pixels")
+)
+
+// MaxPixels ограничивает ширину*высоту исходной картинки: маленький файл может
+// распаковаться в огромный растр, поэтому размер проверяется до декодирования
+const MaxPixels = 40_000_000
+
+// DetectType определяет тип картинки по первым байтам
+func DetectType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !supportedTypes[contentType] {
+		return "", ErrUnsupportedType
+	}
+	return contentType, nil
+}
+
+// Decode декодирует картинку, предварительно проверив ее размер по заголовку
+func Decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("image has no pixels")
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Fit уменьшает картинку так, чтобы она поместилась в квадрат maxSide x maxSide,
+// сохраняя пропорции. Маленькие картинки не увеличиваются. Прозрачные области
+// заливаются белым, так как результат кодируется в JPEG.
+func Fit(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			w, h = maxSide, max(1, h*maxSide/w)
+		} else {
+			w, h = max(1, w*maxSide/h), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// EncodeJPEG записывает картинку в JPEG
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
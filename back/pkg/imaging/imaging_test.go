@@ -0,0 +1,116 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encode кодирует пустую картинку w x h в формат format
+func encode(t *testing.T, format string, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+// gifHeader - заголовок GIF с заявленным размером без самих пикселей
+func gifHeader(w, h int) []byte {
+	return append([]byte("GIF89a"), byte(w), byte(w>>8), byte(h), byte(h>>8), 0, 0, 0)
+}
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"jpeg", encode(t, "jpeg", 2, 2), "image/jpeg"},
+		{"png", encode(t, "png", 2, 2), "image/png"},
+		{"gif", encode(t, "gif", 2, 2), "image/gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp"},
+	}
+	for _, tt := range tests {
+		got, err := DetectType(tt.data)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: DetectType = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"empty": nil,
+		"text":  []byte("just some text"),
+		"pdf":   []byte("%PDF-1.7\n"),
+		"bmp":   []byte("BM\x00\x00\x00\x00"),
+	} {
+		if got, err := DetectType(data); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("%s: DetectType = %q, %v; want ErrUnsupportedType", name, got, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	img, err := Decode(encode(t, "png", 30, 20))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(30, 20) {
+		t.Fatalf("decoded size %v, want 30x20", size)
+	}
+
+	// Размер проверяется по заголовку, до распаковки растра
+	if _, err := Decode(gifHeader(10_000, 5_000)); !errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("oversized image: got %v, want ErrTooManyPixels", err)
+	}
+	if _, err := Decode(gifHeader(0, 10)); err == nil {
+		t.Fatal("image without pixels must be rejected")
+	}
+	if _, err := Decode([]byte("not an image")); err == nil {
+		t.Fatal("garbage must not decode")
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h, side   int
+		wantW, wantH int
+	}{
+		{"landscape", 800, 400, 200, 200, 100},
+		{"portrait", 400, 800, 200, 100, 200},
+		{"square", 500, 500, 100, 100, 100},
+		{"exact fit", 300, 200, 300, 300, 200},
+		{"small is not upscaled", 120, 80, 400, 120, 80},
+		{"thin strip keeps a pixel", 1000, 2, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		got := Fit(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.side)
+		if size := got.Bounds().Size(); size != image.Pt(tt.wantW, tt.wantH) {
+			t.Errorf("%s: Fit(%dx%d, %d) = %v, want %dx%d", tt.name, tt.w, tt.h, tt.side, size, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestFitFillsTransparencyWithWhite(t *testing.T) {
+	got := Fit(image.NewRGBA(image.Rect(0, 0, 10, 10)), 5)
+	if c := color.RGBAModel.Convert(got.At(2, 2)); c != color.RGBAModel.Convert(color.White) {
+		t.Fatalf("transparent pixel became %v, want white", c)
+	}
+}
@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrator, err := New(nil)
+	if err != nil {
+		t.Fatalf("load embedded migrations: %v", err)
+	}
+
+	migrations := migrator.Migrations()
+	if len(migrations) == 0 || migrations[0].Version != 1 || migrations[0].Name != "baseline" {
+		t.Fatalf("expected baseline to be the first migration, got %+v", migrations)
+	}
+	for i, migration := range migrations {
+		if migration.Down == "" {
+			t.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+		if i > 0 && migration.Version == migrations[i-1].Version {
+			t.Errorf("duplicate migration version %d", migration.Version)
+		}
+	}
+}
+
+func TestLoadOrdersAndValidates(t *testing.T) {
+	migrations, err := load(fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("SELECT 2")},
+		"0001_first.up.sql":    {Data: []byte("SELECT 1")},
+		"0001_first.down.sql":  {Data: []byte("SELECT -1")},
+		"0010_tenth.up.sql":    {Data: []byte("SELECT 10")},
+		"0010_tenth.down.sql":  {Data: []byte("SELECT -10")},
+		"0002_second.down.sql": {Data: []byte("SELECT -2")},
+	})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	var versions []int64
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	if len(versions) != 3 || versions[0] != 1 || versions[1] != 2 || versions[2] != 10 {
+		t.Fatalf("expected versions [1 2 10], got %v", versions)
+	}
+
+	invalid := []fstest.MapFS{
+		{"0001_only_down.down.sql": {Data: []byte("SELECT 1")}},
+		{"first.up.sql": {Data: []byte("SELECT 1")}},
+		{"0001_a.up.sql": {Data: []byte("SELECT 1")}, "0001_b.down.sql": {Data: []byte("SELECT 1")}},
+	}
+	for _, fsys := range invalid {
+		if _, err := load(fsys); err == nil {
+			t.Errorf("expected error for %v", fsys)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS waitlist_entries;
+DROP TABLE IF EXISTS book_movement_histories;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS exchanges;
+DROP TABLE IF EXISTS books;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS locations;
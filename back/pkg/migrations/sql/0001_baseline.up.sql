@@ -0,0 +1,84 @@
+-- Базовая схема: набор сущностей на момент перехода с GORM AutoMigrate на миграции.
+-- Все объекты создаются с IF NOT EXISTS, поэтому для базы, которую раньше создавал
+-- AutoMigrate, эта миграция только фиксирует версию.
+
+CREATE TABLE IF NOT EXISTS locations (
+    id      uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name    text NOT NULL,
+    address text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id                       uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    email                    text NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    password                 text NOT NULL,
+    name                     text NOT NULL,
+    role                     varchar(20) DEFAULT 'user',
+    created_at               timestamptz,
+    refresh_token            text,
+    refresh_token_expires_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS books (
+    id                  uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id            uuid NOT NULL CONSTRAINT fk_books_owner REFERENCES users (id),
+    title               text NOT NULL,
+    author              text NOT NULL,
+    description         text,
+    condition           varchar(20) DEFAULT 'good',
+    status              varchar(20) DEFAULT 'available',
+    current_location_id uuid CONSTRAINT fk_locations_books REFERENCES locations (id),
+    image_url           text,
+    updated_at          timestamptz,
+    created_at          timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS exchanges (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     uuid NOT NULL CONSTRAINT fk_users_exchanges REFERENCES users (id),
+    book_id     uuid NOT NULL CONSTRAINT fk_books_exchanges REFERENCES books (id),
+    status      varchar(20) DEFAULT 'requested',
+    booked_at   timestamptz,
+    expires_at  timestamptz,
+    borrowed_at timestamptz,
+    due_at      timestamptz,
+    renewals    bigint NOT NULL DEFAULT 0,
+    location_id uuid CONSTRAINT fk_exchanges_location REFERENCES locations (id)
+);
+CREATE INDEX IF NOT EXISTS idx_exchanges_due_at ON exchanges (due_at);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    book_id    uuid NOT NULL CONSTRAINT fk_books_reviews REFERENCES books (id),
+    user_id    uuid NOT NULL CONSTRAINT fk_users_reviews REFERENCES users (id),
+    rating     smallint NOT NULL,
+    text       text,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_book_user ON reviews (book_id, user_id);
+
+CREATE TABLE IF NOT EXISTS book_movement_histories (
+    id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    book_id          uuid NOT NULL CONSTRAINT fk_book_movement_histories_book REFERENCES books (id),
+    from_location_id uuid CONSTRAINT fk_book_movement_histories_from_location REFERENCES locations (id),
+    to_location_id   uuid CONSTRAINT fk_book_movement_histories_to_location REFERENCES locations (id),
+    exchange_id      uuid CONSTRAINT fk_book_movement_histories_exchange REFERENCES exchanges (id),
+    user_id          uuid CONSTRAINT fk_book_movement_histories_user REFERENCES users (id),
+    action           varchar(50) NOT NULL,
+    notes            text,
+    previous_status  varchar(20),
+    new_status       varchar(20),
+    created_at       timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_book_movement_histories_book_id ON book_movement_histories (book_id);
+CREATE INDEX IF NOT EXISTS idx_book_movement_histories_exchange_id ON book_movement_histories (exchange_id);
+CREATE INDEX IF NOT EXISTS idx_book_movement_histories_created_at ON book_movement_histories (created_at);
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    book_id    uuid NOT NULL CONSTRAINT fk_waitlist_entries_book REFERENCES books (id),
+    user_id    uuid NOT NULL CONSTRAINT fk_waitlist_entries_user REFERENCES users (id),
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waitlist_book_user ON waitlist_entries (book_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_created_at ON waitlist_entries (created_at);
@@ -0,0 +1,5 @@
+-- Расширение pg_trgm не удаляем: им могут пользоваться другие объекты базы
+DROP INDEX IF EXISTS idx_books_author_trgm;
+DROP INDEX IF EXISTS idx_books_title_trgm;
+DROP INDEX IF EXISTS idx_books_search_vector;
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
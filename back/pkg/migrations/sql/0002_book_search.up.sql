@@ -0,0 +1,16 @@
+-- Полнотекстовый поиск по книгам.
+-- Конфигурация russian стеммит русские слова, а слова латиницей - английским стеммером,
+-- поэтому одного tsvector хватает для обоих языков.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(author, '')), 'B') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING gin (search_vector);
+
+-- Триграммы для поиска с опечатками по названию и автору
+CREATE INDEX IF NOT EXISTS idx_books_title_trgm ON books USING gin (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_books_author_trgm ON books USING gin (author gin_trgm_ops);
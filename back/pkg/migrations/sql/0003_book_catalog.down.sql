@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_reviews_book_id_rating;
+DROP INDEX IF EXISTS idx_books_author_lower;
+DROP INDEX IF EXISTS idx_books_current_location_id;
+DROP INDEX IF EXISTS idx_books_owner_id;
+DROP INDEX IF EXISTS idx_books_created_at;
@@ -0,0 +1,8 @@
+-- Индексы каталога: фильтры по владельцу и пункту, сортировка по дате и фасет по автору
+CREATE INDEX IF NOT EXISTS idx_books_created_at ON books (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_books_owner_id ON books (owner_id);
+CREATE INDEX IF NOT EXISTS idx_books_current_location_id ON books (current_location_id);
+CREATE INDEX IF NOT EXISTS idx_books_author_lower ON books (lower(author));
+
+-- Средний рейтинг считается группировкой отзывов по книге
+CREATE INDEX IF NOT EXISTS idx_reviews_book_id_rating ON reviews (book_id, rating);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_book_movement_histories_book_id_created_at;
+DROP INDEX IF EXISTS idx_book_movement_histories_user_id_created_at;
+DROP INDEX IF EXISTS idx_exchanges_book_id_booked_at;
+DROP INDEX IF EXISTS idx_exchanges_user_id_booked_at;
+DROP INDEX IF EXISTS idx_exchanges_booked_at;
+DROP INDEX IF EXISTS idx_books_title;
+DROP INDEX IF EXISTS idx_users_created_at;
@@ -0,0 +1,8 @@
+-- Индексы под курсорную пагинацию: порядок списка (ключ, id) с условием отбора впереди
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_books_title ON books (title, id);
+CREATE INDEX IF NOT EXISTS idx_exchanges_booked_at ON exchanges (booked_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_exchanges_user_id_booked_at ON exchanges (user_id, booked_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_exchanges_book_id_booked_at ON exchanges (book_id, booked_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_book_movement_histories_user_id_created_at ON book_movement_histories (user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_book_movement_histories_book_id_created_at ON book_movement_histories (book_id, created_at DESC, id DESC);
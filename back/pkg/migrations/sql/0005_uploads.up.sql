@@ -0,0 +1,14 @@
+-- Загруженные картинки; сами файлы (миниатюра и полноразмерная) лежат в BlobStore
+CREATE TABLE IF NOT EXISTS uploads (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id     uuid NOT NULL CONSTRAINT fk_uploads_owner REFERENCES users (id),
+    content_type varchar(50) NOT NULL,
+    size         bigint NOT NULL,
+    width        bigint NOT NULL,
+    height       bigint NOT NULL,
+    created_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_owner_id ON uploads (owner_id);
+
+-- Обложка книги из загрузок; image_url при этом указывает на ее полноразмерный вариант
+ALTER TABLE books ADD COLUMN IF NOT EXISTS image_id uuid CONSTRAINT fk_books_image REFERENCES uploads (id);
@@ -0,0 +1,3 @@
+-- ISBN-13 издания; по нему книги заполняются из набора данных изданий
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn varchar(13);
+CREATE INDEX IF NOT EXISTS idx_books_isbn ON books (isbn);
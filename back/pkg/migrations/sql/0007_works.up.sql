@@ -0,0 +1,34 @@
+-- Произведения каталога: экземпляры (books) с одинаковыми названием и автором ссылаются на одно произведение.
+-- key совпадает с domain.WorkKey: автор и название в нижнем регистре, без лишних пробелов, "ё" -> "е".
+CREATE TABLE IF NOT EXISTS works (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    key         text NOT NULL,
+    title       text NOT NULL,
+    author      text NOT NULL,
+    description text,
+    isbn        varchar(13),
+    cover_url   text,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_works_key ON works (key);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS work_id uuid CONSTRAINT fk_books_work REFERENCES works (id);
+CREATE INDEX IF NOT EXISTS idx_books_work_id ON books (work_id);
+
+-- Существующие книги: произведение создается по самому раннему экземпляру
+CREATE TEMPORARY TABLE book_work_keys ON COMMIT DROP AS
+SELECT id, created_at,
+       replace(btrim(regexp_replace(lower(author), '\s+', ' ', 'g')), 'ё', 'е') || '|' ||
+       replace(btrim(regexp_replace(lower(title), '\s+', ' ', 'g')), 'ё', 'е') AS key
+FROM books;
+
+INSERT INTO works (key, title, author, description, isbn, cover_url, created_at, updated_at)
+SELECT DISTINCT ON (k.key) k.key, b.title, b.author, b.description, b.isbn, b.image_url, b.created_at, b.created_at
+FROM book_work_keys k JOIN books b ON b.id = k.id
+ORDER BY k.key, k.created_at, k.id
+ON CONFLICT (key) DO NOTHING;
+
+UPDATE books b SET work_id = w.id
+FROM book_work_keys k JOIN works w ON w.key = k.key
+WHERE k.id = b.id AND b.work_id IS NULL;
@@ -0,0 +1,2 @@
+-- Время удаления аккаунта; строка остается, потому что на нее ссылаются история, обмены и отзывы
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
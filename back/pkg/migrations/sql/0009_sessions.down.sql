@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS refresh_token text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS refresh_token_expires_at timestamptz;
+DROP TABLE IF EXISTS sessions;
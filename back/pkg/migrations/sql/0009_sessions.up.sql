@@ -0,0 +1,17 @@
+-- Сессии: у каждого входа свой refresh-токен, в базе только SHA-256 его секрета.
+-- Токены из users.refresh_token в сессии не переносятся - пользователям нужно войти заново.
+CREATE TABLE IF NOT EXISTS sessions (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      uuid NOT NULL CONSTRAINT fk_sessions_user REFERENCES users (id),
+    token_hash   varchar(64) NOT NULL,
+    device       varchar(200),
+    ip           varchar(45),
+    created_at   timestamptz,
+    last_used_at timestamptz NOT NULL,
+    expires_at   timestamptz NOT NULL,
+    revoked_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+ALTER TABLE users DROP COLUMN IF EXISTS refresh_token;
+ALTER TABLE users DROP COLUMN IF EXISTS refresh_token_expires_at;
@@ -0,0 +1,18 @@
+-- Подтверждение почты. Аккаунты, созданные до появления проверки, считаем подтвержденными,
+-- иначе их владельцы не смогут брать книги.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+UPDATE users SET email_verified_at = coalesce(created_at, now()) WHERE email_verified_at IS NULL AND deleted_at IS NULL;
+
+-- Одноразовые токены из писем (подтверждение почты, сброс пароля); хранится только SHA-256 токена
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    uuid NOT NULL CONSTRAINT fk_account_tokens_user REFERENCES users (id),
+    purpose    varchar(20) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    email      text NOT NULL,
+    created_at timestamptz,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_tokens_token_hash ON account_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id ON account_tokens (user_id);
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS banned_by;
+ALTER TABLE users DROP COLUMN IF EXISTS ban_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS banned_until;
+ALTER TABLE users DROP COLUMN IF EXISTS banned_at;
@@ -0,0 +1,5 @@
+-- Блокировка пользователя модератором или администратором; без banned_until - бессрочная
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_until timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ban_reason text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_by uuid CONSTRAINT fk_users_banned_by REFERENCES users (id);
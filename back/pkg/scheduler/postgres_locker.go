@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log"
+)
+
+// PostgresLocker распределяет задачи между экземплярами сервиса через advisory lock в Postgres.
+// Блокировка сессионная, поэтому держится на отдельном соединении из пула, пока задача выполняется.
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+// NewPostgresLocker creates a locker backed by pg_try_advisory_lock
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+func (l *PostgresLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := lockKey(name)
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// Контекст задачи к этому моменту может быть отменен, а блокировку нужно снять в любом случае
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("scheduler: failed to release lock for job %s: %v", name, err)
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+// lockKey превращает имя задачи в ключ advisory lock
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("bookvito:job:" + name))
+	return int64(h.Sum64())
+}
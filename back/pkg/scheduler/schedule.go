@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseSchedule разбирает расписание задачи в интервал запуска.
+// Поддерживаются cron-дескрипторы @hourly, @daily, @weekly, "@every <duration>"
+// и просто длительность в формате time.Duration ("30m", "2h").
+func ParseSchedule(spec string) (time.Duration, error) {
+	spec = strings.TrimSpace(spec)
+
+	var interval time.Duration
+	switch {
+	case spec == "@hourly":
+		interval = time.Hour
+	case spec == "@daily" || spec == "@midnight":
+		interval = 24 * time.Hour
+	case spec == "@weekly":
+		interval = 7 * 24 * time.Hour
+	case strings.HasPrefix(spec, "@every "):
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return 0, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		interval = d
+	default:
+		d, err := time.ParseDuration(spec)
+		if err != nil {
+			return 0, fmt.Errorf("invalid schedule %q: expected @hourly, @daily, @weekly, @every <duration> or a duration", spec)
+		}
+		interval = d
+	}
+
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid schedule %q: interval must be positive", spec)
+	}
+	return interval, nil
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec string
+		want time.Duration
+	}{
+		{"@hourly", time.Hour},
+		{"@daily", 24 * time.Hour},
+		{"@midnight", 24 * time.Hour},
+		{"@weekly", 7 * 24 * time.Hour},
+		{"@every 15m", 15 * time.Minute},
+		{"  @every 1h30m ", 90 * time.Minute},
+		{"30s", 30 * time.Second},
+		{"2h", 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSchedule(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseScheduleRejectsInvalid(t *testing.T) {
+	for _, spec := range []string{"", "@yearly", "@every", "@every soon", "* * * * *", "10", "-5m", "0s", "@every 0s"} {
+		if got, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) = %s, want error", spec, got)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job - именованная фоновая задача
+type Job struct {
+	Name     string
+	Schedule string // см. ParseSchedule
+	Run      func(ctx context.Context) error
+}
+
+// Locker не дает запустить одну и ту же задачу одновременно на нескольких экземплярах сервиса
+type Locker interface {
+	// TryLock пытается захватить блокировку задачи. Если ok == false, задачу сейчас
+	// выполняет кто-то другой. unlock нужно вызвать после завершения задачи.
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+}
+
+// Status - состояние задачи для админки
+type Status struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	LastRunAt    *time.Time `json:"last_run_at"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastSkipAt   *time.Time `json:"last_skip_at,omitempty"` // Когда задачу пропустили, потому что ее выполнял другой экземпляр
+	NextRunAt    *time.Time `json:"next_run_at"`
+}
+
+type jobState struct {
+	job      Job
+	interval time.Duration
+	status   Status
+}
+
+// Scheduler запускает зарегистрированные задачи по их расписанию до отмены контекста
+type Scheduler struct {
+	locker Locker
+
+	mu      sync.Mutex
+	jobs    []*jobState
+	started bool
+	wg      sync.WaitGroup
+}
+
+// New creates a scheduler; locker may be nil when only one instance is running
+func New(locker Locker) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// Register добавляет задачу. Регистрировать задачи нужно до Start.
+func (s *Scheduler) Register(job Job) error {
+	interval, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("job %s: scheduler is already started", job.Name)
+	}
+	for _, existing := range s.jobs {
+		if existing.job.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+
+	s.jobs = append(s.jobs, &jobState{
+		job:      job,
+		interval: interval,
+		status:   Status{Name: job.Name, Schedule: job.Schedule},
+	})
+	return nil
+}
+
+// Start запускает задачи в фоне. После отмены ctx новые запуски не начинаются,
+// а уже идущие получают отмененный контекст; дождаться их можно через Wait.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+
+	for _, state := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, state)
+	}
+}
+
+// Wait ждет, пока завершатся все циклы задач (после отмены контекста Start),
+// но не дольше, чем живет ctx
+func (s *Scheduler) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Statuses возвращает состояние всех задач, отсортированное по имени
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, state := range s.jobs {
+		statuses = append(statuses, state.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (s *Scheduler) loop(ctx context.Context, state *jobState) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(state.interval)
+	defer ticker.Stop()
+	s.setNextRun(state, time.Now().Add(state.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, state)
+			s.setNextRun(state, time.Now().Add(state.interval))
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, state *jobState) {
+	name := state.job.Name
+
+	if s.locker != nil {
+		unlock, ok, err := s.locker.TryLock(ctx, name)
+		if err != nil {
+			log.Printf("scheduler: failed to acquire lock for job %s: %v", name, err)
+			s.finish(state, time.Now(), 0, err)
+			return
+		}
+		if !ok {
+			now := time.Now()
+			s.mu.Lock()
+			state.status.LastSkipAt = &now
+			s.mu.Unlock()
+			return
+		}
+		defer unlock()
+	}
+
+	s.mu.Lock()
+	state.status.Running = true
+	s.mu.Unlock()
+
+	log.Printf("scheduler: running job %s", name)
+	startedAt := time.Now()
+	err := s.safeRun(ctx, state.job)
+	duration := time.Since(startedAt)
+	if err != nil {
+		log.Printf("scheduler: job %s failed after %s: %v", name, duration, err)
+	}
+	s.finish(state, startedAt, duration, err)
+}
+
+// safeRun не дает панике в задаче уронить весь сервис
+func (s *Scheduler) safeRun(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) finish(state *jobState, startedAt time.Time, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state.status.Running = false
+	state.status.LastRunAt = &startedAt
+	state.status.LastDuration = duration.String()
+	state.status.LastError = ""
+	if err != nil {
+		state.status.LastError = err.Error()
+	}
+}
+
+func (s *Scheduler) setNextRun(state *jobState, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state.status.NextRunAt = &next
+}
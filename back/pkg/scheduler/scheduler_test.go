@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeLocker - блокировка в памяти; held имитирует задачу, которую выполняет другой экземпляр
+type fakeLocker struct {
+	held    bool
+	err     error
+	unlocks int
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held {
+		return nil, false, nil
+	}
+	return func() { l.unlocks++ }, true, nil
+}
+
+// newTestScheduler регистрирует одну задачу и возвращает планировщик и ее состояние
+func newTestScheduler(t *testing.T, locker Locker, run func(ctx context.Context) error) (*Scheduler, *jobState) {
+	t.Helper()
+	s := New(locker)
+	if err := s.Register(Job{Name: "test", Schedule: "@every 5ms", Run: run}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	return s, s.jobs[0]
+}
+
+func TestRegisterValidates(t *testing.T) {
+	s := New(nil)
+	noop := func(ctx context.Context) error { return nil }
+	if err := s.Register(Job{Name: "bad", Schedule: "sometimes", Run: noop}); err == nil {
+		t.Error("invalid schedule must be rejected")
+	}
+	if err := s.Register(Job{Name: "job", Schedule: "@hourly", Run: noop}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := s.Register(Job{Name: "job", Schedule: "@daily", Run: noop}); err == nil {
+		t.Error("duplicate job name must be rejected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	cancel()
+	if err := s.Register(Job{Name: "late", Schedule: "@hourly", Run: noop}); err == nil {
+		t.Error("registration after Start must be rejected")
+	}
+}
+
+func TestRunSkippedWhileLockIsHeld(t *testing.T) {
+	locker := &fakeLocker{held: true}
+	var runs int
+	s, state := newTestScheduler(t, locker, func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+
+	s.runOnce(context.Background(), state)
+	status := s.Statuses()[0]
+	if runs != 0 || status.LastSkipAt == nil || status.LastRunAt != nil {
+		t.Fatalf("job must be skipped while another instance holds the lock: runs=%d status=%+v", runs, status)
+	}
+
+	// Блокировка освободилась: задача выполняется и отпускает ее
+	locker.held = false
+	s.runOnce(context.Background(), state)
+	status = s.Statuses()[0]
+	if runs != 1 || locker.unlocks != 1 || status.LastRunAt == nil || status.Running {
+		t.Fatalf("job must run once the lock is free: runs=%d unlocks=%d status=%+v", runs, locker.unlocks, status)
+	}
+}
+
+func TestRunLockErrorIsReported(t *testing.T) {
+	var runs int
+	s, state := newTestScheduler(t, &fakeLocker{err: errors.New("connection refused")}, func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+
+	s.runOnce(context.Background(), state)
+	if status := s.Statuses()[0]; runs != 0 || status.LastError != "connection refused" {
+		t.Fatalf("lock error must be reported without running the job: runs=%d status=%+v", runs, status)
+	}
+}
+
+func TestRunRecordsLastRunAndError(t *testing.T) {
+	results := []error{errors.New("boom"), nil}
+	s, state := newTestScheduler(t, nil, func(ctx context.Context) error {
+		err := results[0]
+		results = results[1:]
+		return err
+	})
+
+	before := time.Now()
+	s.runOnce(context.Background(), state)
+	status := s.Statuses()[0]
+	if status.LastRunAt == nil || status.LastRunAt.Before(before) || status.LastDuration == "" {
+		t.Fatalf("last run must be recorded, got %+v", status)
+	}
+	if status.LastError != "boom" || status.Running {
+		t.Fatalf("failed run must keep its error, got %+v", status)
+	}
+
+	// Успешный запуск стирает прежнюю ошибку
+	firstRun := *status.LastRunAt
+	s.runOnce(context.Background(), state)
+	status = s.Statuses()[0]
+	if status.LastError != "" || status.LastRunAt.Before(firstRun) {
+		t.Fatalf("successful run must clear the error, got %+v", status)
+	}
+}
+
+func TestRunRecoversFromPanic(t *testing.T) {
+	s, state := newTestScheduler(t, nil, func(ctx context.Context) error {
+		panic("nil map")
+	})
+
+	s.runOnce(context.Background(), state)
+	if status := s.Statuses()[0]; status.LastError != "panic: nil map" || status.Running {
+		t.Fatalf("panic must be reported as an error, got %+v", status)
+	}
+}
+
+func TestStopsWhenContextIsCancelled(t *testing.T) {
+	started := make(chan struct{}, 1)
+	s, _ := newTestScheduler(t, nil, func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("job did not start")
+	}
+	if status := s.Statuses()[0]; !status.Running || status.NextRunAt == nil {
+		t.Fatalf("running job must be reported, got %+v", status)
+	}
+
+	// Отмена доходит до идущей задачи, и цикл планировщика завершается
+	cancel()
+	waitCtx, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+	if err := s.Wait(waitCtx); err != nil {
+		t.Fatalf("scheduler did not stop after cancel: %v", err)
+	}
+	if status := s.Statuses()[0]; status.Running || status.LastError != context.Canceled.Error() {
+		t.Fatalf("cancelled job must finish with context error, got %+v", status)
+	}
+}